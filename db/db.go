@@ -3,6 +3,7 @@ package db
 import (
 	"database/sql"
 	_ "embed"
+	"strings"
 
 	_ "github.com/mattn/go-sqlite3"
 )
@@ -10,16 +11,186 @@ import (
 //go:embed schema.sql
 var schema string
 
+// ftsSchema is applied only when SQLite is built with FTS5,
+// which go-sqlite3 enables with the sqlite_fts5 build tag.
+//
+//go:embed fts.sql
+var ftsSchema string
+
 // NewDB returns go-sqlite3 driver based *sql.DB.
+// Foreign key constraints are enabled on every connection.
 func NewDB(path string) (*sql.DB, error) {
-	db, err := sql.Open("sqlite3", path)
+	dsn := path
+	if strings.Contains(dsn, "?") {
+		dsn += "&_foreign_keys=on"
+	} else {
+		dsn += "?_foreign_keys=on"
+	}
+
+	db, err := sql.Open("sqlite3", dsn)
 	if err != nil {
 		return nil, err
 	}
 
+	// 既存のテーブルは CREATE TABLE IF NOT EXISTS で変わらないため、スキーマのインデックスより先に列を追加する
+	if err := migrate(db); err != nil {
+		return nil, err
+	}
+
 	if _, err := db.Exec(schema); err != nil {
 		return nil, err
 	}
 
+	if err := setUpFTS(db); err != nil {
+		return nil, err
+	}
+
 	return db, nil
 }
+
+// columnMigrations are the columns added to tables after they were first created, in the order they were added.
+// The definitions follow schema.sql, within what ALTER TABLE ADD COLUMN allows.
+var columnMigrations = []struct {
+	table      string
+	column     string
+	definition string
+}{
+	{"todos", "done", "BOOLEAN NOT NULL DEFAULT 0 CHECK(done IN (0, 1))"},
+	{"todos", "completed_at", "DATETIME"},
+	{"todos", "due_at", "DATETIME"},
+	{"todos", "priority", "INTEGER NOT NULL DEFAULT 0 CHECK(priority BETWEEN 0 AND 4)"},
+	{"todos", "project_id", "INTEGER REFERENCES projects(id)"},
+	{"todos", "parent_id", "INTEGER REFERENCES todos(id)"},
+	{"todos", "recurrence", "TEXT NOT NULL DEFAULT ''"},
+	{"todos", "version", "INTEGER NOT NULL DEFAULT 0"},
+	{"todos", "deleted_at", "DATETIME"},
+	{"todos", "next_id", "INTEGER REFERENCES todos(id) ON DELETE SET NULL"},
+}
+
+// triggerMigrations are the triggers of schema.sql replaced since they were first created, with the column
+// whose addition replaced them. They are dropped when the column is added, so that schema.sql recreates them.
+var triggerMigrations = map[string]string{
+	// 更新のたびに version を増やすトリガーに置き換える
+	"todos.version": "trigger_todos_updated_at",
+}
+
+// timeColumns are the DATETIME columns written by the service rather than by SQLite, with the trigger of their table
+// updating it on every change. Older versions wrote them in the format of the driver, "2006-01-02 15:04:05.999999999-07:00",
+// and they are rewritten in the one of DATETIME('now') so that all times compare correctly as text.
+var timeColumns = []struct {
+	table   string
+	column  string
+	trigger string
+}{
+	{"todos", "completed_at", "trigger_todos_updated_at"},
+	{"todos", "due_at", "trigger_todos_updated_at"},
+	{"todos", "deleted_at", "trigger_todos_updated_at"},
+	{"idempotency_keys", "expires_at", ""},
+	{"webhook_deliveries", "next_attempt_at", ""},
+	{"webhook_deliveries", "last_attempt_at", ""},
+}
+
+// migrate adds the columns of columnMigrations missing from existing tables
+// and rewrites the values of timeColumns in other formats, in a transaction.
+// Tables that do not exist yet are left to schema.sql.
+func migrate(db *sql.DB) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	columns := make(map[string]map[string]bool)
+	for _, m := range columnMigrations {
+		if columns[m.table] == nil {
+			if columns[m.table], err = tableColumns(tx, m.table); err != nil {
+				return err
+			}
+		}
+		if len(columns[m.table]) == 0 || columns[m.table][m.column] {
+			continue
+		}
+
+		if _, err := tx.Exec(`ALTER TABLE ` + m.table + ` ADD COLUMN ` + m.column + ` ` + m.definition); err != nil {
+			return err
+		}
+		if trigger, ok := triggerMigrations[m.table+"."+m.column]; ok {
+			if _, err := tx.Exec(`DROP TRIGGER IF EXISTS ` + trigger); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, c := range timeColumns {
+		if columns[c.table] == nil {
+			if columns[c.table], err = tableColumns(tx, c.table); err != nil {
+				return err
+			}
+		}
+		if !columns[c.table][c.column] {
+			continue
+		}
+
+		// DATETIME で解釈できない値は NULL になり、比較から外れるため書き換えない
+		condition := c.column + ` IS NOT NULL AND ` + c.column + ` <> DATETIME(` + c.column + `)`
+		var stale bool
+		if err := tx.QueryRow(`SELECT EXISTS(SELECT 1 FROM ` + c.table + ` WHERE ` + condition + `)`).Scan(&stale); err != nil {
+			return err
+		}
+		if !stale {
+			continue
+		}
+		// 書き換えで更新日時が変わらないようトリガーを外す。トリガーは schema.sql が作り直す
+		if c.trigger != "" {
+			if _, err := tx.Exec(`DROP TRIGGER IF EXISTS ` + c.trigger); err != nil {
+				return err
+			}
+		}
+		if _, err := tx.Exec(`UPDATE ` + c.table + ` SET ` + c.column + ` = DATETIME(` + c.column + `) WHERE ` + condition); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// tableColumns returns the set of the columns of table, which is empty when table does not exist.
+func tableColumns(tx *sql.Tx, table string) (map[string]bool, error) {
+	rows, err := tx.Query(`SELECT name FROM pragma_table_info(?)`, table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns := make(map[string]bool)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		columns[name] = true
+	}
+	return columns, rows.Err()
+}
+
+// setUpFTS creates the full-text search index of todos if FTS5 is available.
+// The index is rebuilt from todos when it is created for an existing database.
+func setUpFTS(db *sql.DB) error {
+	var exists bool
+	if err := db.QueryRow(`SELECT EXISTS(SELECT 1 FROM sqlite_master WHERE name = 'todos_fts')`).Scan(&exists); err != nil {
+		return err
+	}
+
+	if _, err := db.Exec(ftsSchema); err != nil {
+		if strings.Contains(err.Error(), "no such module: fts5") {
+			return nil
+		}
+		return err
+	}
+
+	if !exists {
+		if _, err := db.Exec(`INSERT INTO todos_fts(todos_fts) VALUES('rebuild')`); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -1,8 +1,10 @@
 package db_test
 
 import (
+	"database/sql"
 	"errors"
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/TechBowl-japan/go-stations/db"
@@ -48,3 +50,112 @@ func TestNewDB(t *testing.T) {
 		})
 	}
 }
+
+func TestNewDBMigration(t *testing.T) {
+	t.Parallel()
+
+	// 列を追加する前のスキーマで作られたデータベース
+	const baseline = `
+CREATE TABLE todos (
+  id          INTEGER  NOT NULL PRIMARY KEY AUTOINCREMENT,
+  subject     TEXT     NOT NULL,
+  description TEXT     NOT NULL DEFAULT '',
+  created_at  DATETIME NOT NULL DEFAULT (DATETIME('now')),
+  updated_at  DATETIME NOT NULL DEFAULT (DATETIME('now')),
+  CHECK(subject <> '')
+);
+
+CREATE TRIGGER trigger_todos_updated_at AFTER UPDATE ON todos
+BEGIN
+  UPDATE todos SET updated_at = DATETIME('now') WHERE id == NEW.id;
+END;
+
+INSERT INTO todos(subject) VALUES ('existing');
+`
+	path := filepath.Join(t.TempDir(), "todo.db")
+	old, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatalf("failed to open db: %v", err)
+	}
+	if _, err := old.Exec(baseline); err != nil {
+		t.Fatalf("failed to create baseline schema: %v", err)
+	}
+	old.Close()
+
+	// 2 回目は追加済みの列をそのまま使う
+	for i := 0; i < 2; i++ {
+		dbConn, err := db.NewDB(path)
+		if err != nil {
+			t.Fatalf("failed to open baseline db: %v", err)
+		}
+
+		if _, err := dbConn.Exec(`UPDATE todos SET done = 1, priority = 3, project_id = NULL WHERE id = 1`); err != nil {
+			t.Fatalf("failed to update todo: %v", err)
+		}
+		var (
+			subject string
+			done    bool
+			version int64
+		)
+		err = dbConn.QueryRow(`SELECT subject, done, version FROM todos WHERE id = 1 AND deleted_at IS NULL AND recurrence = ''`).Scan(&subject, &done, &version)
+		if err != nil {
+			t.Fatalf("failed to read todo: %v", err)
+		}
+		// 置き換えたトリガーが更新のたびに version を増やす
+		if subject != "existing" || !done || version != int64(i+1) {
+			t.Errorf("unexpected todo, got subject = %s, done = %v, version = %d", subject, done, version)
+		}
+		dbConn.Close()
+	}
+}
+
+func TestNewDBTimeMigration(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "todo.db")
+	dbConn, err := db.NewDB(path)
+	if err != nil {
+		t.Fatalf("failed to create db: %v", err)
+	}
+	// 以前のバージョンはドライバーの形式で時刻を書き込んでいた
+	_, err = dbConn.Exec(`INSERT INTO todos(subject, due_at, completed_at, deleted_at, updated_at) VALUES
+  ('driver format', '2024-03-11 08:00:00.5+00:00', '2024-03-10 23:00:00+09:00', NULL, '2024-01-01 00:00:00'),
+  ('sqlite format', '2024-03-11 08:00:00', NULL, '2024-03-12 00:00:00', '2024-01-01 00:00:00')`)
+	if err != nil {
+		t.Fatalf("failed to insert todos: %v", err)
+	}
+	dbConn.Close()
+
+	dbConn, err = db.NewDB(path)
+	if err != nil {
+		t.Fatalf("failed to open db: %v", err)
+	}
+	t.Cleanup(func() { dbConn.Close() })
+
+	// DATETIME の列はそのまま読むと time.Time に変換されるため、式にして保存された値を読む
+	rows, err := dbConn.Query(`SELECT subject, IFNULL(due_at, ''), IFNULL(completed_at, ''), IFNULL(deleted_at, ''), +updated_at, version FROM todos ORDER BY id`)
+	if err != nil {
+		t.Fatalf("failed to read todos: %v", err)
+	}
+	defer rows.Close()
+
+	want := [][]string{
+		{"driver format", "2024-03-11 08:00:00", "2024-03-10 14:00:00", "", "2024-01-01 00:00:00", "0"},
+		{"sqlite format", "2024-03-11 08:00:00", "", "2024-03-12 00:00:00", "2024-01-01 00:00:00", "0"},
+	}
+	for i := 0; rows.Next(); i++ {
+		got := make([]string, 6)
+		dest := make([]interface{}, len(got))
+		for j := range got {
+			dest[j] = &got[j]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			t.Fatalf("failed to scan todo: %v", err)
+		}
+		for j := range got {
+			if got[j] != want[i][j] {
+				t.Errorf("unexpected column %d of todo %d, got = %q, want = %q", j, i+1, got[j], want[i][j])
+			}
+		}
+	}
+}
@@ -1,13 +1,127 @@
-CREATE TABLE IF NOT EXISTS todos (
+CREATE TABLE IF NOT EXISTS projects (
   id          INTEGER  NOT NULL PRIMARY KEY AUTOINCREMENT,
-  subject     TEXT     NOT NULL,
+  name        TEXT     NOT NULL,
   description TEXT     NOT NULL DEFAULT '',
   created_at  DATETIME NOT NULL DEFAULT (DATETIME('now')),
   updated_at  DATETIME NOT NULL DEFAULT (DATETIME('now')),
-  CHECK(subject <> '')
+  CHECK(name <> '')
+);
+
+CREATE TRIGGER IF NOT EXISTS trigger_projects_updated_at AFTER UPDATE ON projects
+BEGIN
+  UPDATE projects SET updated_at = DATETIME('now') WHERE id == NEW.id;
+END;
+
+CREATE TABLE IF NOT EXISTS todos (
+  id           INTEGER  NOT NULL PRIMARY KEY AUTOINCREMENT,
+  subject      TEXT     NOT NULL,
+  description  TEXT     NOT NULL DEFAULT '',
+  done         BOOLEAN  NOT NULL DEFAULT 0,
+  completed_at DATETIME,
+  due_at       DATETIME,
+  priority     INTEGER  NOT NULL DEFAULT 0,
+  project_id   INTEGER  REFERENCES projects(id),
+  parent_id    INTEGER  REFERENCES todos(id),
+  recurrence   TEXT     NOT NULL DEFAULT '',
+  next_id      INTEGER  REFERENCES todos(id) ON DELETE SET NULL,
+  version      INTEGER  NOT NULL DEFAULT 0,
+  deleted_at   DATETIME,
+  created_at   DATETIME NOT NULL DEFAULT (DATETIME('now')),
+  updated_at   DATETIME NOT NULL DEFAULT (DATETIME('now')),
+  CHECK(subject <> ''),
+  CHECK(done IN (0, 1)),
+  CHECK(priority BETWEEN 0 AND 4)
 );
 
 CREATE TRIGGER IF NOT EXISTS trigger_todos_updated_at AFTER UPDATE ON todos
 BEGIN
-  UPDATE todos SET updated_at = DATETIME('now') WHERE id == NEW.id;
+  UPDATE todos SET updated_at = DATETIME('now'), version = OLD.version + 1 WHERE id == NEW.id;
 END;
+
+CREATE INDEX IF NOT EXISTS index_todos_project_id ON todos(project_id);
+
+CREATE INDEX IF NOT EXISTS index_todos_parent_id ON todos(parent_id);
+
+CREATE INDEX IF NOT EXISTS index_todos_due_at ON todos(due_at);
+
+CREATE INDEX IF NOT EXISTS index_todos_priority_due_at ON todos(priority DESC, due_at);
+
+CREATE INDEX IF NOT EXISTS index_todos_deleted_at ON todos(deleted_at);
+
+CREATE TABLE IF NOT EXISTS tags (
+  id   INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+  name TEXT    NOT NULL UNIQUE,
+  CHECK(name <> '')
+);
+
+CREATE TABLE IF NOT EXISTS todo_tags (
+  todo_id INTEGER NOT NULL REFERENCES todos(id) ON DELETE CASCADE,
+  tag_id  INTEGER NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+  PRIMARY KEY (todo_id, tag_id)
+);
+
+CREATE INDEX IF NOT EXISTS index_todo_tags_tag_id ON todo_tags(tag_id);
+
+CREATE TABLE IF NOT EXISTS todo_events (
+  id         INTEGER  NOT NULL PRIMARY KEY AUTOINCREMENT,
+  todo_id    INTEGER  NOT NULL,
+  action     TEXT     NOT NULL,
+  actor      TEXT     NOT NULL DEFAULT '',
+  old_value  TEXT,
+  new_value  TEXT,
+  created_at DATETIME NOT NULL DEFAULT (DATETIME('now')),
+  CHECK(action <> '')
+);
+
+CREATE INDEX IF NOT EXISTS index_todo_events_todo_id ON todo_events(todo_id, id);
+
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+  idempotency_key TEXT     NOT NULL PRIMARY KEY,
+  request_hash    TEXT     NOT NULL,
+  status          INTEGER  NOT NULL DEFAULT 0,
+  header          TEXT     NOT NULL DEFAULT '{}',
+  body            BLOB,
+  created_at      DATETIME NOT NULL DEFAULT (DATETIME('now')),
+  expires_at      DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS index_idempotency_keys_expires_at ON idempotency_keys(expires_at);
+
+CREATE TABLE IF NOT EXISTS webhooks (
+  id         INTEGER  NOT NULL PRIMARY KEY AUTOINCREMENT,
+  url        TEXT     NOT NULL,
+  events     TEXT     NOT NULL DEFAULT '',
+  secret     TEXT     NOT NULL,
+  active     BOOLEAN  NOT NULL DEFAULT 1,
+  created_at DATETIME NOT NULL DEFAULT (DATETIME('now')),
+  updated_at DATETIME NOT NULL DEFAULT (DATETIME('now')),
+  CHECK(url <> ''),
+  CHECK(active IN (0, 1))
+);
+
+CREATE TRIGGER IF NOT EXISTS trigger_webhooks_updated_at AFTER UPDATE ON webhooks
+BEGIN
+  UPDATE webhooks SET updated_at = DATETIME('now') WHERE id == NEW.id;
+END;
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+  id               INTEGER  NOT NULL PRIMARY KEY AUTOINCREMENT,
+  webhook_id       INTEGER  NOT NULL REFERENCES webhooks(id) ON DELETE CASCADE,
+  event_id         INTEGER  NOT NULL REFERENCES todo_events(id),
+  status           TEXT     NOT NULL DEFAULT 'pending',
+  attempts         INTEGER  NOT NULL DEFAULT 0,
+  next_attempt_at  DATETIME,
+  last_attempt_at  DATETIME,
+  last_status_code INTEGER  NOT NULL DEFAULT 0,
+  last_error       TEXT     NOT NULL DEFAULT '',
+  created_at       DATETIME NOT NULL DEFAULT (DATETIME('now')),
+  CHECK(status IN ('pending', 'succeeded', 'dead'))
+);
+
+CREATE INDEX IF NOT EXISTS index_webhook_deliveries_webhook_id ON webhook_deliveries(webhook_id, id);
+CREATE INDEX IF NOT EXISTS index_webhook_deliveries_next_attempt_at ON webhook_deliveries(status, next_attempt_at);
+
+CREATE TABLE IF NOT EXISTS secrets (
+  name  TEXT NOT NULL PRIMARY KEY,
+  value BLOB NOT NULL
+);
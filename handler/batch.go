@@ -0,0 +1,106 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/TechBowl-japan/go-stations/model"
+)
+
+// Batch handles the endpoint that runs create, update, delete and complete operations in a single transaction.
+// Operations with invalid data fail without running; in atomic mode they prevent every other operation from running.
+func (h *TODOHandler) Batch(ctx context.Context, req *model.BatchTODORequest) (*model.BatchTODOResponse, error) {
+	results := make([]*model.BatchResult, len(req.Operations))
+	var (
+		ops     []*model.BatchOperation
+		indexes []int
+	)
+	for i, op := range req.Operations {
+		if err := parseBatchOperation(op); err != nil {
+			results[i] = &model.BatchResult{Index: i, Status: model.BatchFailed, Err: err}
+			continue
+		}
+		ops = append(ops, op)
+		indexes = append(indexes, i)
+	}
+
+	if len(ops) < len(req.Operations) && req.Mode != model.BatchBestEffort {
+		for i, result := range results {
+			if result == nil {
+				results[i] = &model.BatchResult{Index: i, Status: model.BatchSkipped}
+			}
+		}
+	} else {
+		done, err := h.svc.BatchTODO(ctx, ops, req.Mode)
+		if err != nil {
+			return nil, err
+		}
+		// サービスに渡した操作の位置をリクエストでの位置に戻す
+		for j, result := range done {
+			result.Index = indexes[j]
+			results[indexes[j]] = result
+		}
+	}
+
+	resp := &model.BatchTODOResponse{Results: results}
+	for _, result := range results {
+		if result.Err != nil {
+			result.Error = newProblem(result.Err)
+		}
+		if result.Status == model.BatchSucceeded {
+			resp.Committed = true
+		}
+	}
+	return resp, nil
+}
+
+// parseBatchOperation validates op and decodes its data into the request of its type.
+func parseBatchOperation(op *model.BatchOperation) error {
+	if op == nil {
+		return &model.ErrValidation{Field: "op", Message: "is required"}
+	}
+	if err := model.Validate(op); err != nil {
+		return err
+	}
+
+	switch op.Op {
+	case model.BatchCreate:
+		op.Create = &model.CreateTODORequest{}
+		return decodeEmbeddedJSON(op.Data, op.Create)
+	case model.BatchUpdate:
+		op.Update = &model.UpdateTODORequest{}
+		return decodeEmbeddedJSON(op.Data, op.Update)
+	case model.BatchDelete:
+		op.Delete = &model.DeleteTODORequest{}
+		return decodeEmbeddedJSON(op.Data, op.Delete)
+	case model.BatchComplete:
+		if op.TODOID == 0 {
+			return &model.ErrValidation{Field: "todo_id", Message: "is required"}
+		}
+	}
+	return nil
+}
+
+// serveBatch handles the "/todos/batch" endpoint.
+func (h *TODOHandler) serveBatch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		RenderError(w, &model.ErrMethodNotAllowed{Allowed: []string{http.MethodPost}})
+		return
+	}
+
+	var req model.BatchTODORequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		RenderError(w, err)
+		return
+	}
+
+	resp, err := h.Batch(r.Context(), &req)
+	if err != nil {
+		RenderError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
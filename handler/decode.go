@@ -0,0 +1,118 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/TechBowl-japan/go-stations/model"
+)
+
+// maxBodySize is the maximum size of a JSON request body.
+const maxBodySize = 1 << 20
+
+// decodeJSON decodes the JSON body of r into v and validates v with model.Validate.
+// Bodies larger than maxBodySize, unknown fields and trailing data are rejected.
+func decodeJSON(w http.ResponseWriter, r *http.Request, v interface{}) error {
+	if err := decodeJSONValue(http.MaxBytesReader(w, r.Body, maxBodySize), v); err != nil {
+		return err
+	}
+	return model.Validate(v)
+}
+
+// decodeJSONValue decodes a single JSON value from body into v, rejecting unknown fields.
+func decodeJSONValue(body io.Reader, v interface{}) error {
+	dec := json.NewDecoder(body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		var errMaxBytes *http.MaxBytesError
+		if errors.As(err, &errMaxBytes) {
+			return &model.ErrRequestTooLarge{Limit: errMaxBytes.Limit}
+		}
+		return &model.ErrBadRequest{Message: "invalid JSON body: " + err.Error()}
+	}
+	if _, err := dec.Token(); !errors.Is(err, io.EOF) {
+		return &model.ErrBadRequest{Message: "invalid JSON body: unexpected data after the JSON value"}
+	}
+	return nil
+}
+
+// A queryParser parses query parameters, collecting every malformed one.
+type queryParser struct {
+	query url.Values
+	errs  []*model.ErrValidation
+}
+
+// int64 stores the integer parameter name into dst when it is present.
+func (p *queryParser) int64(name string, dst *int64) {
+	s := p.query.Get(name)
+	if s == "" {
+		return
+	}
+	v, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		p.errs = append(p.errs, &model.ErrValidation{Field: name, Message: "must be an integer"})
+		return
+	}
+	*dst = v
+}
+
+// bool stores the boolean parameter name into dst when it is present.
+func (p *queryParser) bool(name string, dst *bool) {
+	s := p.query.Get(name)
+	if s == "" {
+		return
+	}
+	v, err := strconv.ParseBool(s)
+	if err != nil {
+		p.errs = append(p.errs, &model.ErrValidation{Field: name, Message: "must be true or false"})
+		return
+	}
+	*dst = v
+}
+
+// duration stores the duration parameter name, such as "720h", into dst when it is present.
+func (p *queryParser) duration(name string, dst *time.Duration) {
+	s := p.query.Get(name)
+	if s == "" {
+		return
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		p.errs = append(p.errs, &model.ErrValidation{Field: name, Message: "must be a duration such as 720h"})
+		return
+	}
+	*dst = v
+}
+
+// invalid reports err of parsing a parameter, which is a *model.ErrValidation.
+func (p *queryParser) invalid(err error) {
+	var errValidation *model.ErrValidation
+	if errors.As(err, &errValidation) {
+		p.errs = append(p.errs, errValidation)
+		return
+	}
+	p.errs = append(p.errs, &model.ErrValidation{Message: err.Error()})
+}
+
+// validate validates req with its validate tags and reports them together with the parse errors.
+func (p *queryParser) validate(req interface{}) error {
+	return model.JoinValidation(append(p.errs, model.Violations(req)...))
+}
+
+// decodeEmbeddedJSON decodes data embedded in a WebSocket command or a batch operation into v and validates v,
+// as decodeJSON does for request bodies.
+func decodeEmbeddedJSON(data json.RawMessage, v interface{}) error {
+	if len(data) == 0 {
+		return &model.ErrValidation{Field: "data", Message: "is required"}
+	}
+	if err := decodeJSONValue(bytes.NewReader(data), v); err != nil {
+		return err
+	}
+	return model.Validate(v)
+}
@@ -0,0 +1,103 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/TechBowl-japan/go-stations/model"
+)
+
+// problemContentType is the media type of RFC 9457 problem details.
+const problemContentType = "application/problem+json"
+
+// RenderError writes err as problem details with the status code matching its type.
+// This is the single place where errors are mapped to status codes; errors of unknown
+// types are logged and hidden behind 500 Internal Server Error.
+func RenderError(w http.ResponseWriter, err error) {
+	problem := newProblem(err)
+	var errMethodNotAllowed *model.ErrMethodNotAllowed
+	if errors.As(err, &errMethodNotAllowed) && len(errMethodNotAllowed.Allowed) > 0 {
+		w.Header().Set("Allow", strings.Join(errMethodNotAllowed.Allowed, ", "))
+	}
+	w.Header().Set("Content-Type", problemContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(problem.Status)
+	_ = json.NewEncoder(w).Encode(problem)
+}
+
+// newProblem converts err into problem details.
+func newProblem(err error) *model.Problem {
+	var (
+		errValidationFailed     *model.ErrValidationFailed
+		errValidation           *model.ErrValidation
+		errBadRequest           *model.ErrBadRequest
+		errNotFound             *model.ErrNotFound
+		errForbidden            *model.ErrForbidden
+		errMethodNotAllowed     *model.ErrMethodNotAllowed
+		errConflict             *model.ErrConflict
+		errPreconditionFailed   *model.ErrPreconditionFailed
+		errUnsupportedMediaType *model.ErrUnsupportedMediaType
+		errNotAcceptable        *model.ErrNotAcceptable
+		errRequestTooLarge      *model.ErrRequestTooLarge
+		errIdempotencyKeyReused *model.ErrIdempotencyKeyReused
+		errUnavailable          *model.ErrUnavailable
+	)
+
+	var (
+		status int
+		code   string
+		fields []*model.ProblemField
+	)
+	switch {
+	case errors.As(err, &errValidationFailed):
+		status, code = http.StatusBadRequest, "validation_failed"
+		for _, e := range errValidationFailed.Errors {
+			fields = append(fields, &model.ProblemField{Field: e.Field, Message: e.Message})
+		}
+	case errors.As(err, &errValidation):
+		status, code = http.StatusBadRequest, "validation_failed"
+		fields = []*model.ProblemField{{Field: errValidation.Field, Message: errValidation.Message}}
+	case errors.As(err, &errBadRequest):
+		status, code = http.StatusBadRequest, "bad_request"
+	case errors.As(err, &errForbidden):
+		status, code = http.StatusForbidden, "forbidden"
+	case errors.As(err, &errNotFound):
+		status, code = http.StatusNotFound, "not_found"
+	case errors.As(err, &errMethodNotAllowed):
+		status, code = http.StatusMethodNotAllowed, "method_not_allowed"
+	case errors.As(err, &errConflict):
+		status, code = http.StatusConflict, "conflict"
+	case errors.As(err, &errPreconditionFailed):
+		status, code = http.StatusPreconditionFailed, "precondition_failed"
+	case errors.As(err, &errUnsupportedMediaType):
+		status, code = http.StatusUnsupportedMediaType, "unsupported_media_type"
+	case errors.As(err, &errNotAcceptable):
+		status, code = http.StatusNotAcceptable, "not_acceptable"
+	case errors.As(err, &errRequestTooLarge):
+		status, code = http.StatusRequestEntityTooLarge, "request_too_large"
+	case errors.As(err, &errIdempotencyKeyReused):
+		status, code = http.StatusUnprocessableEntity, "idempotency_key_reused"
+	case errors.As(err, &errUnavailable):
+		status, code = http.StatusNotImplemented, "not_implemented"
+	default:
+		log.Println("handler: internal server error, err =", err)
+		return &model.Problem{
+			Type:   "about:blank",
+			Title:  http.StatusText(http.StatusInternalServerError),
+			Status: http.StatusInternalServerError,
+			Code:   "internal",
+		}
+	}
+
+	return &model.Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: err.Error(),
+		Code:   code,
+		Errors: fields,
+	}
+}
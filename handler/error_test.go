@@ -0,0 +1,92 @@
+package handler_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/TechBowl-japan/go-stations/handler"
+	"github.com/TechBowl-japan/go-stations/model"
+)
+
+func TestRenderError(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		err        error
+		wantStatus int
+		wantCode   string
+		wantFields int
+		wantAllow  string
+	}{
+		"Validation": {
+			err:        &model.ErrValidation{Field: "subject", Message: "is required"},
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "validation_failed",
+			wantFields: 1,
+		},
+		"Wrapped not found": {
+			err:        fmt.Errorf("read: %w", &model.ErrNotFound{}),
+			wantStatus: http.StatusNotFound,
+			wantCode:   "not_found",
+		},
+		"Method not allowed": {
+			err:        &model.ErrMethodNotAllowed{Allowed: []string{http.MethodGet, http.MethodHead}},
+			wantStatus: http.StatusMethodNotAllowed,
+			wantCode:   "method_not_allowed",
+			wantAllow:  "GET, HEAD",
+		},
+		"Precondition failed": {
+			err:        &model.ErrPreconditionFailed{},
+			wantStatus: http.StatusPreconditionFailed,
+			wantCode:   "precondition_failed",
+		},
+		"Not acceptable": {
+			err:        &model.ErrNotAcceptable{Message: "cannot export as application/pdf"},
+			wantStatus: http.StatusNotAcceptable,
+			wantCode:   "not_acceptable",
+		},
+		"Unknown error is hidden": {
+			err:        errors.New("database is locked"),
+			wantStatus: http.StatusInternalServerError,
+			wantCode:   "internal",
+		},
+	}
+
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			w := httptest.NewRecorder()
+			handler.RenderError(w, c.err)
+
+			if w.Code != c.wantStatus {
+				t.Errorf("unexpected status, got = %d, want = %d", w.Code, c.wantStatus)
+			}
+			if got := w.Header().Get("Content-Type"); got != "application/problem+json" {
+				t.Errorf("unexpected content type, got = %s", got)
+			}
+			if got := w.Header().Get("Allow"); got != c.wantAllow {
+				t.Errorf("unexpected allow, got = %q, want = %q", got, c.wantAllow)
+			}
+
+			var problem model.Problem
+			if err := json.NewDecoder(w.Body).Decode(&problem); err != nil {
+				t.Fatalf("failed to decode: %v", err)
+			}
+			if problem.Status != c.wantStatus || problem.Code != c.wantCode {
+				t.Errorf("unexpected problem, got = %+v", problem)
+			}
+			if len(problem.Errors) != c.wantFields {
+				t.Errorf("unexpected field errors, got = %d, want = %d", len(problem.Errors), c.wantFields)
+			}
+			if c.wantStatus == http.StatusInternalServerError && problem.Detail != "" {
+				t.Errorf("internal error detail is exposed: %s", problem.Detail)
+			}
+		})
+	}
+}
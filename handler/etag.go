@@ -0,0 +1,85 @@
+package handler
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/TechBowl-japan/go-stations/model"
+)
+
+// todoETag returns the strong entity tag of a representation of todo encoded as body.
+// It is the version of todo followed by a hash of body, so that the representations with other fields,
+// or with an expanded project or subtasks that changed without todo, have different tags.
+func todoETag(todo *model.Todo, body []byte) string {
+	sum := sha256.Sum256(body)
+	return strconv.Quote(strconv.FormatInt(todo.Version, 10) + "-" + hex.EncodeToString(sum[:8]))
+}
+
+// writeTODOResponse writes resp, a response carrying todo, as JSON with status and its ETag.
+// A GET or HEAD request whose If-None-Match matches the ETag results in 304 Not Modified instead.
+func writeTODOResponse(w http.ResponseWriter, r *http.Request, status int, todo *model.Todo, resp interface{}) {
+	var body bytes.Buffer
+	if err := json.NewEncoder(&body).Encode(resp); err != nil {
+		RenderError(w, err)
+		return
+	}
+
+	etag := todoETag(todo, body.Bytes())
+	w.Header().Set("ETag", etag)
+	if (r.Method == http.MethodGet || r.Method == http.MethodHead) && noneMatch(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, _ = w.Write(body.Bytes())
+}
+
+// parseIfMatch parses an If-Match header into the versions of the entity tags it lists.
+// nil is returned when the header is absent or "*", meaning no version check.
+// Only the version of a tag is compared, as the rest differs between the representations of the same version.
+// Weak and malformed entity tags never match, as If-Match uses the strong comparison.
+func parseIfMatch(header string) []int64 {
+	header = strings.TrimSpace(header)
+	if header == "" || header == "*" {
+		return nil
+	}
+
+	versions := []int64{}
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if strings.HasPrefix(tag, "W/") {
+			continue
+		}
+		s, err := strconv.Unquote(tag)
+		if err != nil {
+			continue
+		}
+		s, _, _ = strings.Cut(s, "-")
+		version, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			continue
+		}
+		versions = append(versions, version)
+	}
+	return versions
+}
+
+// noneMatch reports whether an If-None-Match header matches etag using the weak comparison.
+func noneMatch(header, etag string) bool {
+	header = strings.TrimSpace(header)
+	if header == "*" {
+		return true
+	}
+	for _, tag := range strings.Split(header, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(tag), "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,137 @@
+package handler_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/TechBowl-japan/go-stations/db"
+	"github.com/TechBowl-japan/go-stations/handler"
+	"github.com/TechBowl-japan/go-stations/model"
+	"github.com/TechBowl-japan/go-stations/service"
+)
+
+func TestTODOHandlerETagView(t *testing.T) {
+	t.Parallel()
+
+	todoDB, err := db.NewDB(filepath.Join(t.TempDir(), "todo.db"))
+	if err != nil {
+		t.Fatalf("failed to create db: %v", err)
+	}
+	t.Cleanup(func() { todoDB.Close() })
+
+	ctx := context.Background()
+	projectSvc := service.NewProjectService(todoDB)
+	project, err := projectSvc.CreateProject(ctx, "home", "")
+	if err != nil {
+		t.Fatalf("failed to create project: %v", err)
+	}
+	svc := service.NewTODOService(todoDB)
+	if _, err := svc.CreateTODOWithAttributes(ctx, "subject", "", &model.TODOAttributes{ProjectID: &project.ID}); err != nil {
+		t.Fatalf("failed to create todo: %v", err)
+	}
+	h := handler.NewTODOHandler(svc)
+
+	get := func(query, ifNoneMatch string) *httptest.ResponseRecorder {
+		t.Helper()
+		r := httptest.NewRequest(http.MethodGet, "/todos/1"+query, nil)
+		if ifNoneMatch != "" {
+			r.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	// 表現ごとに ETag が異なる
+	plain := get("", "").Header().Get("ETag")
+	expanded := get("?expand=project", "").Header().Get("ETag")
+	selected := get("?fields=subject", "").Header().Get("ETag")
+	if plain == "" || plain == expanded || plain == selected || expanded == selected {
+		t.Fatalf("unexpected etags, plain = %s, expanded = %s, selected = %s", plain, expanded, selected)
+	}
+	if w := get("?expand=project", plain); w.Code != http.StatusOK {
+		t.Errorf("etag of another representation matched, status = %d", w.Code)
+	}
+	if w := get("?expand=project", expanded); w.Code != http.StatusNotModified {
+		t.Errorf("unexpected status, got = %d, want = %d", w.Code, http.StatusNotModified)
+	}
+
+	// TODO が変わらなくても、展開したプロジェクトが変われば 304 にならない
+	if _, err := projectSvc.UpdateProject(ctx, project.ID, "office", ""); err != nil {
+		t.Fatalf("failed to update project: %v", err)
+	}
+	if w := get("?expand=project", expanded); w.Code != http.StatusOK || w.Header().Get("ETag") == expanded {
+		t.Errorf("stale expanded todo, status = %d, etag = %s", w.Code, w.Header().Get("ETag"))
+	}
+	if w := get("", plain); w.Code != http.StatusNotModified {
+		t.Errorf("unexpected status, got = %d, want = %d", w.Code, http.StatusNotModified)
+	}
+}
+
+func TestTODOHandlerConditionalRequests(t *testing.T) {
+	t.Parallel()
+
+	todoDB, err := db.NewDB(filepath.Join(t.TempDir(), "todo.db"))
+	if err != nil {
+		t.Fatalf("failed to create db: %v", err)
+	}
+	t.Cleanup(func() { todoDB.Close() })
+
+	h := handler.NewTODOHandler(service.NewTODOService(todoDB))
+	do := func(method, path, body string, header map[string]string) *httptest.ResponseRecorder {
+		t.Helper()
+		r := httptest.NewRequest(method, path, strings.NewReader(body))
+		r.Header.Set("Content-Type", "application/json")
+		for k, v := range header {
+			r.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	w := do(http.MethodPost, "/todos", `{"subject":"created"}`, nil)
+	created := w.Header().Get("ETag")
+	if w.Code != http.StatusCreated || !strings.HasPrefix(created, `"0-`) {
+		t.Fatalf("unexpected create response, status = %d, etag = %s", w.Code, created)
+	}
+	if got := do(http.MethodGet, "/todos/1", "", nil).Header().Get("ETag"); got != created {
+		t.Errorf("unexpected etag of read, got = %s, want = %s", got, created)
+	}
+
+	steps := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		header     map[string]string
+		wantStatus int
+	}{
+		{name: "Current version", method: http.MethodPut, path: "/todos", body: `{"id":1,"subject":"updated"}`, header: map[string]string{"If-Match": created}, wantStatus: http.StatusOK},
+		{name: "Stale version", method: http.MethodPut, path: "/todos", body: `{"id":1,"subject":"lost"}`, header: map[string]string{"If-Match": created}, wantStatus: http.StatusPreconditionFailed},
+		{name: "Version without hash", method: http.MethodPatch, path: "/todos/1", body: `{"description":"patched"}`, header: map[string]string{"If-Match": `"1"`}, wantStatus: http.StatusOK},
+		{name: "Weak tag never matches", method: http.MethodPatch, path: "/todos/1", body: `{"description":"lost"}`, header: map[string]string{"If-Match": `W/"2"`}, wantStatus: http.StatusPreconditionFailed},
+		{name: "Any version", method: http.MethodPost, path: "/todos/1/complete", header: map[string]string{"If-Match": "*"}, wantStatus: http.StatusOK},
+		{name: "Stale delete", method: http.MethodDelete, path: "/todos", body: `{"ids":[1]}`, header: map[string]string{"If-Match": `"2"`}, wantStatus: http.StatusPreconditionFailed},
+		{name: "Stale If-None-Match", method: http.MethodGet, path: "/todos/1", header: map[string]string{"If-None-Match": created}, wantStatus: http.StatusOK},
+	}
+	for _, step := range steps {
+		w := do(step.method, step.path, step.body, step.header)
+		if w.Code != step.wantStatus {
+			t.Errorf("%s: unexpected status, got = %d, want = %d, body = %s", step.name, w.Code, step.wantStatus, w.Body)
+		}
+	}
+
+	// 現在の ETag は弱い比較でも一致し、本文を返さない
+	current := do(http.MethodGet, "/todos/1", "", nil).Header().Get("ETag")
+	for _, ifNoneMatch := range []string{current, "W/" + current, `"0-0", ` + current, "*"} {
+		w := do(http.MethodGet, "/todos/1", "", map[string]string{"If-None-Match": ifNoneMatch})
+		if w.Code != http.StatusNotModified || w.Body.Len() != 0 || w.Header().Get("ETag") != current {
+			t.Errorf("If-None-Match %s: unexpected response, status = %d, etag = %s", ifNoneMatch, w.Code, w.Header().Get("ETag"))
+		}
+	}
+}
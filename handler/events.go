@@ -0,0 +1,118 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/TechBowl-japan/go-stations/model"
+)
+
+const (
+	// eventsHeartbeatInterval is the interval of the comments keeping an idle event stream open.
+	eventsHeartbeatInterval = 15 * time.Second
+	// eventsRetry is the reconnection delay suggested to EventSource clients, in milliseconds.
+	eventsRetry = 3000
+)
+
+// errEventStreamClosed is returned by writes to an event stream whose handler has returned.
+var errEventStreamClosed = errors.New("event stream closed")
+
+// serveEvents handles the "/todos/events" endpoint, streaming TODO events as Server-Sent Events.
+// Each event has its id in the persisted event sequence, so a client reconnecting with
+// Last-Event-ID (or ?last_event_id=) receives the events it missed; others start from now.
+func (h *TODOHandler) serveEvents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		RenderError(w, &model.ErrMethodNotAllowed{Allowed: []string{http.MethodGet}})
+		return
+	}
+
+	ctx := r.Context()
+	lastEventID := r.Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = r.URL.Query().Get("last_event_id")
+	}
+	var lastID int64
+	if lastEventID != "" {
+		id, err := strconv.ParseInt(lastEventID, 10, 64)
+		if err != nil || id < 0 {
+			RenderError(w, &model.ErrValidation{Field: "last_event_id", Message: "must be a non-negative integer"})
+			return
+		}
+		lastID = id
+	} else {
+		id, err := h.svc.LatestTODOEventID(ctx)
+		if err != nil {
+			RenderError(w, err)
+			return
+		}
+		lastID = id
+	}
+
+	// ストリームはサーバーの WriteTimeout より長く続くため、この接続の書き込み期限を外す
+	rc := http.NewResponseController(w)
+	_ = rc.SetWriteDeadline(time.Time{})
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	// ハートビートの goroutine が ServeHTTP から戻った後に ResponseWriter を使わないよう、
+	// done を閉じた後の書き込みを止め、goroutine の終了を待ってから戻る
+	var (
+		mu   sync.Mutex
+		wg   sync.WaitGroup
+		done = make(chan struct{})
+	)
+	write := func(format string, args ...interface{}) error {
+		mu.Lock()
+		defer mu.Unlock()
+		select {
+		case <-done:
+			return errEventStreamClosed
+		default:
+		}
+		if _, err := fmt.Fprintf(w, format, args...); err != nil {
+			return err
+		}
+		return rc.Flush()
+	}
+	if err := write("retry: %d\n\n", eventsRetry); err != nil {
+		return
+	}
+
+	defer func() {
+		mu.Lock()
+		close(done)
+		mu.Unlock()
+		wg.Wait()
+	}()
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(eventsHeartbeatInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if err := write(": ping\n\n"); err != nil {
+					return
+				}
+			}
+		}
+	}()
+
+	_ = h.svc.WatchTODOEvents(ctx, lastID, func(event *model.TODOEvent) error {
+		data, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		return write("id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Action, data)
+	})
+}
@@ -0,0 +1,379 @@
+package handler
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/TechBowl-japan/go-stations/model"
+)
+
+const (
+	// exportWriteTimeout is how long writing exportDeadlineInterval TODOs can take; slower clients are disconnected.
+	exportWriteTimeout = 30 * time.Second
+	// exportDeadlineInterval is the number of TODOs written before the write deadline is extended.
+	exportDeadlineInterval = 100
+)
+
+// exportFormats are the export formats with their content types and file extensions, in the order of preference.
+var exportFormats = []struct {
+	format      model.ExportFormat
+	contentType string
+	extension   string
+}{
+	{model.ExportCSV, "text/csv", "csv"},
+	{model.ExportNDJSON, "application/x-ndjson", "ndjson"},
+	{model.ExportMarkdown, "text/markdown", "md"},
+}
+
+// exportColumns are the CSV columns of the fields of model.Todo, in the order of its JSON.
+var exportColumns = []string{"id", "subject", "description", "done", "completed_at", "due_at", "priority", "tags", "project_id", "parent_id", "recurrence", "created_at", "updated_at"}
+
+// markdownEscaper escapes the characters of Markdown formatting in a line of text.
+var markdownEscaper = strings.NewReplacer(`\`, `\\`, "`", "\\`", `*`, `\*`, `_`, `\_`, `[`, `\[`, `]`, `\]`, `<`, `\<`, `>`, `\>`, `#`, `\#`)
+
+// A todoExportWriter writes TODOs in an export format.
+type todoExportWriter interface {
+	Write(todo *model.Todo) error
+	// Close writes the buffered output.
+	Close() error
+}
+
+// negotiateExportFormat returns the format parameter, or the format of accept most preferred by the client.
+// Without either, TODOs are exported as CSV.
+func negotiateExportFormat(format model.ExportFormat, accept string) (model.ExportFormat, error) {
+	if format != "" || strings.TrimSpace(accept) == "" {
+		if format == "" {
+			return model.ExportCSV, nil
+		}
+		return format, nil
+	}
+
+	var (
+		best  model.ExportFormat
+		bestQ float64
+	)
+	for _, mediaRange := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(mediaRange))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if s, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(s, 64); err != nil {
+				continue
+			}
+		}
+		// 同じ重みの場合は先に指定された形式を使う
+		if q <= bestQ {
+			continue
+		}
+		for _, f := range exportFormats {
+			if matchMediaRange(mediaType, f.contentType) {
+				best, bestQ = f.format, q
+				break
+			}
+		}
+	}
+	if best == "" {
+		return "", &model.ErrNotAcceptable{Message: fmt.Sprintf("cannot export as %s; available types are text/csv, application/x-ndjson and text/markdown", accept)}
+	}
+	return best, nil
+}
+
+// matchMediaRange reports whether contentType matches mediaRange such as "text/*".
+func matchMediaRange(mediaRange, contentType string) bool {
+	if mediaRange == "*/*" || mediaRange == contentType {
+		return true
+	}
+	// application/ndjson は application/x-ndjson の別名として扱う
+	if mediaRange == "application/ndjson" {
+		return contentType == "application/x-ndjson"
+	}
+	prefix, ok := strings.CutSuffix(mediaRange, "*")
+	return ok && strings.HasSuffix(prefix, "/") && strings.HasPrefix(contentType, prefix)
+}
+
+// newTODOExportWriter returns the writer of format writing the fields chosen by view to w.
+func newTODOExportWriter(format model.ExportFormat, w io.Writer, view *model.TODOView) (todoExportWriter, error) {
+	switch format {
+	case model.ExportNDJSON:
+		return &ndjsonExportWriter{w: w, view: view}, nil
+	case model.ExportMarkdown:
+		return &markdownExportWriter{w: w}, nil
+	}
+
+	var columns []string
+	for _, column := range exportColumns {
+		if view.Selects(column) {
+			columns = append(columns, column)
+		}
+	}
+	if view.Expands(model.TODOExpandProject) {
+		columns = append(columns, "project")
+	}
+	if view.Expands(model.TODOExpandSubtasks) {
+		columns = append(columns, "subtasks_total", "subtasks_done")
+	}
+	cw := &csvExportWriter{w: csv.NewWriter(w), columns: columns}
+	return cw, cw.w.Write(columns)
+}
+
+// csvExportWriter writes TODOs as CSV rows under a header of the columns.
+type csvExportWriter struct {
+	w       *csv.Writer
+	columns []string
+}
+
+func (cw *csvExportWriter) Write(todo *model.Todo) error {
+	record := make([]string, len(cw.columns))
+	for i, column := range cw.columns {
+		record[i] = escapeCSVFormula(exportValue(todo, column))
+	}
+	return cw.w.Write(record)
+}
+
+func (cw *csvExportWriter) Close() error {
+	cw.w.Flush()
+	return cw.w.Error()
+}
+
+// csvFormulaPrefixes are the first characters that make spreadsheets evaluate a cell as a formula.
+const csvFormulaPrefixes = "=+-@\t\r"
+
+// escapeCSVFormula prefixes value with ' when a spreadsheet would evaluate it as a formula,
+// so that a subject like =HYPERLINK(...) is shown as text. readCSVImport removes the prefix.
+// A value starting with ' itself is prefixed too, so that its own ' is kept when it is imported.
+func escapeCSVFormula(value string) string {
+	if value != "" && strings.ContainsRune(csvFormulaPrefixes+"'", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+// unescapeCSVFormula removes the prefix added by escapeCSVFormula.
+// Other values starting with ' are kept as they are.
+func unescapeCSVFormula(value string) string {
+	if len(value) >= 2 && value[0] == '\'' && strings.ContainsRune(csvFormulaPrefixes+"'", rune(value[1])) {
+		return value[1:]
+	}
+	return value
+}
+
+// exportValue returns the CSV value of column of todo. Times are in RFC 3339 and tags are comma-separated.
+func exportValue(todo *model.Todo, column string) string {
+	formatID := func(id *int64) string {
+		if id == nil {
+			return ""
+		}
+		return strconv.FormatInt(*id, 10)
+	}
+	formatTime := func(t *time.Time) string {
+		if t == nil {
+			return ""
+		}
+		return t.Format(time.RFC3339)
+	}
+
+	switch column {
+	case "id":
+		return strconv.FormatInt(todo.ID, 10)
+	case "subject":
+		return todo.Subject
+	case "description":
+		return todo.Description
+	case "done":
+		return strconv.FormatBool(todo.Done)
+	case "completed_at":
+		return formatTime(todo.CompletedAt)
+	case "due_at":
+		return formatTime(todo.DueAt)
+	case "priority":
+		return string(todo.Priority)
+	case "tags":
+		return strings.Join(todo.Tags, ",")
+	case "project_id":
+		return formatID(todo.ProjectID)
+	case "parent_id":
+		return formatID(todo.ParentID)
+	case "recurrence":
+		return todo.Recurrence
+	case "created_at":
+		return formatTime(&todo.CreatedAt)
+	case "updated_at":
+		return formatTime(&todo.UpdatedAt)
+	case "project":
+		if todo.Project == nil {
+			return ""
+		}
+		return todo.Project.Name
+	case "subtasks_total", "subtasks_done":
+		if todo.Subtasks == nil {
+			return ""
+		}
+		if column == "subtasks_total" {
+			return strconv.FormatInt(todo.Subtasks.Total, 10)
+		}
+		return strconv.FormatInt(todo.Subtasks.Done, 10)
+	}
+	return ""
+}
+
+// ndjsonExportWriter writes TODOs as JSON, one per line, with the fields chosen by view.
+type ndjsonExportWriter struct {
+	w    io.Writer
+	view *model.TODOView
+}
+
+func (nw *ndjsonExportWriter) Write(todo *model.Todo) error {
+	line, err := nw.view.MarshalTODO(todo)
+	if err != nil {
+		return err
+	}
+	_, err = nw.w.Write(append(line, '\n'))
+	return err
+}
+
+func (nw *ndjsonExportWriter) Close() error {
+	return nil
+}
+
+// markdownExportWriter writes TODOs as a Markdown checklist, with the due date, the priority and the tags
+// after the subject and the description indented under it.
+type markdownExportWriter struct {
+	w io.Writer
+}
+
+func (mw *markdownExportWriter) Write(todo *model.Todo) error {
+	box := " "
+	if todo.Done {
+		box = "x"
+	}
+	var b strings.Builder
+	b.WriteString("- [" + box + "] " + markdownEscaper.Replace(todo.Subject))
+
+	var notes []string
+	if todo.DueAt != nil {
+		notes = append(notes, "due "+todo.DueAt.In(time.Local).Format("2006-01-02 15:04"))
+	}
+	if todo.Priority != model.PriorityNone {
+		notes = append(notes, "priority "+string(todo.Priority))
+	}
+	for _, tag := range todo.Tags {
+		notes = append(notes, "#"+markdownEscaper.Replace(tag))
+	}
+	if len(notes) > 0 {
+		b.WriteString(" (" + strings.Join(notes, ", ") + ")")
+	}
+	b.WriteString("\n")
+
+	if description := strings.TrimSpace(todo.Description); description != "" {
+		for _, line := range strings.Split(description, "\n") {
+			b.WriteString("  " + markdownEscaper.Replace(strings.TrimRight(line, "\r")) + "\n")
+		}
+	}
+
+	_, err := io.WriteString(mw.w, b.String())
+	return err
+}
+
+func (mw *markdownExportWriter) Close() error {
+	return nil
+}
+
+// countingWriter counts the bytes written to w.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	return n, err
+}
+
+// serveExport handles the "/todos/export" endpoint.
+func (h *TODOHandler) serveExport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		RenderError(w, &model.ErrMethodNotAllowed{Allowed: []string{http.MethodGet, http.MethodHead}})
+		return
+	}
+
+	query := r.URL.Query()
+	read, err := parseReadTODORequest(query)
+	if err != nil {
+		RenderError(w, err)
+		return
+	}
+	req := model.ExportTODORequest{ReadTODORequest: *read, Format: model.ExportFormat(query.Get("format"))}
+	if err := model.Validate(&req); err != nil {
+		RenderError(w, err)
+		return
+	}
+	format, err := negotiateExportFormat(req.Format, r.Header.Get("Accept"))
+	if err != nil {
+		RenderError(w, err)
+		return
+	}
+
+	for _, f := range exportFormats {
+		if f.format == format {
+			w.Header().Set("Content-Type", f.contentType+"; charset=utf-8")
+			w.Header().Set("Content-Disposition", `attachment; filename="todos.`+f.extension+`"`)
+		}
+	}
+	w.Header().Set("Vary", "Accept")
+	if r.Method == http.MethodHead {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	// 書き出しはサーバーの WriteTimeout より長くかかりうるため、一定の件数ごとに書き込み期限を延ばす
+	rc := http.NewResponseController(w)
+	_ = rc.SetWriteDeadline(time.Now().Add(exportWriteTimeout))
+
+	out := &countingWriter{w: w}
+	ew, err := newTODOExportWriter(format, out, req.View)
+	if err == nil {
+		var count int
+		err = h.svc.ExportTODO(r.Context(), &model.TODOQuery{
+			Status:        req.Status,
+			Due:           req.Due,
+			DueWithinDays: req.DueWithinDays,
+			Filter:        req.Where,
+			Order:         req.Order,
+			Tags:          req.Tags,
+			TagMatch:      req.TagMatch,
+			ProjectID:     req.ProjectID,
+			View:          req.View,
+		}, func(todo *model.Todo) error {
+			if count++; count%exportDeadlineInterval == 0 {
+				_ = rc.SetWriteDeadline(time.Now().Add(exportWriteTimeout))
+			}
+			return ew.Write(todo)
+		})
+	}
+	if err == nil {
+		err = ew.Close()
+	}
+	if err == nil {
+		return
+	}
+
+	// 書き出し始める前であればエラーを返せる
+	if out.n == 0 {
+		w.Header().Del("Content-Disposition")
+		RenderError(w, err)
+		return
+	}
+	// 途中までの内容を完全な書き出しと誤解されないよう、接続を切る
+	log.Println("handler: failed to export todos, err =", err)
+	panic(http.ErrAbortHandler)
+}
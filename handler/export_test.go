@@ -0,0 +1,170 @@
+package handler_test
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/TechBowl-japan/go-stations/db"
+	"github.com/TechBowl-japan/go-stations/handler"
+	"github.com/TechBowl-japan/go-stations/model"
+	"github.com/TechBowl-japan/go-stations/service"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestTODOHandlerExport(t *testing.T) {
+	t.Parallel()
+
+	todoDB, err := db.NewDB(filepath.Join(t.TempDir(), "todo.db"))
+	if err != nil {
+		t.Fatalf("failed to create db: %v", err)
+	}
+	t.Cleanup(func() { todoDB.Close() })
+
+	ctx := context.Background()
+	svc := service.NewTODOService(todoDB)
+	// 書き出しのバッチの大きさを超える件数を作る
+	for i := 0; i < 150; i++ {
+		if _, err := svc.CreateTODO(ctx, "filler", ""); err != nil {
+			t.Fatalf("failed to create todo: %v", err)
+		}
+	}
+	if _, err := svc.CreateTODOWithAttributes(ctx, "Buy *milk*", "line 1\nline 2", &model.TODOAttributes{Priority: model.PriorityHigh, Tags: []string{"home"}}); err != nil {
+		t.Fatalf("failed to create todo: %v", err)
+	}
+	h := handler.NewTODOHandler(svc)
+
+	cases := map[string]struct {
+		query           string
+		accept          string
+		wantStatus      int
+		wantContentType string
+		wantLines       int
+		wantLast        string
+	}{
+		"CSV by default": {
+			query:           "fields=subject,tags",
+			wantStatus:      http.StatusOK,
+			wantContentType: "text/csv; charset=utf-8",
+			wantLines:       152,
+			wantLast:        "151,Buy *milk*,home",
+		},
+		"NDJSON by Accept": {
+			query:           "fields=subject&filter=priority:high",
+			accept:          "text/html, application/x-ndjson;q=0.9, text/csv;q=0.5",
+			wantStatus:      http.StatusOK,
+			wantContentType: "application/x-ndjson; charset=utf-8",
+			wantLines:       1,
+			wantLast:        `{"id":151,"subject":"Buy *milk*"}`,
+		},
+		"Markdown by format": {
+			query:           "format=markdown&tag=home",
+			accept:          "application/json",
+			wantStatus:      http.StatusOK,
+			wantContentType: "text/markdown; charset=utf-8",
+			wantLines:       3,
+			wantLast:        "  line 2",
+		},
+		"Not acceptable": {
+			accept:     "application/pdf",
+			wantStatus: http.StatusNotAcceptable,
+		},
+		"Invalid filter": {
+			query:      "filter=done:maybe",
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			r := httptest.NewRequest(http.MethodGet, "/todos/export?"+c.query, nil)
+			if c.accept != "" {
+				r.Header.Set("Accept", c.accept)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if w.Code != c.wantStatus {
+				t.Fatalf("unexpected status, got = %d, want = %d, body = %s", w.Code, c.wantStatus, w.Body)
+			}
+			if c.wantStatus != http.StatusOK {
+				return
+			}
+			if got := w.Header().Get("Content-Type"); got != c.wantContentType {
+				t.Errorf("unexpected content type, got = %s, want = %s", got, c.wantContentType)
+			}
+			lines := strings.Split(strings.TrimSuffix(w.Body.String(), "\n"), "\n")
+			if len(lines) != c.wantLines || lines[len(lines)-1] != c.wantLast {
+				t.Errorf("unexpected body, got %d lines ending with %q, want %d lines ending with %q", len(lines), lines[len(lines)-1], c.wantLines, c.wantLast)
+			}
+		})
+	}
+}
+
+func TestTODOHandlerExportFormula(t *testing.T) {
+	t.Parallel()
+
+	todoDB, err := db.NewDB(filepath.Join(t.TempDir(), "todo.db"))
+	if err != nil {
+		t.Fatalf("failed to create db: %v", err)
+	}
+	t.Cleanup(func() { todoDB.Close() })
+
+	ctx := context.Background()
+	svc := service.NewTODOService(todoDB)
+	subjects := []string{`=HYPERLINK("http://example.com","x")`, "+1", "-1", "@SUM(A1)", "\tindented", "\rreturn", "plain", "'quoted", "'=1"}
+	for _, subject := range subjects {
+		if _, err := svc.CreateTODO(ctx, subject, ""); err != nil {
+			t.Fatalf("failed to create todo: %v", err)
+		}
+	}
+	h := handler.NewTODOHandler(svc)
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/todos/export?fields=subject&sort=id", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status, got = %d, body = %s", w.Code, w.Body)
+	}
+	exported := w.Body.String()
+	records, err := csv.NewReader(strings.NewReader(exported)).ReadAll()
+	if err != nil {
+		t.Fatalf("failed to read csv: %v", err)
+	}
+	var got []string
+	for _, record := range records[1:] {
+		got = append(got, record[1])
+	}
+	// 数式として評価される値と ' で始まる値だけ ' を付ける
+	want := []string{`'=HYPERLINK("http://example.com","x")`, "'+1", "'-1", "'@SUM(A1)", "'\tindented", "'\rreturn", "plain", "''quoted", "''=1"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("unexpected subjects (-want +got):\n%s", diff)
+	}
+
+	// 書き出した CSV を取り込むと元の件名に戻る。前後の空白は取り込みで取り除かれる
+	r := httptest.NewRequest(http.MethodPost, "/todos/import?dry_run=true", strings.NewReader(exported))
+	r.Header.Set("Content-Type", "text/csv")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	var resp model.ImportTODOResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	got, want = nil, nil
+	for i, result := range resp.Results {
+		if result.TODO != nil {
+			got = append(got, result.TODO.Subject)
+		}
+		want = append(want, strings.TrimSpace(subjects[i]))
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("unexpected imported subjects (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,456 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/TechBowl-japan/go-stations/model"
+)
+
+const (
+	// maxImportSize is the maximum size of an import body, which is larger than maxBodySize for migrations.
+	maxImportSize = 10 << 20
+	// maxImportItems is the maximum number of TODOs imported at once.
+	maxImportItems = 5000
+)
+
+// importFormats are the import formats with their content types.
+var importFormats = []struct {
+	format      model.ImportFormat
+	contentType string
+}{
+	{model.ImportCSV, "text/csv"},
+	{model.ImportJSON, "application/json"},
+	{model.ImportTodoTxt, "text/plain"},
+}
+
+// utf8BOM is the byte order mark that spreadsheets write at the beginning of CSV files.
+var utf8BOM = []byte("\xef\xbb\xbf")
+
+// importColumns set the values of the CSV columns to the TODO imported, parsing the values written by exportValue.
+// Columns that cannot be imported, such as id and created_at, are ignored.
+var importColumns = map[string]func(item *model.ImportTODOItem, value string) error{
+	"subject": func(item *model.ImportTODOItem, value string) error {
+		item.Subject = value
+		return nil
+	},
+	"description": func(item *model.ImportTODOItem, value string) error {
+		item.Description = value
+		return nil
+	},
+	"done": func(item *model.ImportTODOItem, value string) (err error) {
+		if value == "" {
+			return nil
+		}
+		if item.Done, err = strconv.ParseBool(value); err != nil {
+			return &model.ErrValidation{Field: "done", Message: "must be true or false"}
+		}
+		return nil
+	},
+	"completed_at": func(item *model.ImportTODOItem, value string) (err error) {
+		item.CompletedAt, err = parseImportTime("completed_at", value)
+		return err
+	},
+	"due_at": func(item *model.ImportTODOItem, value string) (err error) {
+		item.DueAt, err = parseImportTime("due_at", value)
+		return err
+	},
+	"priority": func(item *model.ImportTODOItem, value string) error {
+		item.Priority = model.Priority(value)
+		return nil
+	},
+	"tags": func(item *model.ImportTODOItem, value string) error {
+		if value != "" {
+			item.Tags = strings.Split(value, ",")
+		}
+		return nil
+	},
+	"project_id": func(item *model.ImportTODOItem, value string) (err error) {
+		item.ProjectID, err = parseImportID("project_id", value)
+		return err
+	},
+	"parent_id": func(item *model.ImportTODOItem, value string) (err error) {
+		item.ParentID, err = parseImportID("parent_id", value)
+		return err
+	},
+	"recurrence": func(item *model.ImportTODOItem, value string) error {
+		item.Recurrence = value
+		return nil
+	},
+	"project": func(item *model.ImportTODOItem, value string) error {
+		item.Project = strings.TrimSpace(value)
+		return nil
+	},
+}
+
+// negotiateImportFormat returns the format parameter, or the format of the content type of the body.
+func negotiateImportFormat(format model.ImportFormat, contentType string) (model.ImportFormat, error) {
+	if format != "" {
+		return format, nil
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err == nil {
+		for _, f := range importFormats {
+			if f.contentType == mediaType {
+				return f.format, nil
+			}
+		}
+	}
+	return "", &model.ErrUnsupportedMediaType{Message: "cannot import " + contentType + "; supported types are text/csv, application/json and text/plain for todo.txt"}
+}
+
+// parseImportTime parses value of field in RFC 3339, or a date such as 2006-01-02 meaning its midnight in the local time zone.
+// An empty value is nil.
+func parseImportTime(field, value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		if t, err = time.ParseInLocation(time.DateOnly, value, time.Local); err != nil {
+			return nil, &model.ErrValidation{Field: field, Message: "must be a date such as 2006-01-02 or an RFC 3339 time"}
+		}
+	}
+	return &t, nil
+}
+
+// parseImportID parses value of field as an id. An empty value is nil.
+func parseImportID(field, value string) (*int64, error) {
+	if value == "" {
+		return nil, nil
+	}
+	id, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return nil, &model.ErrValidation{Field: field, Message: "must be an integer"}
+	}
+	return &id, nil
+}
+
+// readImportItems reads the TODOs of data in format.
+// Malformed TODOs have their errors set, while errors of data as a whole, such as broken JSON, are returned.
+func readImportItems(format model.ImportFormat, data []byte) ([]*model.ImportTODOItem, error) {
+	data = bytes.TrimPrefix(data, utf8BOM)
+
+	var (
+		items []*model.ImportTODOItem
+		err   error
+	)
+	switch format {
+	case model.ImportJSON:
+		items, err = readJSONImport(data)
+	case model.ImportTodoTxt:
+		items = readTodoTxtImport(data)
+	default:
+		items, err = readCSVImport(data)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if len(items) == 0 {
+		return nil, &model.ErrValidation{Field: "body", Message: "has no TODOs"}
+	}
+	if len(items) > maxImportItems {
+		return nil, &model.ErrValidation{Field: "body", Message: fmt.Sprintf("must have at most %d TODOs", maxImportItems)}
+	}
+	return items, nil
+}
+
+// readCSVImport reads the rows of CSV data under a header of the columns, which must include subject.
+func readCSVImport(data []byte) ([]*model.ImportTODOItem, error) {
+	r := csv.NewReader(bytes.NewReader(data))
+	header, err := r.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, &model.ErrBadRequest{Message: "invalid CSV body: " + err.Error()}
+	}
+
+	columns := make([]string, len(header))
+	hasSubject := false
+	for i, name := range header {
+		columns[i] = strings.ToLower(strings.TrimSpace(name))
+		hasSubject = hasSubject || columns[i] == "subject"
+	}
+	if !hasSubject {
+		return nil, &model.ErrValidation{Field: "body", Message: "must have a subject column"}
+	}
+
+	var items []*model.ImportTODOItem
+	for {
+		record, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			return items, nil
+		}
+
+		item := &model.ImportTODOItem{}
+		var errParse *csv.ParseError
+		switch {
+		case errors.As(err, &errParse) && errors.Is(errParse.Err, csv.ErrFieldCount):
+			// 列の数が違う行は読み飛ばし、残りの行を読み続ける
+			item.Line = errParse.StartLine
+			item.Err = &model.ErrValidation{Field: "body", Message: fmt.Sprintf("must have %d columns", len(columns))}
+			items = append(items, item)
+			continue
+		case err != nil:
+			return nil, &model.ErrBadRequest{Message: "invalid CSV body: " + err.Error()}
+		}
+
+		item.Line, _ = r.FieldPos(0)
+		var errs []*model.ErrValidation
+		for i, value := range record {
+			set, ok := importColumns[columns[i]]
+			if !ok {
+				continue
+			}
+			if err := set(item, unescapeCSVFormula(value)); err != nil {
+				var errValidation *model.ErrValidation
+				if errors.As(err, &errValidation) {
+					errs = append(errs, errValidation)
+				}
+			}
+		}
+		if item.Project != "" && item.ProjectID != nil {
+			errs = append(errs, &model.ErrValidation{Field: "project", Message: "cannot be combined with project_id"})
+		}
+		item.Err = model.JoinValidation(errs)
+		items = append(items, item)
+	}
+}
+
+// readJSONImport reads the TODOs of a JSON array, with the fields of POST /todos and done and completed_at.
+// Unknown fields are ignored, so that TODOs exported from other tools can be imported as they are.
+func readJSONImport(data []byte) ([]*model.ImportTODOItem, error) {
+	errInvalid := func(err error) error {
+		return &model.ErrBadRequest{Message: "invalid JSON body: " + err.Error()}
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if token, err := dec.Token(); err != nil || token != json.Delim('[') {
+		return nil, &model.ErrBadRequest{Message: "invalid JSON body: must be an array of TODOs"}
+	}
+
+	var items []*model.ImportTODOItem
+	for dec.More() {
+		// 要素の行番号は、直前の区切りの後の空白を除いた位置から数える
+		start := int(dec.InputOffset())
+		start += len(data[start:]) - len(bytes.TrimLeft(data[start:], " \t\r\n,"))
+
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return nil, errInvalid(err)
+		}
+		item := &model.ImportTODOItem{}
+		if err := json.Unmarshal(value, item); err != nil {
+			item.Err = &model.ErrBadRequest{Message: "invalid TODO: " + err.Error()}
+		}
+		item.Line = 1 + bytes.Count(data[:start], []byte("\n"))
+		items = append(items, item)
+	}
+	if _, err := dec.Token(); err != nil {
+		return nil, errInvalid(err)
+	}
+	if _, err := dec.Token(); !errors.Is(err, io.EOF) {
+		return nil, &model.ErrBadRequest{Message: "invalid JSON body: unexpected data after the JSON value"}
+	}
+	return items, nil
+}
+
+// readTodoTxtImport reads the TODOs of the non-empty lines of todo.txt data.
+func readTodoTxtImport(data []byte) []*model.ImportTODOItem {
+	var items []*model.ImportTODOItem
+	for i, line := range strings.Split(string(data), "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		item := parseTodoTxtLine(line)
+		item.Line = i + 1
+		items = append(items, item)
+	}
+	return items
+}
+
+// parseTodoTxtLine parses a line of todo.txt such as "x 2024-01-02 (A) 2024-01-01 Call mom +family @phone due:2024-01-05".
+// Priorities A, B and C are urgent, high and medium and the others are low. Contexts are tags, a project is
+// the project of the name, and due is the due date. The creation date is dropped, since it cannot be imported,
+// and the other key:value pairs are kept in the subject.
+func parseTodoTxtLine(line string) *model.ImportTODOItem {
+	item := &model.ImportTODOItem{}
+	isDate := func(s string) bool {
+		_, err := time.Parse(time.DateOnly, s)
+		return err == nil
+	}
+	isPriority := func(s string) bool {
+		return len(s) == 3 && s[0] == '(' && s[1] >= 'A' && s[1] <= 'Z' && s[2] == ')'
+	}
+
+	fields := strings.Fields(line)
+	if len(fields) > 0 && fields[0] == "x" {
+		item.Done = true
+		fields = fields[1:]
+		if len(fields) > 0 && isDate(fields[0]) {
+			item.CompletedAt, _ = parseImportTime("completed_at", fields[0])
+			fields = fields[1:]
+		}
+	}
+	if len(fields) > 0 && isPriority(fields[0]) {
+		item.Priority = todoTxtPriority(fields[0][1])
+		fields = fields[1:]
+	}
+	if len(fields) > 0 && isDate(fields[0]) {
+		fields = fields[1:]
+	}
+
+	var (
+		words    []string
+		projects []string
+		errs     []*model.ErrValidation
+	)
+	for _, field := range fields {
+		key, value, _ := strings.Cut(field, ":")
+		switch {
+		case len(field) > 1 && field[0] == '@':
+			item.Tags = append(item.Tags, field[1:])
+		case len(field) > 1 && field[0] == '+':
+			projects = append(projects, field[1:])
+		case key == "due" && value != "":
+			dueAt, err := parseImportTime("due", value)
+			if err != nil {
+				errs = append(errs, err.(*model.ErrValidation))
+				continue
+			}
+			item.DueAt = dueAt
+		case key == "pri" && len(value) == 1 && value[0] >= 'A' && value[0] <= 'Z':
+			// 完了した TODO は優先度を pri:A のように書く
+			item.Priority = todoTxtPriority(value[0])
+		default:
+			words = append(words, field)
+		}
+	}
+	item.Subject = strings.Join(words, " ")
+	switch {
+	case len(projects) == 1:
+		item.Project = projects[0]
+	case len(projects) > 1:
+		errs = append(errs, &model.ErrValidation{Field: "project", Message: "must be at most one +project"})
+	}
+	item.Err = model.JoinValidation(errs)
+	return item
+}
+
+// todoTxtPriority returns the priority of the todo.txt priority letter.
+func todoTxtPriority(letter byte) model.Priority {
+	switch letter {
+	case 'A':
+		return model.PriorityUrgent
+	case 'B':
+		return model.PriorityHigh
+	case 'C':
+		return model.PriorityMedium
+	}
+	return model.PriorityLow
+}
+
+// Import handles the endpoint that creates TODOs read from a CSV, JSON or todo.txt body in a single transaction.
+// When a TODO is invalid, the others are still tried without committing, so that every error is reported at once.
+func (h *TODOHandler) Import(ctx context.Context, req *model.ImportTODORequest) (*model.ImportTODOResponse, error) {
+	results := make([]*model.ImportResult, len(req.Items))
+	var (
+		items   []*model.ImportTODOItem
+		indexes []int
+	)
+	for i, item := range req.Items {
+		var errValidationFailed *model.ErrValidationFailed
+		switch {
+		case item.Err == nil:
+			item.Err = model.Validate(item)
+		case errors.As(item.Err, &errValidationFailed):
+			// 読み込めなかった値のエラーと合わせて、他の値の検証のエラーも報告する
+			item.Err = model.JoinValidation(append(errValidationFailed.Errors, model.Violations(item)...))
+		}
+		if item.Err != nil {
+			results[i] = &model.ImportResult{Line: item.Line, Status: model.ImportFailed, Err: item.Err}
+			continue
+		}
+		items = append(items, item)
+		indexes = append(indexes, i)
+	}
+
+	done, err := h.svc.ImportTODO(ctx, items, req.DryRun || len(items) < len(req.Items))
+	if err != nil {
+		return nil, err
+	}
+	// サービスに渡した TODO の位置をリクエストでの位置に戻す
+	for j, result := range done {
+		results[indexes[j]] = result
+	}
+
+	resp := &model.ImportTODOResponse{DryRun: req.DryRun, Results: results}
+	for _, result := range results {
+		if result.Err != nil {
+			result.Error = newProblem(result.Err)
+			resp.Failed++
+		}
+		if result.Status == model.ImportCreated {
+			resp.Committed = true
+		}
+	}
+	return resp, nil
+}
+
+// serveImport handles the "/todos/import" endpoint.
+func (h *TODOHandler) serveImport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		RenderError(w, &model.ErrMethodNotAllowed{Allowed: []string{http.MethodPost}})
+		return
+	}
+
+	query := r.URL.Query()
+	p := queryParser{query: query}
+	req := model.ImportTODORequest{Format: model.ImportFormat(query.Get("format"))}
+	p.bool("dry_run", &req.DryRun)
+	if err := p.validate(&req); err != nil {
+		RenderError(w, err)
+		return
+	}
+	format, err := negotiateImportFormat(req.Format, r.Header.Get("Content-Type"))
+	if err != nil {
+		w.Header().Set("Accept-Post", "text/csv, application/json, text/plain")
+		RenderError(w, err)
+		return
+	}
+
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxImportSize))
+	if err != nil {
+		var errMaxBytes *http.MaxBytesError
+		if errors.As(err, &errMaxBytes) {
+			err = &model.ErrRequestTooLarge{Limit: errMaxBytes.Limit}
+		}
+		RenderError(w, err)
+		return
+	}
+	if req.Items, err = readImportItems(format, data); err != nil {
+		RenderError(w, err)
+		return
+	}
+
+	resp, err := h.Import(r.Context(), &req)
+	if err != nil {
+		RenderError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
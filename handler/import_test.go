@@ -0,0 +1,186 @@
+package handler_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/TechBowl-japan/go-stations/db"
+	"github.com/TechBowl-japan/go-stations/handler"
+	"github.com/TechBowl-japan/go-stations/model"
+	"github.com/TechBowl-japan/go-stations/service"
+)
+
+func TestTODOHandlerImport(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		query         string
+		contentType   string
+		body          string
+		wantStatus    int
+		wantCommitted bool
+		wantStatuses  []model.ImportResultStatus
+		wantLines     []int
+		wantSubjects  []string
+		check         func(t *testing.T, results []*model.ImportResult)
+	}{
+		"CSV": {
+			contentType:   "text/csv",
+			body:          "\xef\xbb\xbfid,Subject,tags,done,priority\n7,Buy milk,\"home,errand\",,high\n8,\"Read, book\",,true,\n",
+			wantStatus:    http.StatusOK,
+			wantCommitted: true,
+			wantStatuses:  []model.ImportResultStatus{model.ImportCreated, model.ImportCreated},
+			wantLines:     []int{2, 3},
+			wantSubjects:  []string{"Buy milk", "Read, book"},
+			check: func(t *testing.T, results []*model.ImportResult) {
+				if todo := results[0].TODO; todo.ID != 1 || todo.Priority != model.PriorityHigh || len(todo.Tags) != 2 {
+					t.Errorf("unexpected todo, got = %+v", todo)
+				}
+				if todo := results[1].TODO; !todo.Done || todo.CompletedAt == nil {
+					t.Errorf("unexpected todo, got = %+v", todo)
+				}
+			},
+		},
+		"JSON": {
+			contentType:   "application/json",
+			body:          "[\n  {\"id\": 3, \"subject\": \"a\", \"tags\": [\"x\"]},\n  {\"subject\": \"b\", \"done\": true, \"completed_at\": \"2024-01-02T03:04:05Z\"}\n]",
+			wantStatus:    http.StatusOK,
+			wantCommitted: true,
+			wantStatuses:  []model.ImportResultStatus{model.ImportCreated, model.ImportCreated},
+			wantLines:     []int{2, 3},
+			wantSubjects:  []string{"a", "b"},
+			check: func(t *testing.T, results []*model.ImportResult) {
+				if todo := results[1].TODO; todo.CompletedAt == nil || todo.CompletedAt.Year() != 2024 {
+					t.Errorf("unexpected completed_at, got = %v", todo.CompletedAt)
+				}
+			},
+		},
+		"todo.txt": {
+			query:         "format=todotxt",
+			body:          "(A) 2024-01-01 Call mom +Family @phone due:2024-01-05 url:http://example.com\n\nx 2024-01-03 2024-01-01 Pay bills pri:B\n",
+			wantStatus:    http.StatusOK,
+			wantCommitted: true,
+			wantStatuses:  []model.ImportResultStatus{model.ImportCreated, model.ImportCreated},
+			wantLines:     []int{1, 3},
+			wantSubjects:  []string{"Call mom url:http://example.com", "Pay bills"},
+			check: func(t *testing.T, results []*model.ImportResult) {
+				todo := results[0].TODO
+				if todo.Priority != model.PriorityUrgent || todo.DueAt == nil || todo.ProjectID == nil || len(todo.Tags) != 1 || todo.Tags[0] != "phone" {
+					t.Errorf("unexpected todo, got = %+v", todo)
+				}
+				if todo := results[1].TODO; !todo.Done || todo.Priority != model.PriorityHigh {
+					t.Errorf("unexpected todo, got = %+v", todo)
+				}
+			},
+		},
+		"Dry run": {
+			query:        "dry_run=true",
+			contentType:  "text/plain; charset=utf-8",
+			body:         "Call mom +Family\n",
+			wantStatus:   http.StatusOK,
+			wantStatuses: []model.ImportResultStatus{model.ImportValid},
+			wantLines:    []int{1},
+			wantSubjects: []string{"Call mom"},
+		},
+		"Invalid rows": {
+			contentType:  "text/csv",
+			body:         "subject,due_at,parent_id\nfine,,\n,tomorrow,\nno parent,,100\nshort\n",
+			wantStatus:   http.StatusOK,
+			wantStatuses: []model.ImportResultStatus{model.ImportValid, model.ImportFailed, model.ImportFailed, model.ImportFailed},
+			wantLines:    []int{2, 3, 4, 5},
+			wantSubjects: []string{"fine"},
+			check: func(t *testing.T, results []*model.ImportResult) {
+				if got := len(results[1].Error.Errors); got != 2 {
+					t.Errorf("unexpected errors of line 3, got = %+v", results[1].Error.Errors)
+				}
+			},
+		},
+		"No subject column": {
+			contentType: "text/csv",
+			body:        "title\nBuy milk\n",
+			wantStatus:  http.StatusBadRequest,
+		},
+		"Broken JSON": {
+			contentType: "application/json",
+			body:        `[{"subject": "a"}`,
+			wantStatus:  http.StatusBadRequest,
+		},
+		"Unsupported type": {
+			contentType: "application/xml",
+			body:        "<todos/>",
+			wantStatus:  http.StatusUnsupportedMediaType,
+		},
+	}
+
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			todoDB, err := db.NewDB(filepath.Join(t.TempDir(), "todo.db"))
+			if err != nil {
+				t.Fatalf("failed to create db: %v", err)
+			}
+			t.Cleanup(func() { todoDB.Close() })
+			svc := service.NewTODOService(todoDB)
+
+			r := httptest.NewRequest(http.MethodPost, "/todos/import?"+c.query, strings.NewReader(c.body))
+			if c.contentType != "" {
+				r.Header.Set("Content-Type", c.contentType)
+			}
+			w := httptest.NewRecorder()
+			handler.NewTODOHandler(svc).ServeHTTP(w, r)
+
+			if w.Code != c.wantStatus {
+				t.Fatalf("unexpected status, got = %d, want = %d, body = %s", w.Code, c.wantStatus, w.Body)
+			}
+			if c.wantStatus != http.StatusOK {
+				return
+			}
+
+			var resp model.ImportTODOResponse
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if resp.Committed != c.wantCommitted || len(resp.Results) != len(c.wantStatuses) {
+				t.Fatalf("unexpected response, got = %+v", resp)
+			}
+			var subjects []string
+			for i, result := range resp.Results {
+				if result.Status != c.wantStatuses[i] || result.Line != c.wantLines[i] {
+					t.Errorf("unexpected result %d, got = %+v", i, result)
+				}
+				if (result.Status == model.ImportFailed) != (result.Error != nil) {
+					t.Errorf("unexpected error of result %d, got = %+v", i, result.Error)
+				}
+				if result.TODO != nil {
+					subjects = append(subjects, result.TODO.Subject)
+				}
+			}
+			if strings.Join(subjects, "|") != strings.Join(c.wantSubjects, "|") {
+				t.Errorf("unexpected subjects, got = %q, want = %q", subjects, c.wantSubjects)
+			}
+			if c.check != nil {
+				c.check(t, resp.Results)
+			}
+
+			// コミットされた場合だけ TODO が作成される
+			todos, err := svc.ReadTODO(context.Background(), 0, 10)
+			if err != nil {
+				t.Fatalf("failed to read todos: %v", err)
+			}
+			var want int
+			if c.wantCommitted {
+				want = len(c.wantSubjects)
+			}
+			if len(todos) != want {
+				t.Errorf("unexpected number of todos, got = %d, want = %d", len(todos), want)
+			}
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/TechBowl-japan/go-stations/handler"
+	"github.com/TechBowl-japan/go-stations/model"
+	"github.com/TechBowl-japan/go-stations/service"
+)
+
+const (
+	// ActorHeader is the request header naming who makes the request, recorded in the TODO history.
+	ActorHeader = "X-Actor"
+	// maxActorLength is the maximum length of an actor.
+	maxActorLength = 255
+)
+
+// Actor attributes the changes made by a request to the actor named in the X-Actor header.
+// Requests without the header are recorded without an actor.
+func Actor(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		actor := r.Header.Get(ActorHeader)
+		if actor == "" {
+			h.ServeHTTP(w, r)
+			return
+		}
+		if len(actor) > maxActorLength {
+			handler.RenderError(w, &model.ErrValidation{Field: ActorHeader, Message: "must be at most 255 characters"})
+			return
+		}
+		h.ServeHTTP(w, r.WithContext(service.WithActor(r.Context(), actor)))
+	})
+}
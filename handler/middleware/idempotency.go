@@ -0,0 +1,160 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/TechBowl-japan/go-stations/handler"
+	"github.com/TechBowl-japan/go-stations/model"
+	"github.com/TechBowl-japan/go-stations/service"
+)
+
+const (
+	// IdempotencyKeyHeader is the request header carrying the idempotency key.
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// idempotentReplayedHeader is set on responses replayed from a stored one.
+	idempotentReplayedHeader = "Idempotent-Replayed"
+	// maxIdempotencyKeyLength is the maximum length of an idempotency key.
+	maxIdempotencyKeyLength = 255
+	// maxIdempotentBodySize is the maximum size of a body buffered to compute the request hash.
+	maxIdempotentBodySize = 1 << 20
+	// idempotencyLease is how long a key stays reserved while its request is in progress.
+	// Retries get 409 Conflict until then, and can be processed after it if the server stopped in the middle.
+	idempotencyLease = time.Minute
+)
+
+// Idempotency makes mutating requests sent with an Idempotency-Key safe to retry.
+// The first response other than a 5xx is stored for ttl and replayed for retries with the same key,
+// while reusing the key with a different query or body is rejected with 422.
+// Keys are scoped to the client, told apart by its Authorization and X-Actor headers, and to the method and path,
+// so the same key sent by another client or to another endpoint is a different key.
+// Requests without the header are passed through unchanged.
+func Idempotency(svc *service.IdempotencyService, ttl time.Duration) func(http.Handler) http.Handler {
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(IdempotencyKeyHeader)
+			if key == "" || !isMutating(r.Method) {
+				h.ServeHTTP(w, r)
+				return
+			}
+			if len(key) > maxIdempotencyKeyLength {
+				handler.RenderError(w, &model.ErrValidation{Field: IdempotencyKeyHeader, Message: "must be at most 255 characters"})
+				return
+			}
+
+			body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxIdempotentBodySize))
+			if err != nil {
+				var errMaxBytes *http.MaxBytesError
+				if errors.As(err, &errMaxBytes) {
+					handler.RenderError(w, &model.ErrRequestTooLarge{Limit: errMaxBytes.Limit})
+					return
+				}
+				handler.RenderError(w, &model.ErrBadRequest{Message: "failed to read body"})
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			ctx := r.Context()
+			key = idempotencyScope(r, key)
+			stored, err := svc.ReserveIdempotencyKey(ctx, key, requestHash(r, body), idempotencyLease)
+			if err != nil {
+				handler.RenderError(w, err)
+				return
+			}
+			if stored != nil {
+				for k, v := range stored.Header {
+					w.Header()[k] = v
+				}
+				w.Header().Set(idempotentReplayedHeader, "true")
+				w.WriteHeader(stored.Status)
+				_, _ = w.Write(stored.Body)
+				return
+			}
+
+			// クライアントが切断しても結果は保存する
+			ctx = context.WithoutCancel(ctx)
+			rec := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+			defer func() {
+				// panic したハンドラーの予約は解放し、panic はそのまま http.Server に任せる
+				if p := recover(); p != nil {
+					if err := svc.ReleaseIdempotencyKey(ctx, key); err != nil {
+						log.Println("middleware: failed to release idempotency key, err =", err)
+					}
+					panic(p)
+				}
+			}()
+			h.ServeHTTP(rec, r)
+
+			if rec.status >= http.StatusInternalServerError {
+				err = svc.ReleaseIdempotencyKey(ctx, key)
+			} else {
+				err = svc.SaveIdempotentResponse(ctx, key, &model.IdempotentResponse{
+					Status: rec.status,
+					Header: w.Header().Clone(),
+					Body:   rec.body.Bytes(),
+				}, ttl)
+			}
+			if err != nil {
+				log.Println("middleware: failed to store idempotent response, err =", err)
+			}
+		})
+	}
+}
+
+// isMutating reports whether method changes the server state.
+func isMutating(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// idempotencyScope returns key scoped to the client and the endpoint of r.
+// The credentials are hashed together with the rest, so that they are not stored.
+func idempotencyScope(r *http.Request, key string) string {
+	h := sha256.New()
+	for _, v := range []string{r.Header.Get("Authorization"), r.Header.Get(ActorHeader), r.Method, r.URL.Path, key} {
+		io.WriteString(h, v)
+		// 区切りを入れて、値の境界をずらした別の組み合わせと区別する
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// requestHash identifies the payload of r, so that a key cannot be reused for another request.
+func requestHash(r *http.Request, body []byte) string {
+	h := sha256.New()
+	io.WriteString(h, r.Method+" "+r.URL.RequestURI()+"\n")
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// responseRecorder passes a response through while keeping a copy of its status and body.
+type responseRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (rec *responseRecorder) WriteHeader(status int) {
+	if !rec.wroteHeader {
+		rec.status = status
+		rec.wroteHeader = true
+	}
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *responseRecorder) Write(b []byte) (int, error) {
+	rec.wroteHeader = true
+	rec.body.Write(b)
+	return rec.ResponseWriter.Write(b)
+}
@@ -0,0 +1,90 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/TechBowl-japan/go-stations/db"
+	"github.com/TechBowl-japan/go-stations/handler/middleware"
+	"github.com/TechBowl-japan/go-stations/service"
+)
+
+func TestIdempotency(t *testing.T) {
+	t.Parallel()
+
+	todoDB, err := db.NewDB(filepath.Join(t.TempDir(), "todo.db"))
+	if err != nil {
+		t.Fatalf("failed to create db: %v", err)
+	}
+	t.Cleanup(func() { todoDB.Close() })
+
+	var calls int
+	status := http.StatusCreated
+	h := middleware.Idempotency(service.NewIdempotencyService(todoDB), time.Hour)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if status == 0 {
+			panic("handler failed")
+		}
+		w.WriteHeader(status)
+		w.Write([]byte("created"))
+	}))
+
+	do := func(path, actor, key, body string) (w *httptest.ResponseRecorder, panicked bool) {
+		r := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		r.Header.Set(middleware.IdempotencyKeyHeader, key)
+		if actor != "" {
+			r.Header.Set(middleware.ActorHeader, actor)
+		}
+		w = httptest.NewRecorder()
+		defer func() {
+			panicked = recover() != nil
+		}()
+		h.ServeHTTP(w, r)
+		return w, false
+	}
+
+	steps := []struct {
+		name      string
+		path      string
+		actor     string
+		key       string
+		body      string
+		status    int
+		wantCode  int
+		wantCalls int
+	}{
+		{name: "First request", key: "a", body: "x", status: http.StatusCreated, wantCode: http.StatusCreated, wantCalls: 1},
+		{name: "Retry is replayed", key: "a", body: "x", status: http.StatusCreated, wantCode: http.StatusCreated, wantCalls: 1},
+		{name: "Reuse with another body", key: "a", body: "y", status: http.StatusCreated, wantCode: http.StatusUnprocessableEntity, wantCalls: 1},
+		{name: "Server error is not stored", key: "b", body: "x", status: http.StatusInternalServerError, wantCode: http.StatusInternalServerError, wantCalls: 2},
+		{name: "Retry after server error", key: "b", body: "x", status: http.StatusCreated, wantCode: http.StatusCreated, wantCalls: 3},
+		// キーはクライアントとエンドポイントごとに別になる
+		{name: "Same key to another path", path: "/projects", key: "a", body: "y", status: http.StatusCreated, wantCode: http.StatusCreated, wantCalls: 4},
+		{name: "Same key by another actor", actor: "bob", key: "a", body: "y", status: http.StatusCreated, wantCode: http.StatusCreated, wantCalls: 5},
+		{name: "Retry by another actor is replayed", actor: "bob", key: "a", body: "y", status: http.StatusCreated, wantCode: http.StatusCreated, wantCalls: 5},
+		// panic したリクエストは処理中のまま残らない
+		{name: "Panic is not stored", key: "c", body: "x", status: 0, wantCalls: 6},
+		{name: "Retry after panic", key: "c", body: "x", status: http.StatusCreated, wantCode: http.StatusCreated, wantCalls: 7},
+	}
+
+	for _, step := range steps {
+		status = step.status
+		if step.path == "" {
+			step.path = "/todos"
+		}
+		w, panicked := do(step.path, step.actor, step.key, step.body)
+		if panicked != (step.status == 0) {
+			t.Errorf("%s: unexpected panic, got = %v", step.name, panicked)
+		}
+		if step.status != 0 && w.Code != step.wantCode {
+			t.Errorf("%s: unexpected status, got = %d, want = %d", step.name, w.Code, step.wantCode)
+		}
+		if calls != step.wantCalls {
+			t.Errorf("%s: unexpected handler calls, got = %d, want = %d", step.name, calls, step.wantCalls)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/TechBowl-japan/go-stations/model"
+)
+
+// newReadTODOResponse returns the response of the TODO list endpoints for page read with view.
+func newReadTODOResponse(page *model.TODOPage, view *model.TODOView) *model.ReadTODOResponse {
+	return &model.ReadTODOResponse{
+		Todos:      page.Todos,
+		NextCursor: page.NextCursor,
+		PrevCursor: page.PrevCursor,
+		TotalCount: page.TotalCount,
+		View:       view,
+	}
+}
+
+// setPageLinks sets the Link header (RFC 8288) of resp read by r, with the URLs of its first, next and previous pages.
+// The URLs keep the other query parameters of r, such as the filters.
+func setPageLinks(w http.ResponseWriter, r *http.Request, resp *model.ReadTODOResponse) {
+	link := func(rel, cursor string) string {
+		query := r.URL.Query()
+		query.Del("prev_id")
+		query.Del("cursor")
+		if cursor != "" {
+			query.Set("cursor", cursor)
+		}
+		u := *r.URL
+		u.RawQuery = query.Encode()
+		return "<" + u.RequestURI() + `>; rel="` + rel + `"`
+	}
+
+	links := []string{link("first", "")}
+	if resp.NextCursor != "" {
+		links = append(links, link("next", resp.NextCursor))
+	}
+	if resp.PrevCursor != "" {
+		links = append(links, link("prev", resp.PrevCursor))
+	}
+	w.Header().Set("Link", strings.Join(links, ", "))
+}
@@ -0,0 +1,88 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime"
+	"strings"
+
+	"github.com/TechBowl-japan/go-stations/model"
+)
+
+const (
+	mergePatchContentType = "application/merge-patch+json"
+	jsonPatchContentType  = "application/json-patch+json"
+)
+
+// errUnsupportedPatchType is returned by decodeTODOPatch for an unknown Content-Type.
+var errUnsupportedPatchType error = &model.ErrUnsupportedMediaType{Message: "unsupported content type"}
+
+// jsonPatchOperation is an operation of RFC 6902 JSON Patch.
+type jsonPatchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	Value json.RawMessage `json:"value"`
+}
+
+// decodeTODOPatch decodes the body of PATCH /todos/{id} according to contentType.
+// JSON Patch is accepted for add, replace and remove of top-level fields, which are
+// translated into the equivalent merge patch.
+func decodeTODOPatch(body io.Reader, contentType string) (*model.TODOPatch, error) {
+	mediaType := "application/json"
+	if contentType != "" {
+		var err error
+		mediaType, _, err = mime.ParseMediaType(contentType)
+		if err != nil {
+			return nil, errUnsupportedPatchType
+		}
+	}
+
+	switch mediaType {
+	case "application/json", mergePatchContentType:
+	case jsonPatchContentType:
+		merge, err := jsonPatchToMergePatch(body)
+		if err != nil {
+			return nil, err
+		}
+		body = bytes.NewReader(merge)
+	default:
+		return nil, errUnsupportedPatchType
+	}
+
+	var patch model.TODOPatch
+	if err := decodeJSONValue(body, &patch); err != nil {
+		return nil, err
+	}
+	return &patch, nil
+}
+
+// jsonPatchToMergePatch translates a JSON Patch document into a merge patch.
+func jsonPatchToMergePatch(body io.Reader) ([]byte, error) {
+	var ops []jsonPatchOperation
+	if err := decodeJSONValue(body, &ops); err != nil {
+		return nil, err
+	}
+
+	merge := make(map[string]json.RawMessage, len(ops))
+	for _, op := range ops {
+		field := strings.TrimPrefix(op.Path, "/")
+		if field == op.Path || field == "" || strings.Contains(field, "/") {
+			return nil, &model.ErrValidation{Field: "path", Message: "unsupported path " + op.Path}
+		}
+		field = strings.NewReplacer("~1", "/", "~0", "~").Replace(field)
+
+		switch op.Op {
+		case "add", "replace":
+			if op.Value == nil {
+				return nil, &model.ErrValidation{Field: "value", Message: "is required for " + op.Op}
+			}
+			merge[field] = op.Value
+		case "remove":
+			merge[field] = json.RawMessage("null")
+		default:
+			return nil, &model.ErrValidation{Field: "op", Message: "unsupported op " + op.Op}
+		}
+	}
+	return json.Marshal(merge)
+}
@@ -0,0 +1,206 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/TechBowl-japan/go-stations/model"
+	"github.com/TechBowl-japan/go-stations/service"
+)
+
+// A ProjectHandler implements handling REST endpoints for projects.
+type ProjectHandler struct {
+	svc     *service.ProjectService
+	todoSvc *service.TODOService
+}
+
+// NewProjectHandler returns ProjectHandler based http.Handler.
+func NewProjectHandler(svc *service.ProjectService, todoSvc *service.TODOService) *ProjectHandler {
+	return &ProjectHandler{
+		svc:     svc,
+		todoSvc: todoSvc,
+	}
+}
+
+// Create handles the endpoint that creates the project.
+func (h *ProjectHandler) Create(ctx context.Context, req *model.CreateProjectRequest) (*model.CreateProjectResponse, error) {
+	project, err := h.svc.CreateProject(ctx, req.Name, req.Description)
+	if err != nil {
+		return nil, err
+	}
+	return &model.CreateProjectResponse{Project: *project}, nil
+}
+
+// Read handles the endpoint that reads the projects.
+func (h *ProjectHandler) Read(ctx context.Context, req *model.ReadProjectRequest) (*model.ReadProjectResponse, error) {
+	projects, err := h.svc.ReadProject(ctx, req.PrevID, req.Size)
+	if err != nil {
+		return nil, err
+	}
+	return &model.ReadProjectResponse{Projects: projects}, nil
+}
+
+// ReadByID handles the endpoint that reads the project.
+func (h *ProjectHandler) ReadByID(ctx context.Context, req *model.ReadProjectByIDRequest) (*model.ReadProjectByIDResponse, error) {
+	project, err := h.svc.ReadProjectByID(ctx, req.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &model.ReadProjectByIDResponse{Project: *project}, nil
+}
+
+// Update handles the endpoint that updates the project.
+func (h *ProjectHandler) Update(ctx context.Context, req *model.UpdateProjectRequest) (*model.UpdateProjectResponse, error) {
+	project, err := h.svc.UpdateProject(ctx, req.ID, req.Name, req.Description)
+	if err != nil {
+		return nil, err
+	}
+	return &model.UpdateProjectResponse{Project: *project}, nil
+}
+
+// Delete handles the endpoint that deletes the project.
+func (h *ProjectHandler) Delete(ctx context.Context, req *model.DeleteProjectRequest) (*model.DeleteProjectResponse, error) {
+	if err := h.svc.DeleteProject(ctx, req.ID, req.Policy); err != nil {
+		return nil, err
+	}
+	return &model.DeleteProjectResponse{}, nil
+}
+
+// ReadTODO handles the endpoint that reads the TODOs in the project.
+func (h *ProjectHandler) ReadTODO(ctx context.Context, req *model.ReadProjectTODORequest) (*model.ReadTODOResponse, error) {
+	if _, err := h.svc.ReadProjectByID(ctx, req.ProjectID); err != nil {
+		return nil, err
+	}
+	page, err := h.todoSvc.ListTODOPage(ctx, &model.TODOQuery{
+		PrevID:        req.PrevID,
+		Size:          req.Size,
+		Status:        req.Status,
+		Due:           req.Due,
+		DueWithinDays: req.DueWithinDays,
+		Filter:        req.Where,
+		Order:         req.Order,
+		Tags:          req.Tags,
+		TagMatch:      req.TagMatch,
+		ProjectID:     &req.ProjectID,
+		Cursor:        req.Cursor,
+		CountTotal:    req.TotalCount,
+		View:          req.View,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return newReadTODOResponse(page, req.View), nil
+}
+
+// ServeHTTP implements http.Handler to accept HTTP requests for project endpoints.
+func (h *ProjectHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, action, ok := splitIDPath(r.URL.Path, "/projects")
+	if !ok {
+		h.serveCollection(w, r)
+		return
+	}
+
+	var (
+		resp interface{}
+		err  error
+	)
+	switch {
+	case action == "todos" && r.Method == http.MethodGet:
+		req, perr := parseReadTODORequest(r.URL.Query())
+		if perr != nil {
+			RenderError(w, perr)
+			return
+		}
+		resp, err = h.ReadTODO(ctx, &model.ReadProjectTODORequest{ProjectID: id, ReadTODORequest: *req})
+
+	case action != "":
+		RenderError(w, &model.ErrNotFound{Resource: "endpoint"})
+		return
+
+	case r.Method == http.MethodGet:
+		resp, err = h.ReadByID(ctx, &model.ReadProjectByIDRequest{ID: id})
+
+	case r.Method == http.MethodPut:
+		var req model.UpdateProjectRequest
+		if err := decodeJSON(w, r, &req); err != nil {
+			RenderError(w, err)
+			return
+		}
+		req.ID = id
+		resp, err = h.Update(ctx, &req)
+
+	case r.Method == http.MethodDelete:
+		req := model.DeleteProjectRequest{
+			ID:     id,
+			Policy: model.ProjectDeletePolicy(r.URL.Query().Get("policy")),
+		}
+		if err := model.Validate(&req); err != nil {
+			RenderError(w, err)
+			return
+		}
+		resp, err = h.Delete(ctx, &req)
+
+	default:
+		RenderError(w, &model.ErrMethodNotAllowed{Allowed: []string{http.MethodGet, http.MethodPut, http.MethodDelete}})
+		return
+	}
+	if err != nil {
+		RenderError(w, err)
+		return
+	}
+
+	if page, ok := resp.(*model.ReadTODOResponse); ok {
+		setPageLinks(w, r, page)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// serveCollection handles "/projects" endpoints.
+func (h *ProjectHandler) serveCollection(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	switch r.Method {
+	case http.MethodGet:
+		var req model.ReadProjectRequest
+		p := queryParser{query: r.URL.Query()}
+		p.int64("prev_id", &req.PrevID)
+		p.int64("size", &req.Size)
+		if err := p.validate(&req); err != nil {
+			RenderError(w, err)
+			return
+		}
+
+		resp, err := h.Read(ctx, &req)
+		if err != nil {
+			RenderError(w, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(resp)
+
+	case http.MethodPost:
+		var req model.CreateProjectRequest
+		if err := decodeJSON(w, r, &req); err != nil {
+			RenderError(w, err)
+			return
+		}
+
+		resp, err := h.Create(ctx, &req)
+		if err != nil {
+			RenderError(w, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(resp)
+
+	default:
+		RenderError(w, &model.ErrMethodNotAllowed{Allowed: []string{http.MethodGet, http.MethodPost}})
+	}
+}
@@ -3,14 +3,31 @@ package router
 import (
 	"database/sql"
 	"net/http"
+	"time"
 
 	"github.com/TechBowl-japan/go-stations/handler"
+	"github.com/TechBowl-japan/go-stations/handler/middleware"
 	"github.com/TechBowl-japan/go-stations/service"
 )
 
+// Config は NewRouterWithConfig で変更できる設定
+type Config struct {
+	// TrashRetention はゴミ箱の TODO を残しておく期間で、POST /todos/purge の既定値と下限になる
+	TrashRetention time.Duration
+}
+
 // NewRouter はエンドポイントを登録して http.Handler を返す
 func NewRouter(todoDB *sql.DB) http.Handler {
+	return NewRouterWithConfig(todoDB, &Config{TrashRetention: service.DefaultTrashRetention})
+}
+
+// NewRouterWithConfig は cfg の設定でエンドポイントを登録して http.Handler を返す
+func NewRouterWithConfig(todoDB *sql.DB, cfg *Config) http.Handler {
+	// Idempotency-Key を付けたリクエストのレスポンスを保存しておく期間
+	const idempotencyKeyTTL = 24 * time.Hour
+
 	mux := http.NewServeMux()
+	idempotency := middleware.Idempotency(service.NewIdempotencyService(todoDB), idempotencyKeyTTL)
 
 	// 例: /health にアクセスすると "ok" を返す
 	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
@@ -20,8 +37,22 @@ func NewRouter(todoDB *sql.DB) http.Handler {
 
 	todoService := service.NewTODOService(todoDB)
 	todoHandler := handler.NewTODOHandler(todoService)
+	todoHandler.SetTrashRetention(cfg.TrashRetention)
 	// 例: /todos にアクセスすると TodoHandler が処理する
-	mux.HandleFunc("/todos/", todoHandler.ServeHTTP)
+	mux.Handle("/todos/", middleware.Actor(idempotency(todoHandler)))
+	// /todos/ws では WebSocket で TODO の変更を購読し、変更を送る
+	mux.Handle("/todos/ws", middleware.Actor(handler.NewTODOSocketHandler(todoService)))
+
+	tagHandler := handler.NewTagHandler(service.NewTagService(todoDB))
+	mux.Handle("/tags", tagHandler)
+
+	projectHandler := middleware.Actor(idempotency(handler.NewProjectHandler(service.NewProjectService(todoDB), todoService)))
+	mux.Handle("/projects", projectHandler)
+	mux.Handle("/projects/", projectHandler)
+
+	webhookHandler := idempotency(handler.NewWebhookHandler(service.NewWebhookService(todoDB)))
+	mux.Handle("/webhooks", webhookHandler)
+	mux.Handle("/webhooks/", webhookHandler)
 
 	return mux
 }
@@ -0,0 +1,39 @@
+//go:build !sqlite_fts5
+
+package handler_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/TechBowl-japan/go-stations/db"
+	"github.com/TechBowl-japan/go-stations/handler"
+	"github.com/TechBowl-japan/go-stations/model"
+	"github.com/TechBowl-japan/go-stations/service"
+)
+
+// TestTODOHandlerSearchUnavailable checks the response of the build without FTS5.
+func TestTODOHandlerSearchUnavailable(t *testing.T) {
+	t.Parallel()
+
+	todoDB, err := db.NewDB(filepath.Join(t.TempDir(), "todo.db"))
+	if err != nil {
+		t.Fatalf("failed to create db: %v", err)
+	}
+	t.Cleanup(func() { todoDB.Close() })
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/todos/search?q=milk", nil)
+	handler.NewTODOHandler(service.NewTODOService(todoDB)).ServeHTTP(w, r)
+
+	if w.Code != http.StatusNotImplemented {
+		t.Fatalf("unexpected status, got = %d, body = %s", w.Code, w.Body)
+	}
+	var problem model.Problem
+	if err := json.NewDecoder(w.Body).Decode(&problem); err != nil || problem.Code != "not_implemented" {
+		t.Errorf("unexpected problem, got = %+v, err = %v", problem, err)
+	}
+}
@@ -0,0 +1,328 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/TechBowl-japan/go-stations/handler/websocket"
+	"github.com/TechBowl-japan/go-stations/model"
+	"github.com/TechBowl-japan/go-stations/service"
+)
+
+const (
+	// socketPingInterval is the interval of the pings keeping the connection alive.
+	socketPingInterval = 30 * time.Second
+	// socketReadTimeout is how long a connection can stay silent, including pongs, before it is closed.
+	socketReadTimeout = 2 * socketPingInterval
+	// socketWriteTimeout is how long a message can take to be written; slower clients are disconnected.
+	socketWriteTimeout = 10 * time.Second
+	// socketQueueSize is the number of outgoing messages buffered per connection.
+	// When it is full, reading events and commands waits for the client to catch up.
+	socketQueueSize = 64
+)
+
+// A TODOSocketHandler serves a WebSocket endpoint to subscribe to TODO changes and to send mutations.
+type TODOSocketHandler struct {
+	svc      *service.TODOService
+	todo     *TODOHandler
+	upgrader websocket.Upgrader
+}
+
+// NewTODOSocketHandler returns TODOSocketHandler based http.Handler.
+func NewTODOSocketHandler(svc *service.TODOService) *TODOSocketHandler {
+	return &TODOSocketHandler{
+		svc:  svc,
+		todo: NewTODOHandler(svc),
+		upgrader: websocket.Upgrader{
+			MaxMessageSize: maxBodySize,
+			ReadTimeout:    socketReadTimeout,
+		},
+	}
+}
+
+// ServeHTTP implements http.Handler to upgrade the request and serve the connection until it is closed.
+func (h *TODOSocketHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	conn, err := h.upgrader.Upgrade(w, r)
+	if err != nil {
+		if errors.Is(err, websocket.ErrBadHandshake) {
+			w.Header().Set("Upgrade", "websocket")
+			RenderError(w, &model.ErrBadRequest{Message: err.Error()})
+			return
+		}
+		if errors.Is(err, websocket.ErrBadOrigin) {
+			RenderError(w, &model.ErrForbidden{Message: err.Error()})
+			return
+		}
+		log.Println("handler: failed to upgrade to websocket, err =", err)
+		return
+	}
+	defer conn.Close()
+
+	// 接続より前の変更は送らない
+	lastID, err := h.svc.LatestTODOEventID(r.Context())
+	if err != nil {
+		log.Println("handler: failed to read the latest todo event, err =", err)
+		_ = conn.WriteClose(websocket.CloseInternalError, "")
+		return
+	}
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	s := &socketSession{
+		conn:          conn,
+		out:           make(chan *model.SocketMessage, socketQueueSize),
+		subscriptions: make(map[string]*model.TODOFilter),
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		defer cancel()
+		s.writeLoop(ctx)
+	}()
+	go func() {
+		defer wg.Done()
+		defer cancel()
+		_ = h.svc.WatchTODOEvents(ctx, lastID, func(event *model.TODOEvent) error {
+			return s.publish(ctx, event)
+		})
+	}()
+
+	// 書き込みや購読が失敗したら接続を閉じ、読み込みを終わらせる。
+	// すでに close フレームを送っている場合 WriteClose は何もしない
+	go func() {
+		<-ctx.Done()
+		_ = conn.WriteClose(websocket.CloseNormalClosure, "")
+		conn.Close()
+	}()
+
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			break
+		}
+		if err := s.send(ctx, h.handleCommand(ctx, s, data)); err != nil {
+			break
+		}
+	}
+
+	cancel()
+	wg.Wait()
+}
+
+// handleCommand runs the command encoded in data and returns the message reporting its result.
+func (h *TODOSocketHandler) handleCommand(ctx context.Context, s *socketSession, data []byte) *model.SocketMessage {
+	var cmd model.SocketCommand
+	if err := decodeJSONValue(bytes.NewReader(data), &cmd); err != nil {
+		return &model.SocketMessage{Type: model.SocketError, Error: newProblem(err)}
+	}
+
+	result, err := h.runCommand(ctx, s, &cmd)
+	if err != nil {
+		return &model.SocketMessage{Type: model.SocketError, ID: cmd.ID, Error: newProblem(err)}
+	}
+
+	switch cmd.Type {
+	case model.SocketSubscribe:
+		return &model.SocketMessage{Type: model.SocketSubscribed, ID: cmd.ID}
+	case model.SocketUnsubscribe:
+		return &model.SocketMessage{Type: model.SocketUnsubscribed, ID: cmd.ID}
+	}
+	return &model.SocketMessage{Type: model.SocketResult, ID: cmd.ID, Result: result}
+}
+
+// runCommand validates cmd and runs it through TODOHandler, so that commands behave as the REST endpoints.
+func (h *TODOSocketHandler) runCommand(ctx context.Context, s *socketSession, cmd *model.SocketCommand) (interface{}, error) {
+	if err := model.Validate(cmd); err != nil {
+		return nil, err
+	}
+
+	switch cmd.Type {
+	case model.SocketSubscribe:
+		if err := model.Validate(&cmd.Filter); err != nil {
+			return nil, err
+		}
+		s.subscribe(cmd.ID, &cmd.Filter)
+		return nil, nil
+	case model.SocketUnsubscribe:
+		if !s.unsubscribe(cmd.ID) {
+			return nil, &model.ErrNotFound{Resource: "subscription"}
+		}
+		return nil, nil
+	case model.SocketCreate:
+		var req model.CreateTODORequest
+		if err := decodeEmbeddedJSON(cmd.Data, &req); err != nil {
+			return nil, err
+		}
+		return h.todo.Create(ctx, &req)
+	case model.SocketUpdate:
+		var req model.UpdateTODORequest
+		if err := decodeEmbeddedJSON(cmd.Data, &req); err != nil {
+			return nil, err
+		}
+		return h.todo.Update(ctx, &req)
+	case model.SocketPatch:
+		if cmd.TODOID == 0 {
+			return nil, &model.ErrValidation{Field: "todo_id", Message: "is required"}
+		}
+		req := model.PatchTODORequest{ID: cmd.TODOID}
+		if err := decodeEmbeddedJSON(cmd.Data, &req.TODOPatch); err != nil {
+			return nil, err
+		}
+		return h.todo.Patch(ctx, &req)
+	case model.SocketDelete:
+		var req model.DeleteTODORequest
+		if err := decodeEmbeddedJSON(cmd.Data, &req); err != nil {
+			return nil, err
+		}
+		return h.todo.Delete(ctx, &req)
+	case model.SocketComplete:
+		if cmd.TODOID == 0 {
+			return nil, &model.ErrValidation{Field: "todo_id", Message: "is required"}
+		}
+		return h.todo.Complete(ctx, &model.CompleteTODORequest{ID: cmd.TODOID})
+	case model.SocketReopen:
+		if cmd.TODOID == 0 {
+			return nil, &model.ErrValidation{Field: "todo_id", Message: "is required"}
+		}
+		return h.todo.Reopen(ctx, &model.ReopenTODORequest{ID: cmd.TODOID})
+	}
+	return nil, &model.ErrBadRequest{Message: "unknown command"}
+}
+
+// A socketSession holds the state of a WebSocket connection.
+type socketSession struct {
+	conn *websocket.Conn
+	out  chan *model.SocketMessage
+
+	mu            sync.Mutex
+	subscriptions map[string]*model.TODOFilter
+}
+
+// subscribe starts or replaces the subscription id.
+func (s *socketSession) subscribe(id string, filter *model.TODOFilter) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.subscriptions[id] = filter
+}
+
+// unsubscribe stops the subscription id and reports whether it existed.
+func (s *socketSession) unsubscribe(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.subscriptions[id]
+	delete(s.subscriptions, id)
+	return ok
+}
+
+// publish sends event to every subscription whose filter matches the TODO before or after the change.
+func (s *socketSession) publish(ctx context.Context, event *model.TODOEvent) error {
+	type match struct {
+		id      string
+		removed bool
+	}
+
+	s.mu.Lock()
+	var matches []match
+	for id, filter := range s.subscriptions {
+		before, after := filter.Match(event.OldValue), filter.Match(event.NewValue)
+		if before || after {
+			matches = append(matches, match{id: id, removed: !after})
+		}
+	}
+	s.mu.Unlock()
+	if len(matches) == 0 {
+		return nil
+	}
+
+	patch, err := mergePatch(event.OldValue, event.NewValue)
+	if err != nil {
+		return err
+	}
+	for _, m := range matches {
+		if err := s.send(ctx, &model.SocketMessage{Type: model.SocketEvent, ID: m.id, Event: event, Patch: patch, Removed: m.removed}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// send queues msg, waiting while the queue is full.
+func (s *socketSession) send(ctx context.Context, msg *model.SocketMessage) error {
+	select {
+	case s.out <- msg:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// writeLoop writes the queued messages and periodic pings until ctx is done or a write fails.
+func (s *socketSession) writeLoop(ctx context.Context) {
+	ticker := time.NewTicker(socketPingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg := <-s.out:
+			data, err := json.Marshal(msg)
+			if err != nil {
+				log.Println("handler: failed to encode websocket message, err =", err)
+				return
+			}
+			if err := s.conn.WriteMessage(websocket.TextMessage, data, time.Now().Add(socketWriteTimeout)); err != nil {
+				return
+			}
+		case <-ticker.C:
+			if err := s.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(socketWriteTimeout)); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// mergePatch returns the JSON Merge Patch (RFC 7396) turning the top-level fields of oldValue into newValue.
+// A nil newValue results in null, and a nil oldValue in the whole newValue.
+func mergePatch(oldValue, newValue *model.Todo) (json.RawMessage, error) {
+	if newValue == nil {
+		return json.RawMessage("null"), nil
+	}
+	newJSON, err := json.Marshal(newValue)
+	if err != nil || oldValue == nil {
+		return newJSON, err
+	}
+	oldJSON, err := json.Marshal(oldValue)
+	if err != nil {
+		return nil, err
+	}
+
+	var oldFields, newFields map[string]json.RawMessage
+	if err := json.Unmarshal(oldJSON, &oldFields); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(newJSON, &newFields); err != nil {
+		return nil, err
+	}
+	patch := make(map[string]json.RawMessage)
+	for name, value := range newFields {
+		if !bytes.Equal(oldFields[name], value) {
+			patch[name] = value
+		}
+	}
+	for name := range oldFields {
+		if _, ok := newFields[name]; !ok {
+			patch[name] = json.RawMessage("null")
+		}
+	}
+	return json.Marshal(patch)
+}
@@ -0,0 +1,174 @@
+package handler_test
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/TechBowl-japan/go-stations/db"
+	"github.com/TechBowl-japan/go-stations/handler"
+	"github.com/TechBowl-japan/go-stations/model"
+	"github.com/TechBowl-japan/go-stations/service"
+)
+
+func TestTODOSocketHandler(t *testing.T) {
+	t.Parallel()
+
+	todoDB, err := db.NewDB(filepath.Join(t.TempDir(), "todo.db"))
+	if err != nil {
+		t.Fatalf("failed to create db: %v", err)
+	}
+	t.Cleanup(func() { todoDB.Close() })
+
+	srv := httptest.NewServer(handler.NewTODOSocketHandler(service.NewTODOService(todoDB)))
+	t.Cleanup(srv.Close)
+
+	c := dialSocket(t, srv.URL)
+	c.send(t, `{"type":"subscribe","id":"open","filter":{"status":"open"}}`)
+	if msg := c.read(t); msg.Type != model.SocketSubscribed || msg.ID != "open" {
+		t.Fatalf("unexpected message, got = %+v", msg)
+	}
+
+	c.send(t, `{"type":"create","id":"c1","data":{"subject":"via socket"}}`)
+	c.send(t, `{"type":"complete","id":"c2","todo_id":1}`)
+	c.send(t, `{"type":"create","id":"c3","data":{}}`)
+
+	// コマンドの結果とイベントは到着順が決まらないため、種類ごとに集める
+	results := make(map[string]*model.SocketMessage)
+	var events []*model.SocketMessage
+	for len(results) < 3 || len(events) < 2 {
+		msg := c.read(t)
+		switch msg.Type {
+		case model.SocketEvent:
+			events = append(events, msg)
+		default:
+			results[msg.ID] = msg
+		}
+	}
+
+	if results["c1"].Type != model.SocketResult || results["c2"].Type != model.SocketResult {
+		t.Errorf("unexpected results, got = %+v, %+v", results["c1"], results["c2"])
+	}
+	if msg := results["c3"]; msg.Type != model.SocketError || msg.Error == nil || msg.Error.Code != "validation_failed" {
+		t.Errorf("unexpected error, got = %+v", msg)
+	}
+	if events[0].Event.Action != model.TODOActionCreate || events[0].Removed {
+		t.Errorf("unexpected create event, got = %+v", events[0])
+	}
+	// 完了によって status=open の条件から外れる
+	if !events[1].Removed || string(events[1].Patch) == "" || !strings.Contains(string(events[1].Patch), `"done":true`) {
+		t.Errorf("unexpected complete event, got = %+v, patch = %s", events[1], events[1].Patch)
+	}
+}
+
+func TestTODOSocketHandlerRejectsPlainRequest(t *testing.T) {
+	t.Parallel()
+
+	w := httptest.NewRecorder()
+	handler.NewTODOSocketHandler(nil).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/todos/ws", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("unexpected status, got = %d", w.Code)
+	}
+}
+
+func TestTODOSocketHandlerRejectsOtherOrigin(t *testing.T) {
+	t.Parallel()
+
+	r := httptest.NewRequest(http.MethodGet, "/todos/ws", nil)
+	r.Header.Set("Upgrade", "websocket")
+	r.Header.Set("Connection", "Upgrade")
+	r.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+	r.Header.Set("Sec-WebSocket-Version", "13")
+	r.Header.Set("Origin", "https://evil.example.com")
+	w := httptest.NewRecorder()
+	handler.NewTODOSocketHandler(nil).ServeHTTP(w, r)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("unexpected status, got = %d", w.Code)
+	}
+}
+
+// socketClient is a minimal WebSocket client for tests.
+type socketClient struct {
+	conn net.Conn
+	br   *bufio.Reader
+}
+
+func dialSocket(t *testing.T, url string) *socketClient {
+	t.Helper()
+
+	conn, err := net.Dial("tcp", strings.TrimPrefix(url, "http://"))
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	io.WriteString(conn, "GET /todos/ws HTTP/1.1\r\nHost: test\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n"+
+		"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\nSec-WebSocket-Version: 13\r\n\r\n")
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, nil)
+	if err != nil {
+		t.Fatalf("failed to read handshake: %v", err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols || resp.Header.Get("Sec-WebSocket-Accept") != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("unexpected handshake, status = %d, header = %v", resp.StatusCode, resp.Header)
+	}
+	return &socketClient{conn: conn, br: br}
+}
+
+// send writes text as a masked text frame.
+func (c *socketClient) send(t *testing.T, text string) {
+	t.Helper()
+
+	mask := []byte{1, 2, 3, 4}
+	frame := []byte{0x81, 0x80 | byte(len(text))}
+	frame = append(frame, mask...)
+	for i := 0; i < len(text); i++ {
+		frame = append(frame, text[i]^mask[i%4])
+	}
+	if _, err := c.conn.Write(frame); err != nil {
+		t.Fatalf("failed to write: %v", err)
+	}
+}
+
+// read reads the next text frame as a message.
+func (c *socketClient) read(t *testing.T) *model.SocketMessage {
+	t.Helper()
+
+	var header [2]byte
+	if _, err := io.ReadFull(c.br, header[:]); err != nil {
+		t.Fatalf("failed to read: %v", err)
+	}
+	length := int(header[1] & 0x7f)
+	switch length {
+	case 126:
+		var ext [2]byte
+		io.ReadFull(c.br, ext[:])
+		length = int(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		io.ReadFull(c.br, ext[:])
+		length = int(binary.BigEndian.Uint64(ext[:]))
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(c.br, payload); err != nil {
+		t.Fatalf("failed to read: %v", err)
+	}
+	if header[0]&0x0f != 1 {
+		t.Fatalf("unexpected opcode %d", header[0]&0x0f)
+	}
+
+	var msg model.SocketMessage
+	if err := json.Unmarshal(payload, &msg); err != nil {
+		t.Fatalf("failed to decode %s: %v", payload, err)
+	}
+	return &msg
+}
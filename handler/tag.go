@@ -0,0 +1,48 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/TechBowl-japan/go-stations/model"
+	"github.com/TechBowl-japan/go-stations/service"
+)
+
+// A TagHandler implements handling REST endpoints for tags.
+type TagHandler struct {
+	svc *service.TagService
+}
+
+// NewTagHandler returns TagHandler based http.Handler.
+func NewTagHandler(svc *service.TagService) *TagHandler {
+	return &TagHandler{
+		svc: svc,
+	}
+}
+
+// Read handles the endpoint that reads the tags.
+func (h *TagHandler) Read(ctx context.Context, req *model.ReadTagRequest) (*model.ReadTagResponse, error) {
+	tags, err := h.svc.ReadTag(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &model.ReadTagResponse{Tags: tags}, nil
+}
+
+// ServeHTTP implements http.Handler to accept HTTP requests for tag endpoints.
+func (h *TagHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		RenderError(w, &model.ErrMethodNotAllowed{Allowed: []string{http.MethodGet}})
+		return
+	}
+
+	resp, err := h.Read(r.Context(), &model.ReadTagRequest{})
+	if err != nil {
+		RenderError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
@@ -5,7 +5,10 @@ import (
 	"encoding/json"
 	"errors"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/TechBowl-japan/go-stations/model"
 	"github.com/TechBowl-japan/go-stations/service"
@@ -14,18 +17,27 @@ import (
 // A TODOHandler implements handling REST endpoints.
 type TODOHandler struct {
 	svc *service.TODOService
+	// trashRetention is the default and the minimum age of the TODOs purged by POST /todos/purge.
+	trashRetention time.Duration
 }
 
 // NewTODOHandler returns TODOHandler based http.Handler.
 func NewTODOHandler(svc *service.TODOService) *TODOHandler {
 	return &TODOHandler{
-		svc: svc,
+		svc:            svc,
+		trashRetention: service.DefaultTrashRetention,
 	}
 }
 
+// SetTrashRetention sets how long TODOs are kept in the trash, which POST /todos/purge respects
+// unless forced. A non-positive retention means that the trash is never purged automatically.
+func (h *TODOHandler) SetTrashRetention(retention time.Duration) {
+	h.trashRetention = retention
+}
+
 // Create handles the endpoint that creates the TODO.
 func (h *TODOHandler) Create(ctx context.Context, req *model.CreateTODORequest) (*model.CreateTODOResponse, error) {
-	todo, err := h.svc.CreateTODO(ctx, req.Subject, req.Description)
+	todo, err := h.svc.CreateTODOWithAttributes(ctx, req.Subject, req.Description, &req.TODOAttributes)
 	if err != nil {
 		return nil, err
 	}
@@ -34,143 +46,496 @@ func (h *TODOHandler) Create(ctx context.Context, req *model.CreateTODORequest)
 
 // Read handles the endpoint that reads the TODOs.
 func (h *TODOHandler) Read(ctx context.Context, req *model.ReadTODORequest) (*model.ReadTODOResponse, error) {
-	todos, err := h.svc.ReadTODO(ctx, req.PrevID, req.Size)
+	page, err := h.svc.ListTODOPage(ctx, &model.TODOQuery{
+		PrevID:        req.PrevID,
+		Size:          req.Size,
+		Status:        req.Status,
+		Due:           req.Due,
+		DueWithinDays: req.DueWithinDays,
+		Filter:        req.Where,
+		Order:         req.Order,
+		Tags:          req.Tags,
+		TagMatch:      req.TagMatch,
+		ProjectID:     req.ProjectID,
+		Cursor:        req.Cursor,
+		CountTotal:    req.TotalCount,
+		View:          req.View,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return newReadTODOResponse(page, req.View), nil
+}
+
+// ReadByID handles the endpoint that reads the TODO.
+func (h *TODOHandler) ReadByID(ctx context.Context, req *model.ReadTODOByIDRequest) (*model.ReadTODOByIDResponse, error) {
+	todo, err := h.svc.ReadTODOByIDWithView(ctx, req.ID, req.View)
 	if err != nil {
 		return nil, err
 	}
-	return &model.ReadTODOResponse{Todos: todos}, nil
+	return &model.ReadTODOByIDResponse{TODO: *todo, View: req.View}, nil
 }
 
 // Update handles the endpoint that updates the TODO.
+// Attributes absent from req are kept, so that clients unaware of them do not clear them.
 func (h *TODOHandler) Update(ctx context.Context, req *model.UpdateTODORequest) (*model.UpdateTODOResponse, error) {
-	todo, err := h.svc.UpdateTODO(ctx, int64(req.ID), req.Subject, req.Description)
+	todo, err := h.svc.PatchTODO(ctx, req.ID, req.Patch(), req.IfMatch)
 	if err != nil {
 		return nil, err
 	}
 	return &model.UpdateTODOResponse{TODO: *todo}, nil
 }
 
+// Patch handles the endpoint that partially updates the TODO.
+func (h *TODOHandler) Patch(ctx context.Context, req *model.PatchTODORequest) (*model.PatchTODOResponse, error) {
+	todo, err := h.svc.PatchTODO(ctx, req.ID, &req.TODOPatch, req.IfMatch)
+	if err != nil {
+		return nil, err
+	}
+	return &model.PatchTODOResponse{TODO: *todo}, nil
+}
+
 // Delete handles the endpoint that deletes the TODOs.
 func (h *TODOHandler) Delete(ctx context.Context, req *model.DeleteTODORequest) (*model.DeleteTODOResponse, error) {
-	err := h.svc.DeleteTODO(ctx, req.IDs)
+	err := h.svc.DeleteTODOWithPolicy(ctx, req.IDs, req.Children, req.IfMatch)
 	if err != nil {
 		return nil, err
 	}
 	return &model.DeleteTODOResponse{}, nil
 }
 
-func (h *TODOHandler) renderError(w http.ResponseWriter, message string, code int) {
-	http.Error(w, message, code)
+// Complete handles the endpoint that marks the TODO as done.
+func (h *TODOHandler) Complete(ctx context.Context, req *model.CompleteTODORequest) (*model.CompleteTODOResponse, error) {
+	todo, next, err := h.svc.CompleteTODO(ctx, req.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &model.CompleteTODOResponse{TODO: *todo, Next: next}, nil
 }
 
-// ServeHTTP implements http.Handler to accept HTTP requests for TODO endpoints.
-func (h *TODOHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+// Reopen handles the endpoint that marks the TODO as not done.
+func (h *TODOHandler) Reopen(ctx context.Context, req *model.ReopenTODORequest) (*model.ReopenTODOResponse, error) {
+	todo, err := h.svc.ReopenTODO(ctx, req.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &model.ReopenTODOResponse{TODO: *todo}, nil
+}
+
+// ReadChildren handles the endpoint that reads the children of the TODO.
+func (h *TODOHandler) ReadChildren(ctx context.Context, req *model.ReadTODOChildrenRequest) (*model.ReadTODOResponse, error) {
+	page, err := h.svc.ReadTODOChildren(ctx, req.ID, &model.TODOQuery{
+		PrevID:        req.PrevID,
+		Size:          req.Size,
+		Status:        req.Status,
+		Due:           req.Due,
+		DueWithinDays: req.DueWithinDays,
+		Filter:        req.Where,
+		Order:         req.Order,
+		Tags:          req.Tags,
+		TagMatch:      req.TagMatch,
+		ProjectID:     req.ProjectID,
+		Cursor:        req.Cursor,
+		CountTotal:    req.TotalCount,
+		View:          req.View,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return newReadTODOResponse(page, req.View), nil
+}
+
+// ReadTree handles the endpoint that reads the TODO with all of its descendants.
+func (h *TODOHandler) ReadTree(ctx context.Context, req *model.ReadTODOTreeRequest) (*model.ReadTODOTreeResponse, error) {
+	tree, err := h.svc.ReadTODOTree(ctx, req.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &model.ReadTODOTreeResponse{Tree: tree}, nil
+}
+
+// ReadHistory handles the endpoint that reads the change history of the TODO.
+func (h *TODOHandler) ReadHistory(ctx context.Context, req *model.ReadTODOHistoryRequest) (*model.ReadTODOHistoryResponse, error) {
+	events, err := h.svc.ReadTODOHistory(ctx, req.ID, req.PrevID, req.Size)
+	if err != nil {
+		return nil, err
+	}
+	return &model.ReadTODOHistoryResponse{Events: events}, nil
+}
+
+// Revert handles the endpoint that reverts the TODO to a prior revision.
+func (h *TODOHandler) Revert(ctx context.Context, req *model.RevertTODORequest) (*model.RevertTODOResponse, error) {
+	todo, err := h.svc.RevertTODO(ctx, req.ID, req.Revision, req.IfMatch)
+	if err != nil {
+		return nil, err
+	}
+	return &model.RevertTODOResponse{TODO: *todo}, nil
+}
+
+// Search handles the endpoint that searches the TODOs by keywords.
+func (h *TODOHandler) Search(ctx context.Context, req *model.SearchTODORequest) (*model.SearchTODOResponse, error) {
+	results, err := h.svc.SearchTODO(ctx, req.Query, req.Size, req.Offset)
+	if err != nil {
+		return nil, err
+	}
+	return &model.SearchTODOResponse{Results: results}, nil
+}
+
+// parseReadTODORequest parses and validates the query parameters of GET /todos.
+func parseReadTODORequest(query url.Values) (*model.ReadTODORequest, error) {
+	p := queryParser{query: query}
+	req := model.ReadTODORequest{
+		Status:   model.TODOStatus(query.Get("status")),
+		Due:      model.TODODue(query.Get("due")),
+		Sort:     model.TODOSort(query.Get("sort")),
+		Filter:   query.Get("filter"),
+		Cursor:   query.Get("cursor"),
+		Fields:   query.Get("fields"),
+		Expand:   query.Get("expand"),
+		Tags:     query["tag"],
+		TagMatch: model.TagMatch(query.Get("tag_match")),
+	}
+	p.int64("prev_id", &req.PrevID)
+	p.int64("size", &req.Size)
+	p.int64("due_within", &req.DueWithinDays)
+	if query.Get("project_id") != "" {
+		var projectID int64
+		p.int64("project_id", &projectID)
+		req.ProjectID = &projectID
+	}
+	p.bool("total_count", &req.TotalCount)
+	if req.Cursor != "" && req.PrevID != 0 {
+		p.invalid(&model.ErrValidation{Field: "cursor", Message: "cannot be combined with prev_id"})
+	}
+	var err error
+	if req.Where, err = model.ParseFilter(req.Filter); err != nil {
+		p.invalid(err)
+	}
+	if req.Order, err = model.ParseSort(string(req.Sort)); err != nil {
+		p.invalid(err)
+	}
+	if req.View, err = model.ParseTODOView(req.Fields, req.Expand); err != nil {
+		p.invalid(err)
+	}
+	if err := p.validate(&req); err != nil {
+		return nil, err
+	}
+	return &req, nil
+}
+
+// splitIDPath splits a "{prefix}/{id}/{action}" path into its id and action.
+// ok is false when the path does not continue with an id after prefix.
+func splitIDPath(path, prefix string) (id int64, action string, ok bool) {
+	rest := strings.Trim(strings.TrimPrefix(path, prefix), "/")
+	if rest == "" {
+		return 0, "", false
+	}
+	parts := strings.SplitN(rest, "/", 2)
+	id, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return 0, "", false
+	}
+	if len(parts) == 2 {
+		action = parts[1]
+	}
+	return id, action, true
+}
+
+// serveUpdate handles PUT /todos and PUT /todos/{id}.
+// id is the TODO in the path, or 0 for /todos where the TODO is given by the body alone.
+func (h *TODOHandler) serveUpdate(w http.ResponseWriter, r *http.Request, id int64) {
+	var req model.UpdateTODORequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		RenderError(w, err)
+		return
+	}
+	// パスと異なる TODO を更新しないよう、ボディの id はパスと一致する場合だけ受け付ける
+	if id != 0 && req.ID != id {
+		RenderError(w, &model.ErrValidation{Field: "id", Message: "does not match the id in the path"})
+		return
+	}
+
+	req.IfMatch = parseIfMatch(r.Header.Get("If-Match"))
+	resp, err := h.Update(r.Context(), &req)
+	if err != nil {
+		RenderError(w, err)
+		return
+	}
+
+	writeTODOResponse(w, r, http.StatusOK, &resp.TODO, resp)
+}
+
+// serveAction handles "/todos/{id}/{action}" endpoints.
+func (h *TODOHandler) serveAction(w http.ResponseWriter, r *http.Request, id int64, action string) {
 	ctx := r.Context()
 
-	switch r.Method {
-	case http.MethodGet:
-		var req model.ReadTODORequest
-		prevIDStr := r.URL.Query().Get("prev_id")
-		if prevIDStr != "" {
-			prevID, err := strconv.ParseInt(prevIDStr, 10, 64)
-			if err != nil {
-				h.renderError(w, "invalid prev_id", http.StatusBadRequest)
-				return
-			}
-			req.PrevID = prevID
-		}
-		sizeStr := r.URL.Query().Get("size")
-		if sizeStr != "" {
-			size, err := strconv.ParseInt(sizeStr, 10, 64)
-			if err != nil {
-				h.renderError(w, "invalid size", http.StatusBadRequest)
-				return
-			}
-			req.Size = size
-		}
-
-		resp, err := h.Read(ctx, &req)
-		if err != nil {
-			h.renderError(w, "internal server error", http.StatusInternalServerError)
+	var (
+		resp interface{}
+		err  error
+	)
+	switch action {
+	case "complete":
+		if r.Method != http.MethodPost {
+			RenderError(w, &model.ErrMethodNotAllowed{Allowed: []string{http.MethodPost}})
 			return
 		}
-
-		w.Header().Set("Content-Type", "application/json")
-		_ = json.NewEncoder(w).Encode(resp)
-
-	case http.MethodPost:
-		var req model.CreateTODORequest
-		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			h.renderError(w, "bad request", http.StatusBadRequest)
+		resp, err = h.Complete(ctx, &model.CompleteTODORequest{ID: id})
+	case "reopen":
+		if r.Method != http.MethodPost {
+			RenderError(w, &model.ErrMethodNotAllowed{Allowed: []string{http.MethodPost}})
 			return
 		}
-
-		if req.Subject == "" {
-			h.renderError(w, "subject is required", http.StatusBadRequest)
+		resp, err = h.Reopen(ctx, &model.ReopenTODORequest{ID: id})
+	case "children":
+		if r.Method != http.MethodGet {
+			RenderError(w, &model.ErrMethodNotAllowed{Allowed: []string{http.MethodGet}})
 			return
 		}
-
-		resp, err := h.Create(ctx, &req)
-		if err != nil {
-			h.renderError(w, "internal server error", http.StatusInternalServerError)
+		req, perr := parseReadTODORequest(r.URL.Query())
+		if perr != nil {
+			RenderError(w, perr)
+			return
+		}
+		resp, err = h.ReadChildren(ctx, &model.ReadTODOChildrenRequest{ID: id, ReadTODORequest: *req})
+	case "tree":
+		if r.Method != http.MethodGet {
+			RenderError(w, &model.ErrMethodNotAllowed{Allowed: []string{http.MethodGet}})
+			return
+		}
+		resp, err = h.ReadTree(ctx, &model.ReadTODOTreeRequest{ID: id})
+	case "history":
+		if r.Method != http.MethodGet {
+			RenderError(w, &model.ErrMethodNotAllowed{Allowed: []string{http.MethodGet}})
+			return
+		}
+		p := queryParser{query: r.URL.Query()}
+		req := model.ReadTODOHistoryRequest{ID: id}
+		p.int64("prev_id", &req.PrevID)
+		p.int64("size", &req.Size)
+		if err := p.validate(&req); err != nil {
+			RenderError(w, err)
+			return
+		}
+		resp, err = h.ReadHistory(ctx, &req)
+	case "revert":
+		if r.Method != http.MethodPost {
+			RenderError(w, &model.ErrMethodNotAllowed{Allowed: []string{http.MethodPost}})
+			return
+		}
+		var req model.RevertTODORequest
+		if err := decodeJSON(w, r, &req); err != nil {
+			RenderError(w, err)
 			return
 		}
+		req.ID = id
+		req.IfMatch = parseIfMatch(r.Header.Get("If-Match"))
+		resp, err = h.Revert(ctx, &req)
+	default:
+		RenderError(w, &model.ErrNotFound{Resource: "endpoint"})
+		return
+	}
+	if err != nil {
+		RenderError(w, err)
+		return
+	}
 
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusCreated)
-		_ = json.NewEncoder(w).Encode(resp)
+	switch resp := resp.(type) {
+	case *model.CompleteTODOResponse:
+		writeTODOResponse(w, r, http.StatusOK, &resp.TODO, resp)
+		return
+	case *model.ReopenTODOResponse:
+		writeTODOResponse(w, r, http.StatusOK, &resp.TODO, resp)
+		return
+	case *model.RevertTODOResponse:
+		writeTODOResponse(w, r, http.StatusOK, &resp.TODO, resp)
+		return
+	case *model.ReadTODOResponse:
+		setPageLinks(w, r, resp)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
 
-	case http.MethodPut:
-		var req model.UpdateTODORequest
-		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			h.renderError(w, "bad request", http.StatusBadRequest)
-			return
+// serveReadByID handles GET and HEAD of the "/todos/{id}" endpoint.
+// The body written for HEAD is discarded by net/http.
+// A matching If-None-Match results in 304 Not Modified.
+func (h *TODOHandler) serveReadByID(w http.ResponseWriter, r *http.Request, id int64) {
+	query := r.URL.Query()
+	req := model.ReadTODOByIDRequest{ID: id, Fields: query.Get("fields"), Expand: query.Get("expand")}
+	if err := model.Validate(&req); err != nil {
+		RenderError(w, err)
+		return
+	}
+	view, err := model.ParseTODOView(req.Fields, req.Expand)
+	if err != nil {
+		RenderError(w, err)
+		return
+	}
+	req.View = view
+
+	resp, err := h.ReadByID(r.Context(), &req)
+	if err != nil {
+		RenderError(w, err)
+		return
+	}
+
+	writeTODOResponse(w, r, http.StatusOK, &resp.TODO, resp)
+}
+
+// servePatch handles PATCH of the "/todos/{id}" endpoint.
+func (h *TODOHandler) servePatch(w http.ResponseWriter, r *http.Request, id int64) {
+	patch, err := decodeTODOPatch(http.MaxBytesReader(w, r.Body, maxBodySize), r.Header.Get("Content-Type"))
+	if err != nil {
+		if errors.Is(err, errUnsupportedPatchType) {
+			w.Header().Set("Accept-Patch", mergePatchContentType+", "+jsonPatchContentType)
 		}
+		RenderError(w, err)
+		return
+	}
+
+	if err := model.Validate(patch); err != nil {
+		RenderError(w, err)
+		return
+	}
+
+	resp, err := h.Patch(r.Context(), &model.PatchTODORequest{ID: id, TODOPatch: *patch, IfMatch: parseIfMatch(r.Header.Get("If-Match"))})
+	if err != nil {
+		RenderError(w, err)
+		return
+	}
+
+	writeTODOResponse(w, r, http.StatusOK, &resp.TODO, resp)
+}
+
+// serveSearch handles the "/todos/search" endpoint.
+func (h *TODOHandler) serveSearch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		RenderError(w, &model.ErrMethodNotAllowed{Allowed: []string{http.MethodGet}})
+		return
+	}
+
+	p := queryParser{query: r.URL.Query()}
+	req := model.SearchTODORequest{Query: r.URL.Query().Get("q")}
+	p.int64("size", &req.Size)
+	p.int64("offset", &req.Offset)
+	if err := p.validate(&req); err != nil {
+		RenderError(w, err)
+		return
+	}
+
+	resp, err := h.Search(r.Context(), &req)
+	if err != nil {
+		RenderError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// ServeHTTP implements http.Handler to accept HTTP requests for TODO endpoints.
+func (h *TODOHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
 
-		if req.ID == 0 {
-			h.renderError(w, "id is required", http.StatusBadRequest)
+	switch strings.Trim(strings.TrimPrefix(r.URL.Path, "/todos"), "/") {
+	case "search":
+		h.serveSearch(w, r)
+		return
+	case "events":
+		h.serveEvents(w, r)
+		return
+	case "trash":
+		h.serveTrash(w, r)
+		return
+	case "restore":
+		h.serveRestore(w, r)
+		return
+	case "purge":
+		h.servePurge(w, r)
+		return
+	case "batch":
+		h.serveBatch(w, r)
+		return
+	case "export":
+		h.serveExport(w, r)
+		return
+	case "import":
+		h.serveImport(w, r)
+		return
+	}
+
+	if id, action, ok := splitIDPath(r.URL.Path, "/todos"); ok {
+		switch {
+		case action != "":
+			h.serveAction(w, r, id, action)
+			return
+		case r.Method == http.MethodGet || r.Method == http.MethodHead:
+			h.serveReadByID(w, r, id)
+			return
+		case r.Method == http.MethodPatch:
+			h.servePatch(w, r, id)
+			return
+		case r.Method == http.MethodPut:
+			// 以前のクライアントは PUT を /todos/{id} に送るため、パスの TODO の更新として受け付ける
+			h.serveUpdate(w, r, id)
 			return
 		}
+		RenderError(w, &model.ErrMethodNotAllowed{Allowed: []string{http.MethodGet, http.MethodHead, http.MethodPut, http.MethodPatch}})
+		return
+	}
 
-		if req.Subject == "" {
-			h.renderError(w, "subject is required", http.StatusBadRequest)
+	switch r.Method {
+	case http.MethodGet:
+		req, err := parseReadTODORequest(r.URL.Query())
+		if err != nil {
+			RenderError(w, err)
 			return
 		}
 
-		resp, err := h.Update(ctx, &req)
+		resp, err := h.Read(ctx, req)
 		if err != nil {
-			var errNotFound *model.ErrNotFound
-			if errors.As(err, &errNotFound) {
-				h.renderError(w, "not found", http.StatusNotFound)
-				return
-			}
-			h.renderError(w, "internal server error", http.StatusInternalServerError)
+			RenderError(w, err)
 			return
 		}
 
+		setPageLinks(w, r, resp)
 		w.Header().Set("Content-Type", "application/json")
 		_ = json.NewEncoder(w).Encode(resp)
 
+	case http.MethodPost:
+		var req model.CreateTODORequest
+		if err := decodeJSON(w, r, &req); err != nil {
+			RenderError(w, err)
+			return
+		}
+
+		resp, err := h.Create(ctx, &req)
+		if err != nil {
+			RenderError(w, err)
+			return
+		}
+
+		writeTODOResponse(w, r, http.StatusCreated, &resp.TODO, resp)
+
+	case http.MethodPut:
+		h.serveUpdate(w, r, 0)
+
 	case http.MethodDelete:
 		var req model.DeleteTODORequest
-		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			h.renderError(w, "bad request", http.StatusBadRequest)
+		if err := decodeJSON(w, r, &req); err != nil {
+			RenderError(w, err)
 			return
 		}
 
-		if len(req.IDs) == 0 {
-			h.renderError(w, "ids must not be empty", http.StatusBadRequest)
+		req.IfMatch = parseIfMatch(r.Header.Get("If-Match"))
+		if req.IfMatch != nil && len(req.IDs) != 1 {
+			RenderError(w, &model.ErrBadRequest{Message: "If-Match requires exactly one id"})
 			return
 		}
 
 		resp, err := h.Delete(ctx, &req)
 		if err != nil {
-			var errNotFound *model.ErrNotFound
-			if errors.As(err, &errNotFound) {
-				h.renderError(w, "not found", http.StatusNotFound)
-				return
-			}
-			h.renderError(w, "internal server error", http.StatusInternalServerError)
+			RenderError(w, err)
 			return
 		}
 
@@ -178,6 +543,6 @@ func (h *TODOHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		_ = json.NewEncoder(w).Encode(resp)
 
 	default:
-		h.renderError(w, "method not allowed", http.StatusMethodNotAllowed)
+		RenderError(w, &model.ErrMethodNotAllowed{Allowed: []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodDelete}})
 	}
 }
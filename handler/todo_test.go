@@ -0,0 +1,280 @@
+package handler_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/TechBowl-japan/go-stations/db"
+	"github.com/TechBowl-japan/go-stations/handler"
+	"github.com/TechBowl-japan/go-stations/model"
+	"github.com/TechBowl-japan/go-stations/service"
+)
+
+func TestTODOHandlerReadByID(t *testing.T) {
+	t.Parallel()
+
+	todoDB, err := db.NewDB(filepath.Join(t.TempDir(), "todo.db"))
+	if err != nil {
+		t.Fatalf("failed to create db: %v", err)
+	}
+	t.Cleanup(func() { todoDB.Close() })
+
+	ctx := context.Background()
+	svc := service.NewTODOService(todoDB)
+	for _, subject := range []string{"kept", "trashed"} {
+		if _, err := svc.CreateTODO(ctx, subject, "description"); err != nil {
+			t.Fatalf("failed to create todo: %v", err)
+		}
+	}
+	if err := svc.DeleteTODO(ctx, []int64{2}); err != nil {
+		t.Fatalf("failed to delete todo: %v", err)
+	}
+
+	// HEAD のボディを捨てるのは net/http のサーバーなので、ResponseRecorder ではなく実際に通信する
+	srv := httptest.NewServer(handler.NewTODOHandler(svc))
+	t.Cleanup(srv.Close)
+
+	get, err := http.Get(srv.URL + "/todos/1")
+	if err != nil {
+		t.Fatalf("failed to get todo: %v", err)
+	}
+	get.Body.Close()
+	wantETag := get.Header.Get("ETag")
+
+	cases := map[string]struct {
+		method     string
+		path       string
+		wantStatus int
+		wantBody   bool
+	}{
+		"Get": {
+			method:     http.MethodGet,
+			path:       "/todos/1",
+			wantStatus: http.StatusOK,
+			wantBody:   true,
+		},
+		"Head": {
+			method:     http.MethodHead,
+			path:       "/todos/1",
+			wantStatus: http.StatusOK,
+		},
+		"Trailing slash": {
+			method:     http.MethodGet,
+			path:       "/todos/1/",
+			wantStatus: http.StatusOK,
+			wantBody:   true,
+		},
+		"Missing": {
+			method:     http.MethodGet,
+			path:       "/todos/99",
+			wantStatus: http.StatusNotFound,
+		},
+		"Trashed": {
+			method:     http.MethodGet,
+			path:       "/todos/2",
+			wantStatus: http.StatusNotFound,
+		},
+		"Head missing": {
+			method:     http.MethodHead,
+			path:       "/todos/99",
+			wantStatus: http.StatusNotFound,
+		},
+	}
+
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			req, err := http.NewRequest(c.method, srv.URL+c.path, nil)
+			if err != nil {
+				t.Fatalf("failed to create request: %v", err)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("failed to send request: %v", err)
+			}
+			defer resp.Body.Close()
+			body, err := io.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatalf("failed to read body: %v", err)
+			}
+
+			if resp.StatusCode != c.wantStatus {
+				t.Fatalf("unexpected status, got = %d, want = %d", resp.StatusCode, c.wantStatus)
+			}
+			if c.wantStatus == http.StatusNotFound {
+				if got := resp.Header.Get("Content-Type"); got != "application/problem+json" {
+					t.Errorf("unexpected content type, got = %s", got)
+				}
+				return
+			}
+
+			// HEAD は GET と同じヘッダーを返し、ボディは返さない
+			if got := resp.Header.Get("ETag"); got != wantETag {
+				t.Errorf("unexpected etag, got = %s, want = %s", got, wantETag)
+			}
+			if got := resp.Header.Get("Content-Type"); got != "application/json" {
+				t.Errorf("unexpected content type, got = %s", got)
+			}
+			if !c.wantBody {
+				if len(body) != 0 {
+					t.Errorf("unexpected body, got = %s", body)
+				}
+				return
+			}
+			var got model.ReadTODOByIDResponse
+			if err := json.Unmarshal(body, &got); err != nil {
+				t.Fatalf("failed to decode body %s: %v", body, err)
+			}
+			if got.TODO.ID != 1 || got.TODO.Subject != "kept" || got.TODO.Description != "description" {
+				t.Errorf("unexpected todo, got = %+v", got.TODO)
+			}
+		})
+	}
+}
+
+func TestTODOHandlerUpdateKeepsAttributes(t *testing.T) {
+	t.Parallel()
+
+	todoDB, err := db.NewDB(filepath.Join(t.TempDir(), "todo.db"))
+	if err != nil {
+		t.Fatalf("failed to create db: %v", err)
+	}
+	t.Cleanup(func() { todoDB.Close() })
+
+	ctx := context.Background()
+	svc := service.NewTODOService(todoDB)
+	attrs := &model.TODOAttributes{Priority: model.PriorityHigh, Tags: []string{"backend", "release-1.4"}}
+	if _, err := svc.CreateTODOWithAttributes(ctx, "tagged", "", attrs); err != nil {
+		t.Fatalf("failed to create todo: %v", err)
+	}
+	h := handler.NewTODOHandler(svc)
+
+	// 手順は順に実行し、前の手順の更新を引き継ぐ
+	steps := []struct {
+		name         string
+		body         string
+		wantTags     []string
+		wantPriority model.Priority
+	}{
+		{
+			name:         "Legacy body keeps attributes",
+			body:         `{"id": 1, "subject": "renamed", "description": "updated"}`,
+			wantTags:     []string{"backend", "release-1.4"},
+			wantPriority: model.PriorityHigh,
+		},
+		{
+			name:         "Given attributes are replaced",
+			body:         `{"id": 1, "subject": "renamed", "tags": ["backend"]}`,
+			wantTags:     []string{"backend"},
+			wantPriority: model.PriorityHigh,
+		},
+		{
+			name: "Null clears attributes",
+			body: `{"id": 1, "subject": "renamed", "tags": null, "priority": null}`,
+		},
+	}
+	for _, s := range steps {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/todos", strings.NewReader(s.body)))
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: unexpected status, got = %d, body = %s", s.name, w.Code, w.Body)
+		}
+
+		todo, err := svc.ReadTODOByID(ctx, 1)
+		if err != nil {
+			t.Fatalf("%s: failed to read todo: %v", s.name, err)
+		}
+		if todo.Subject != "renamed" || strings.Join(todo.Tags, ",") != strings.Join(s.wantTags, ",") || todo.Priority != s.wantPriority {
+			t.Errorf("%s: unexpected todo, got = %+v", s.name, todo)
+		}
+	}
+}
+
+func TestTODOHandlerByIDMethods(t *testing.T) {
+	t.Parallel()
+
+	todoDB, err := db.NewDB(filepath.Join(t.TempDir(), "todo.db"))
+	if err != nil {
+		t.Fatalf("failed to create db: %v", err)
+	}
+	t.Cleanup(func() { todoDB.Close() })
+
+	ctx := context.Background()
+	svc := service.NewTODOService(todoDB)
+	for _, subject := range []string{"first", "second"} {
+		if _, err := svc.CreateTODO(ctx, subject, ""); err != nil {
+			t.Fatalf("failed to create todo: %v", err)
+		}
+	}
+	h := handler.NewTODOHandler(svc)
+
+	// 手順は順に実行し、拒否したリクエストはどの TODO も変更しない
+	steps := []struct {
+		name        string
+		method      string
+		body        string
+		wantStatus  int
+		wantSubject string
+	}{
+		{
+			name:       "Post",
+			method:     http.MethodPost,
+			body:       `{"subject": "created"}`,
+			wantStatus: http.StatusMethodNotAllowed,
+		},
+		{
+			name:       "Delete",
+			method:     http.MethodDelete,
+			body:       `{"ids": [1]}`,
+			wantStatus: http.StatusMethodNotAllowed,
+		},
+		{
+			name:       "Put to another todo",
+			method:     http.MethodPut,
+			body:       `{"id": 2, "subject": "moved"}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:        "Put",
+			method:      http.MethodPut,
+			body:        `{"id": 1, "subject": "renamed"}`,
+			wantStatus:  http.StatusOK,
+			wantSubject: "renamed",
+		},
+	}
+
+	for _, s := range steps {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(s.method, "/todos/1", strings.NewReader(s.body)))
+		if w.Code != s.wantStatus {
+			t.Fatalf("%s: unexpected status, got = %d, want = %d, body = %s", s.name, w.Code, s.wantStatus, w.Body)
+		}
+		if s.wantStatus == http.StatusMethodNotAllowed {
+			if got := w.Header().Get("Allow"); got != "GET, HEAD, PUT, PATCH" {
+				t.Errorf("%s: unexpected allow, got = %s", s.name, got)
+			}
+		}
+
+		want := map[int64]string{1: "first", 2: "second"}
+		if s.wantSubject != "" {
+			want[1] = s.wantSubject
+		}
+		for id, subject := range want {
+			todo, err := svc.ReadTODOByID(ctx, id)
+			if err != nil {
+				t.Fatalf("%s: failed to read todo %d: %v", s.name, id, err)
+			}
+			if todo.Subject != subject {
+				t.Errorf("%s: unexpected subject of todo %d, got = %s, want = %s", s.name, id, todo.Subject, subject)
+			}
+		}
+	}
+}
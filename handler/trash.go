@@ -0,0 +1,129 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/TechBowl-japan/go-stations/model"
+)
+
+// Trash handles the endpoint that reads the TODOs in the trash.
+func (h *TODOHandler) Trash(ctx context.Context, req *model.ReadTrashRequest) (*model.ReadTrashResponse, error) {
+	todos, err := h.svc.ReadTrash(ctx, req.PrevID, req.Size)
+	if err != nil {
+		return nil, err
+	}
+	return &model.ReadTrashResponse{Todos: todos}, nil
+}
+
+// Restore handles the endpoint that moves the TODOs out of the trash.
+func (h *TODOHandler) Restore(ctx context.Context, req *model.RestoreTODORequest) (*model.RestoreTODOResponse, error) {
+	todos, err := h.svc.RestoreTODO(ctx, req.IDs)
+	if err != nil {
+		return nil, err
+	}
+	return &model.RestoreTODOResponse{Todos: todos}, nil
+}
+
+// Purge handles the endpoint that permanently deletes the TODOs in the trash.
+// TODOs trashed within the retention period are kept unless req.Force is set.
+func (h *TODOHandler) Purge(ctx context.Context, req *model.PurgeTODORequest) (*model.PurgeTODOResponse, error) {
+	if !req.Force {
+		switch {
+		case req.OlderThan == nil && h.trashRetention <= 0:
+			return nil, &model.ErrValidation{Field: "older_than", Message: "is required when the trash retention is disabled"}
+		case req.OlderThan != nil && *req.OlderThan < h.trashRetention:
+			return nil, &model.ErrValidation{Field: "older_than", Message: "must not be shorter than the trash retention " + h.trashRetention.String() + " unless force is true"}
+		}
+	}
+	olderThan := h.trashRetention
+	if req.OlderThan != nil {
+		olderThan = *req.OlderThan
+	}
+
+	purged, err := h.svc.PurgeTODO(ctx, time.Now().Add(-olderThan))
+	if err != nil {
+		return nil, err
+	}
+	return &model.PurgeTODOResponse{Purged: purged}, nil
+}
+
+// serveTrash handles the "/todos/trash" endpoint.
+func (h *TODOHandler) serveTrash(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		RenderError(w, &model.ErrMethodNotAllowed{Allowed: []string{http.MethodGet}})
+		return
+	}
+
+	p := queryParser{query: r.URL.Query()}
+	var req model.ReadTrashRequest
+	p.int64("prev_id", &req.PrevID)
+	p.int64("size", &req.Size)
+	if err := p.validate(&req); err != nil {
+		RenderError(w, err)
+		return
+	}
+
+	resp, err := h.Trash(r.Context(), &req)
+	if err != nil {
+		RenderError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// serveRestore handles the "/todos/restore" endpoint.
+func (h *TODOHandler) serveRestore(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		RenderError(w, &model.ErrMethodNotAllowed{Allowed: []string{http.MethodPost}})
+		return
+	}
+
+	var req model.RestoreTODORequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		RenderError(w, err)
+		return
+	}
+
+	resp, err := h.Restore(r.Context(), &req)
+	if err != nil {
+		RenderError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// servePurge handles the "/todos/purge" endpoint.
+func (h *TODOHandler) servePurge(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		RenderError(w, &model.ErrMethodNotAllowed{Allowed: []string{http.MethodPost}})
+		return
+	}
+
+	p := queryParser{query: r.URL.Query()}
+	var req model.PurgeTODORequest
+	if p.query.Has("older_than") {
+		req.OlderThan = new(time.Duration)
+		p.duration("older_than", req.OlderThan)
+	}
+	p.bool("force", &req.Force)
+	if err := p.validate(&req); err != nil {
+		RenderError(w, err)
+		return
+	}
+
+	resp, err := h.Purge(r.Context(), &req)
+	if err != nil {
+		RenderError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,81 @@
+package handler_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/TechBowl-japan/go-stations/db"
+	"github.com/TechBowl-japan/go-stations/handler"
+	"github.com/TechBowl-japan/go-stations/model"
+	"github.com/TechBowl-japan/go-stations/service"
+)
+
+func TestTODOHandlerPurge(t *testing.T) {
+	t.Parallel()
+
+	todoDB, err := db.NewDB(filepath.Join(t.TempDir(), "todo.db"))
+	if err != nil {
+		t.Fatalf("failed to create db: %v", err)
+	}
+	t.Cleanup(func() { todoDB.Close() })
+
+	ctx := context.Background()
+	svc := service.NewTODOService(todoDB)
+	for _, subject := range []string{"old", "recent"} {
+		if _, err := svc.CreateTODO(ctx, subject, ""); err != nil {
+			t.Fatalf("failed to create todo: %v", err)
+		}
+	}
+	if err := svc.DeleteTODO(ctx, []int64{1, 2}); err != nil {
+		t.Fatalf("failed to delete todos: %v", err)
+	}
+	// old だけを保持期間より前にゴミ箱に移したことにする
+	old := time.Now().Add(-service.DefaultTrashRetention - time.Hour).UTC().Format("2006-01-02 15:04:05")
+	if _, err := todoDB.Exec(`UPDATE todos SET deleted_at = ? WHERE id = 1`, old); err != nil {
+		t.Fatalf("failed to age todo: %v", err)
+	}
+	h := handler.NewTODOHandler(svc)
+
+	// 手順は順に実行し、前の手順で削除された TODO は残らない
+	steps := []struct {
+		name       string
+		query      string
+		wantStatus int
+		wantPurged int64
+		wantTrash  int
+	}{
+		{name: "Bare purge keeps recent todos", wantStatus: http.StatusOK, wantPurged: 1, wantTrash: 1},
+		{name: "Shorter than the retention", query: "?older_than=1h", wantStatus: http.StatusBadRequest, wantTrash: 1},
+		{name: "Zero without force", query: "?older_than=0s", wantStatus: http.StatusBadRequest, wantTrash: 1},
+		{name: "Forced", query: "?older_than=0s&force=true", wantStatus: http.StatusOK, wantPurged: 1},
+	}
+	for _, s := range steps {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/todos/purge"+s.query, nil))
+		if w.Code != s.wantStatus {
+			t.Fatalf("%s: unexpected status, got = %d, want = %d, body = %s", s.name, w.Code, s.wantStatus, w.Body)
+		}
+		if s.wantStatus == http.StatusOK {
+			var resp model.PurgeTODOResponse
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatalf("%s: failed to decode: %v", s.name, err)
+			}
+			if resp.Purged != s.wantPurged {
+				t.Errorf("%s: unexpected purged, got = %d, want = %d", s.name, resp.Purged, s.wantPurged)
+			}
+		}
+
+		trashed, err := svc.ReadTrash(ctx, 0, 10)
+		if err != nil {
+			t.Fatalf("%s: failed to read trash: %v", s.name, err)
+		}
+		if len(trashed) != s.wantTrash || len(trashed) == 1 && trashed[0].Subject != "recent" {
+			t.Errorf("%s: unexpected trash, got = %+v", s.name, trashed)
+		}
+	}
+}
@@ -0,0 +1,207 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/TechBowl-japan/go-stations/model"
+	"github.com/TechBowl-japan/go-stations/service"
+)
+
+// A WebhookHandler implements handling REST endpoints for webhooks.
+type WebhookHandler struct {
+	svc *service.WebhookService
+}
+
+// NewWebhookHandler returns WebhookHandler based http.Handler.
+func NewWebhookHandler(svc *service.WebhookService) *WebhookHandler {
+	return &WebhookHandler{
+		svc: svc,
+	}
+}
+
+// Create handles the endpoint that creates the webhook.
+func (h *WebhookHandler) Create(ctx context.Context, req *model.CreateWebhookRequest) (*model.CreateWebhookResponse, error) {
+	webhook, err := h.svc.CreateWebhook(ctx, req.URL, req.Events, req.Secret)
+	if err != nil {
+		return nil, err
+	}
+	return &model.CreateWebhookResponse{Webhook: *webhook}, nil
+}
+
+// Read handles the endpoint that reads the webhooks.
+func (h *WebhookHandler) Read(ctx context.Context, req *model.ReadWebhookRequest) (*model.ReadWebhookResponse, error) {
+	webhooks, err := h.svc.ReadWebhook(ctx, req.PrevID, req.Size)
+	if err != nil {
+		return nil, err
+	}
+	return &model.ReadWebhookResponse{Webhooks: webhooks}, nil
+}
+
+// ReadByID handles the endpoint that reads the webhook.
+func (h *WebhookHandler) ReadByID(ctx context.Context, req *model.ReadWebhookByIDRequest) (*model.ReadWebhookByIDResponse, error) {
+	webhook, err := h.svc.ReadWebhookByID(ctx, req.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &model.ReadWebhookByIDResponse{Webhook: *webhook}, nil
+}
+
+// Update handles the endpoint that updates the webhook.
+func (h *WebhookHandler) Update(ctx context.Context, req *model.UpdateWebhookRequest) (*model.UpdateWebhookResponse, error) {
+	webhook, err := h.svc.UpdateWebhook(ctx, req.ID, req.URL, req.Events, req.Active, req.Secret)
+	if err != nil {
+		return nil, err
+	}
+	return &model.UpdateWebhookResponse{Webhook: *webhook}, nil
+}
+
+// Delete handles the endpoint that deletes the webhook.
+func (h *WebhookHandler) Delete(ctx context.Context, req *model.DeleteWebhookRequest) (*model.DeleteWebhookResponse, error) {
+	if err := h.svc.DeleteWebhook(ctx, req.ID); err != nil {
+		return nil, err
+	}
+	return &model.DeleteWebhookResponse{}, nil
+}
+
+// ReadDeliveries handles the endpoint that reads the delivery log of the webhook.
+func (h *WebhookHandler) ReadDeliveries(ctx context.Context, req *model.ReadWebhookDeliveryRequest) (*model.ReadWebhookDeliveryResponse, error) {
+	deliveries, err := h.svc.ReadWebhookDeliveries(ctx, req.WebhookID, req.Status, req.PrevID, req.Size)
+	if err != nil {
+		return nil, err
+	}
+	return &model.ReadWebhookDeliveryResponse{Deliveries: deliveries}, nil
+}
+
+// Redeliver handles the endpoint that queues the delivery again.
+func (h *WebhookHandler) Redeliver(ctx context.Context, req *model.RedeliverWebhookRequest) (*model.RedeliverWebhookResponse, error) {
+	delivery, err := h.svc.RedeliverWebhook(ctx, req.WebhookID, req.DeliveryID)
+	if err != nil {
+		return nil, err
+	}
+	return &model.RedeliverWebhookResponse{Delivery: *delivery}, nil
+}
+
+// ServeHTTP implements http.Handler to accept HTTP requests for webhook endpoints.
+func (h *WebhookHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, action, ok := splitIDPath(r.URL.Path, "/webhooks")
+	if !ok {
+		h.serveCollection(w, r)
+		return
+	}
+
+	var (
+		resp interface{}
+		err  error
+	)
+	switch {
+	case action == "deliveries" && r.Method == http.MethodGet:
+		req := model.ReadWebhookDeliveryRequest{
+			WebhookID: id,
+			Status:    model.WebhookDeliveryStatus(r.URL.Query().Get("status")),
+		}
+		p := queryParser{query: r.URL.Query()}
+		p.int64("prev_id", &req.PrevID)
+		p.int64("size", &req.Size)
+		if err := p.validate(&req); err != nil {
+			RenderError(w, err)
+			return
+		}
+		resp, err = h.ReadDeliveries(ctx, &req)
+
+	case strings.HasPrefix(action, "deliveries/"):
+		// "deliveries/{delivery_id}/redeliver"
+		parts := strings.Split(strings.TrimPrefix(action, "deliveries/"), "/")
+		deliveryID, perr := strconv.ParseInt(parts[0], 10, 64)
+		if perr != nil || len(parts) != 2 || parts[1] != "redeliver" {
+			RenderError(w, &model.ErrNotFound{Resource: "endpoint"})
+			return
+		}
+		if r.Method != http.MethodPost {
+			RenderError(w, &model.ErrMethodNotAllowed{Allowed: []string{http.MethodPost}})
+			return
+		}
+		resp, err = h.Redeliver(ctx, &model.RedeliverWebhookRequest{WebhookID: id, DeliveryID: deliveryID})
+
+	case action != "":
+		RenderError(w, &model.ErrNotFound{Resource: "endpoint"})
+		return
+
+	case r.Method == http.MethodGet:
+		resp, err = h.ReadByID(ctx, &model.ReadWebhookByIDRequest{ID: id})
+
+	case r.Method == http.MethodPut:
+		var req model.UpdateWebhookRequest
+		if err := decodeJSON(w, r, &req); err != nil {
+			RenderError(w, err)
+			return
+		}
+		req.ID = id
+		resp, err = h.Update(ctx, &req)
+
+	case r.Method == http.MethodDelete:
+		resp, err = h.Delete(ctx, &model.DeleteWebhookRequest{ID: id})
+
+	default:
+		RenderError(w, &model.ErrMethodNotAllowed{Allowed: []string{http.MethodGet, http.MethodPut, http.MethodDelete}})
+		return
+	}
+	if err != nil {
+		RenderError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// serveCollection handles "/webhooks" endpoints.
+func (h *WebhookHandler) serveCollection(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	switch r.Method {
+	case http.MethodGet:
+		var req model.ReadWebhookRequest
+		p := queryParser{query: r.URL.Query()}
+		p.int64("prev_id", &req.PrevID)
+		p.int64("size", &req.Size)
+		if err := p.validate(&req); err != nil {
+			RenderError(w, err)
+			return
+		}
+
+		resp, err := h.Read(ctx, &req)
+		if err != nil {
+			RenderError(w, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(resp)
+
+	case http.MethodPost:
+		var req model.CreateWebhookRequest
+		if err := decodeJSON(w, r, &req); err != nil {
+			RenderError(w, err)
+			return
+		}
+
+		resp, err := h.Create(ctx, &req)
+		if err != nil {
+			RenderError(w, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(resp)
+
+	default:
+		RenderError(w, &model.ErrMethodNotAllowed{Allowed: []string{http.MethodGet, http.MethodPost}})
+	}
+}
@@ -0,0 +1,371 @@
+// Package websocket implements the server side of the WebSocket protocol (RFC 6455)
+// on top of net/http, as much as the TODO endpoints need.
+package websocket
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// Message types defined in RFC 6455 section 11.8.
+const (
+	TextMessage   = 1
+	BinaryMessage = 2
+	CloseMessage  = 8
+	PingMessage   = 9
+	PongMessage   = 10
+
+	continuationFrame = 0
+)
+
+// Close codes defined in RFC 6455 section 7.4.1.
+const (
+	CloseNormalClosure    = 1000
+	CloseGoingAway        = 1001
+	CloseProtocolError    = 1002
+	CloseUnsupportedData  = 1003
+	CloseNoStatusReceived = 1005
+	CloseInvalidPayload   = 1007
+	ClosePolicyViolation  = 1008
+	CloseMessageTooBig    = 1009
+	CloseInternalError    = 1011
+)
+
+// acceptGUID is appended to Sec-WebSocket-Key to compute Sec-WebSocket-Accept.
+const acceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// maxControlPayload is the maximum payload size of a control frame.
+const maxControlPayload = 125
+
+// ErrBadHandshake is returned by Upgrade when the request is not a valid WebSocket handshake.
+// Nothing is written to the response in that case.
+var ErrBadHandshake = errors.New("websocket: not a websocket handshake")
+
+// ErrBadOrigin is returned by Upgrade when the Origin of the handshake is not allowed.
+// Nothing is written to the response in that case.
+var ErrBadOrigin = errors.New("websocket: origin not allowed")
+
+// A CloseError is returned by ReadMessage when the peer closed the connection.
+type CloseError struct {
+	Code int
+	Text string
+}
+
+func (e *CloseError) Error() string {
+	return fmt.Sprintf("websocket: closed with code %d %s", e.Code, e.Text)
+}
+
+// An Upgrader upgrades HTTP requests to WebSocket connections.
+type Upgrader struct {
+	// MaxMessageSize is the maximum size of a message read from the peer; 0 means no limit.
+	MaxMessageSize int64
+	// ReadTimeout is the maximum time to wait for the next frame; 0 means no timeout.
+	// Pongs count as frames, so sending pings more often than ReadTimeout keeps idle connections open.
+	ReadTimeout time.Duration
+	// AllowedOrigins are the hosts, such as "example.com:8080", that pages can connect from besides the host of the request.
+	// Browsers always send Origin, so this keeps other sites from connecting with the cookies of the user.
+	// Requests without Origin come from non-browser clients and are allowed.
+	AllowedOrigins []string
+}
+
+// Upgrade completes the WebSocket handshake of r and takes over its connection.
+// It returns an error wrapping ErrBadHandshake without writing a response when r is not a handshake.
+func (u *Upgrader) Upgrade(w http.ResponseWriter, r *http.Request) (*Conn, error) {
+	if r.Method != http.MethodGet {
+		return nil, fmt.Errorf("%w: method must be GET", ErrBadHandshake)
+	}
+	if !headerContains(r.Header, "Connection", "upgrade") || !headerContains(r.Header, "Upgrade", "websocket") {
+		return nil, fmt.Errorf("%w: missing upgrade headers", ErrBadHandshake)
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		return nil, fmt.Errorf("%w: Sec-WebSocket-Version must be 13", ErrBadHandshake)
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if decoded, err := base64.StdEncoding.DecodeString(key); err != nil || len(decoded) != 16 {
+		return nil, fmt.Errorf("%w: invalid Sec-WebSocket-Key", ErrBadHandshake)
+	}
+	if !u.checkOrigin(r) {
+		return nil, fmt.Errorf("%w: %s", ErrBadOrigin, r.Header.Get("Origin"))
+	}
+
+	netConn, rw, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		return nil, err
+	}
+	// http.Server の ReadTimeout や WriteTimeout で設定された期限を引き継がないようにする
+	if err := netConn.SetDeadline(time.Time{}); err != nil {
+		netConn.Close()
+		return nil, err
+	}
+
+	handshake := "HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + acceptKey(key) + "\r\n\r\n"
+	if _, err := rw.WriteString(handshake); err != nil {
+		netConn.Close()
+		return nil, err
+	}
+	if err := rw.Flush(); err != nil {
+		netConn.Close()
+		return nil, err
+	}
+
+	return &Conn{
+		conn:           netConn,
+		br:             rw.Reader,
+		maxMessageSize: u.MaxMessageSize,
+		readTimeout:    u.ReadTimeout,
+	}, nil
+}
+
+// checkOrigin reports whether the Origin of r is missing, the host of r or one of u.AllowedOrigins.
+func (u *Upgrader) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Values("Origin")
+	if len(origin) == 0 {
+		return true
+	}
+	o, err := url.Parse(origin[0])
+	if err != nil || o.Host == "" {
+		return false
+	}
+	if strings.EqualFold(o.Host, r.Host) {
+		return true
+	}
+	for _, allowed := range u.AllowedOrigins {
+		if strings.EqualFold(o.Host, allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+// acceptKey computes Sec-WebSocket-Accept for key.
+func acceptKey(key string) string {
+	h := sha1.Sum([]byte(key + acceptGUID))
+	return base64.StdEncoding.EncodeToString(h[:])
+}
+
+// headerContains reports whether the comma separated header name contains token, ignoring case.
+func headerContains(header http.Header, name, token string) bool {
+	for _, value := range header.Values(name) {
+		for _, v := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(v), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// A Conn is a server side WebSocket connection.
+// ReadMessage must be called from a single goroutine, while the write methods can be called concurrently.
+type Conn struct {
+	conn           net.Conn
+	br             *bufio.Reader
+	maxMessageSize int64
+	readTimeout    time.Duration
+
+	wmu    sync.Mutex
+	closed bool
+}
+
+// ReadMessage reads the next text or binary message, assembling fragmented ones.
+// A text message that is not valid UTF-8 fails the connection with CloseInvalidPayload.
+// Pings are answered with pongs and pongs are discarded.
+// A close frame from the peer is answered and returned as *CloseError.
+func (c *Conn) ReadMessage() (messageType int, p []byte, err error) {
+	var message []byte
+	messageType = -1
+	for {
+		if c.readTimeout > 0 {
+			if err := c.conn.SetReadDeadline(time.Now().Add(c.readTimeout)); err != nil {
+				return 0, nil, err
+			}
+		}
+		fin, opcode, payload, err := c.readFrame()
+		if err != nil {
+			return 0, nil, err
+		}
+
+		switch opcode {
+		case PingMessage:
+			if err := c.WriteControl(PongMessage, payload, time.Now().Add(time.Second)); err != nil {
+				return 0, nil, err
+			}
+			continue
+		case PongMessage:
+			continue
+		case CloseMessage:
+			closeErr := &CloseError{Code: CloseNoStatusReceived}
+			if len(payload) == 1 {
+				return 0, nil, c.fail(CloseProtocolError, "invalid close frame")
+			}
+			if len(payload) >= 2 {
+				if !utf8.Valid(payload[2:]) {
+					return 0, nil, c.fail(CloseInvalidPayload, "invalid UTF-8 in close reason")
+				}
+				closeErr.Code = int(binary.BigEndian.Uint16(payload))
+				closeErr.Text = string(payload[2:])
+			}
+			_ = c.WriteClose(closeErr.Code, "")
+			return 0, nil, closeErr
+		case TextMessage, BinaryMessage:
+			if messageType != -1 {
+				return 0, nil, c.fail(CloseProtocolError, "expected a continuation frame")
+			}
+			messageType = opcode
+		case continuationFrame:
+			if messageType == -1 {
+				return 0, nil, c.fail(CloseProtocolError, "unexpected continuation frame")
+			}
+		default:
+			return 0, nil, c.fail(CloseProtocolError, "unknown opcode")
+		}
+
+		if c.maxMessageSize > 0 && int64(len(message)+len(payload)) > c.maxMessageSize {
+			return 0, nil, c.fail(CloseMessageTooBig, "message too big")
+		}
+		message = append(message, payload...)
+		if fin {
+			// 断片の境界で文字が分かれることがあるため、組み立ててから検証する
+			if messageType == TextMessage && !utf8.Valid(message) {
+				return 0, nil, c.fail(CloseInvalidPayload, "invalid UTF-8 in text message")
+			}
+			return messageType, message, nil
+		}
+	}
+}
+
+// readFrame reads a single frame and unmasks its payload.
+func (c *Conn) readFrame() (fin bool, opcode int, payload []byte, err error) {
+	var header [2]byte
+	if _, err := io.ReadFull(c.br, header[:]); err != nil {
+		return false, 0, nil, err
+	}
+	fin = header[0]&0x80 != 0
+	if header[0]&0x70 != 0 {
+		return false, 0, nil, c.fail(CloseProtocolError, "reserved bits are set")
+	}
+	opcode = int(header[0] & 0x0f)
+	control := opcode >= CloseMessage
+	if header[1]&0x80 == 0 {
+		return false, 0, nil, c.fail(CloseProtocolError, "client frames must be masked")
+	}
+
+	length := uint64(header[1] & 0x7f)
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = binary.BigEndian.Uint64(ext[:])
+	}
+	if control && (!fin || length > maxControlPayload) {
+		return false, 0, nil, c.fail(CloseProtocolError, "invalid control frame")
+	}
+	if c.maxMessageSize > 0 && length > uint64(c.maxMessageSize) {
+		return false, 0, nil, c.fail(CloseMessageTooBig, "message too big")
+	}
+
+	var mask [4]byte
+	if _, err := io.ReadFull(c.br, mask[:]); err != nil {
+		return false, 0, nil, err
+	}
+	payload = make([]byte, length)
+	if _, err := io.ReadFull(c.br, payload); err != nil {
+		return false, 0, nil, err
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+
+	return fin, opcode, payload, nil
+}
+
+// fail closes the connection with code because the peer broke the protocol, and returns the reason as an error.
+func (c *Conn) fail(code int, text string) error {
+	_ = c.WriteClose(code, text)
+	return &CloseError{Code: code, Text: text}
+}
+
+// WriteMessage writes data as a single text or binary message, giving up at deadline.
+func (c *Conn) WriteMessage(messageType int, data []byte, deadline time.Time) error {
+	return c.writeFrame(messageType, data, deadline)
+}
+
+// WriteControl writes a ping, pong or close frame, giving up at deadline.
+func (c *Conn) WriteControl(messageType int, data []byte, deadline time.Time) error {
+	if len(data) > maxControlPayload {
+		return errors.New("websocket: control frame payload too long")
+	}
+	return c.writeFrame(messageType, data, deadline)
+}
+
+// WriteClose sends a close frame with code and text. No frame can be written after it.
+func (c *Conn) WriteClose(code int, text string) error {
+	payload := make([]byte, 2, 2+len(text))
+	binary.BigEndian.PutUint16(payload, uint16(code))
+	payload = append(payload, text...)
+	if len(payload) > maxControlPayload {
+		payload = payload[:maxControlPayload]
+	}
+	return c.writeFrame(CloseMessage, payload, time.Now().Add(time.Second))
+}
+
+// writeFrame writes an unmasked final frame.
+func (c *Conn) writeFrame(opcode int, payload []byte, deadline time.Time) error {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+
+	if c.closed {
+		return net.ErrClosed
+	}
+	if opcode == CloseMessage {
+		c.closed = true
+	}
+
+	header := make([]byte, 2, 10)
+	header[0] = 0x80 | byte(opcode)
+	switch n := len(payload); {
+	case n < 126:
+		header[1] = byte(n)
+	case n <= 0xffff:
+		header[1] = 126
+		header = binary.BigEndian.AppendUint16(header, uint16(n))
+	default:
+		header[1] = 127
+		header = binary.BigEndian.AppendUint64(header, uint64(n))
+	}
+
+	if err := c.conn.SetWriteDeadline(deadline); err != nil {
+		return err
+	}
+	buffers := net.Buffers{header, payload}
+	_, err := buffers.WriteTo(c.conn)
+	return err
+}
+
+// Close closes the underlying connection without sending a close frame.
+func (c *Conn) Close() error {
+	return c.conn.Close()
+}
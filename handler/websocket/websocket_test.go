@@ -0,0 +1,350 @@
+package websocket_test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/TechBowl-japan/go-stations/handler/websocket"
+)
+
+// message is the result of a ReadMessage on the server.
+type message struct {
+	messageType int
+	data        []byte
+	err         error
+}
+
+// newServer starts a server upgrading with u and reporting what it reads to the returned channel.
+func newServer(t *testing.T, u *websocket.Upgrader) (string, <-chan *message) {
+	t.Helper()
+
+	messages := make(chan *message, 16)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := u.Upgrade(w, r)
+		if err != nil {
+			messages <- &message{err: err}
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		defer conn.Close()
+		for {
+			messageType, data, err := conn.ReadMessage()
+			messages <- &message{messageType: messageType, data: data, err: err}
+			if err != nil {
+				return
+			}
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return strings.TrimPrefix(srv.URL, "http://"), messages
+}
+
+// client is a raw WebSocket client writing frames as given.
+type client struct {
+	conn net.Conn
+	br   *bufio.Reader
+}
+
+func dial(t *testing.T, addr, origin string) (*client, int) {
+	t.Helper()
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	handshake := "GET / HTTP/1.1\r\nHost: " + addr + "\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n" +
+		"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\nSec-WebSocket-Version: 13\r\n"
+	if origin != "" {
+		handshake += "Origin: " + origin + "\r\n"
+	}
+	io.WriteString(conn, handshake+"\r\n")
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, nil)
+	if err != nil {
+		t.Fatalf("failed to read handshake: %v", err)
+	}
+	return &client{conn: conn, br: br}, resp.StatusCode
+}
+
+// writeFrame writes a frame, masking payload unless masked is false.
+func (c *client) writeFrame(t *testing.T, fin bool, opcode byte, payload []byte, masked bool) {
+	t.Helper()
+
+	frame := []byte{opcode, 0}
+	if fin {
+		frame[0] |= 0x80
+	}
+	switch n := len(payload); {
+	case n < 126:
+		frame[1] = byte(n)
+	case n <= 0xffff:
+		frame[1] = 126
+		frame = binary.BigEndian.AppendUint16(frame, uint16(n))
+	default:
+		frame[1] = 127
+		frame = binary.BigEndian.AppendUint64(frame, uint64(n))
+	}
+	if masked {
+		frame[1] |= 0x80
+		mask := []byte{0x12, 0x34, 0x56, 0x78}
+		frame = append(frame, mask...)
+		for i, b := range payload {
+			frame = append(frame, b^mask[i%4])
+		}
+	} else {
+		frame = append(frame, payload...)
+	}
+	if _, err := c.conn.Write(frame); err != nil {
+		t.Fatalf("failed to write: %v", err)
+	}
+}
+
+// readFrame reads a frame from the server, which must not be masked or fragmented.
+func (c *client) readFrame(t *testing.T) (opcode byte, payload []byte) {
+	t.Helper()
+
+	var header [2]byte
+	if _, err := io.ReadFull(c.br, header[:]); err != nil {
+		t.Fatalf("failed to read: %v", err)
+	}
+	if header[0]&0x80 == 0 || header[1]&0x80 != 0 {
+		t.Fatalf("unexpected frame header %x", header)
+	}
+	length := int(header[1] & 0x7f)
+	switch length {
+	case 126:
+		var ext [2]byte
+		io.ReadFull(c.br, ext[:])
+		length = int(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		io.ReadFull(c.br, ext[:])
+		length = int(binary.BigEndian.Uint64(ext[:]))
+	}
+	payload = make([]byte, length)
+	if _, err := io.ReadFull(c.br, payload); err != nil {
+		t.Fatalf("failed to read: %v", err)
+	}
+	return header[0] & 0x0f, payload
+}
+
+// readClose reads a close frame and returns its code.
+func (c *client) readClose(t *testing.T) int {
+	t.Helper()
+
+	opcode, payload := c.readFrame(t)
+	if opcode != websocket.CloseMessage || len(payload) < 2 {
+		t.Fatalf("unexpected frame, opcode = %d, payload = %q", opcode, payload)
+	}
+	return int(binary.BigEndian.Uint16(payload))
+}
+
+func TestUpgraderOrigin(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		origin     string
+		allowed    []string
+		wantStatus int
+	}{
+		"No origin": {
+			wantStatus: http.StatusSwitchingProtocols,
+		},
+		"Same origin": {
+			origin:     "http://{host}",
+			wantStatus: http.StatusSwitchingProtocols,
+		},
+		"Allowed origin": {
+			origin:     "https://app.example.com",
+			allowed:    []string{"app.example.com"},
+			wantStatus: http.StatusSwitchingProtocols,
+		},
+		"Other origin": {
+			origin:     "https://evil.example.com",
+			allowed:    []string{"app.example.com"},
+			wantStatus: http.StatusForbidden,
+		},
+		"Null origin": {
+			origin:     "null",
+			wantStatus: http.StatusForbidden,
+		},
+	}
+
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			addr, messages := newServer(t, &websocket.Upgrader{AllowedOrigins: c.allowed})
+			_, status := dial(t, addr, strings.ReplaceAll(c.origin, "{host}", addr))
+			if status != c.wantStatus {
+				t.Fatalf("unexpected status, got = %d, want = %d", status, c.wantStatus)
+			}
+			if c.wantStatus == http.StatusForbidden {
+				if msg := <-messages; !errors.Is(msg.err, websocket.ErrBadOrigin) {
+					t.Errorf("unexpected error, got = %v", msg.err)
+				}
+			}
+		})
+	}
+}
+
+func TestConnReadMessage(t *testing.T) {
+	t.Parallel()
+
+	// 3 バイトの「あ」を断片の境界で分ける
+	a := []byte("あ")
+	cases := map[string]struct {
+		write         func(t *testing.T, c *client)
+		wantType      int
+		wantData      string
+		wantPong      string
+		wantCloseCode int
+	}{
+		"Masked text": {
+			write: func(t *testing.T, c *client) {
+				c.writeFrame(t, true, websocket.TextMessage, []byte("hello"), true)
+			},
+			wantType: websocket.TextMessage,
+			wantData: "hello",
+		},
+		"Fragmented with interleaved ping": {
+			write: func(t *testing.T, c *client) {
+				c.writeFrame(t, false, websocket.TextMessage, append([]byte("x"), a[:1]...), true)
+				c.writeFrame(t, true, websocket.PingMessage, []byte("ping"), true)
+				c.writeFrame(t, false, 0, a[1:2], true)
+				c.writeFrame(t, true, 0, append(a[2:], 'y'), true)
+			},
+			wantType: websocket.TextMessage,
+			wantData: "xあy",
+			wantPong: "ping",
+		},
+		"Binary with extended length": {
+			write: func(t *testing.T, c *client) {
+				c.writeFrame(t, true, websocket.BinaryMessage, bytes.Repeat([]byte{0xff}, 300), true)
+			},
+			wantType: websocket.BinaryMessage,
+			wantData: string(bytes.Repeat([]byte{0xff}, 300)),
+		},
+		"Unmasked frame": {
+			write: func(t *testing.T, c *client) {
+				c.writeFrame(t, true, websocket.TextMessage, []byte("hello"), false)
+			},
+			wantCloseCode: websocket.CloseProtocolError,
+		},
+		"Fragmented control frame": {
+			write: func(t *testing.T, c *client) {
+				c.writeFrame(t, false, websocket.PingMessage, []byte("ping"), true)
+			},
+			wantCloseCode: websocket.CloseProtocolError,
+		},
+		"Oversize control frame": {
+			write: func(t *testing.T, c *client) {
+				c.writeFrame(t, true, websocket.PingMessage, bytes.Repeat([]byte("p"), 126), true)
+			},
+			wantCloseCode: websocket.CloseProtocolError,
+		},
+		"Unexpected continuation": {
+			write: func(t *testing.T, c *client) {
+				c.writeFrame(t, true, 0, []byte("hello"), true)
+			},
+			wantCloseCode: websocket.CloseProtocolError,
+		},
+		"New message inside fragmented message": {
+			write: func(t *testing.T, c *client) {
+				c.writeFrame(t, false, websocket.TextMessage, []byte("hel"), true)
+				c.writeFrame(t, true, websocket.TextMessage, []byte("lo"), true)
+			},
+			wantCloseCode: websocket.CloseProtocolError,
+		},
+		"Oversize frame": {
+			write: func(t *testing.T, c *client) {
+				c.writeFrame(t, true, websocket.BinaryMessage, make([]byte, 1025), true)
+			},
+			wantCloseCode: websocket.CloseMessageTooBig,
+		},
+		"Oversize fragmented message": {
+			write: func(t *testing.T, c *client) {
+				c.writeFrame(t, false, websocket.BinaryMessage, make([]byte, 1000), true)
+				c.writeFrame(t, true, 0, make([]byte, 25), true)
+			},
+			wantCloseCode: websocket.CloseMessageTooBig,
+		},
+		"Invalid UTF-8": {
+			write: func(t *testing.T, c *client) {
+				c.writeFrame(t, true, websocket.TextMessage, []byte{'a', 0xff}, true)
+			},
+			wantCloseCode: websocket.CloseInvalidPayload,
+		},
+		"Truncated UTF-8 across fragments": {
+			write: func(t *testing.T, c *client) {
+				c.writeFrame(t, false, websocket.TextMessage, a[:1], true)
+				c.writeFrame(t, true, 0, a[1:2], true)
+			},
+			wantCloseCode: websocket.CloseInvalidPayload,
+		},
+	}
+
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			addr, messages := newServer(t, &websocket.Upgrader{MaxMessageSize: 1024})
+			cl, status := dial(t, addr, "")
+			if status != http.StatusSwitchingProtocols {
+				t.Fatalf("unexpected status, got = %d", status)
+			}
+			c.write(t, cl)
+
+			if c.wantPong != "" {
+				if opcode, payload := cl.readFrame(t); opcode != websocket.PongMessage || string(payload) != c.wantPong {
+					t.Errorf("unexpected pong, opcode = %d, payload = %q", opcode, payload)
+				}
+			}
+			msg := <-messages
+			if c.wantCloseCode != 0 {
+				var closeErr *websocket.CloseError
+				if !errors.As(msg.err, &closeErr) || closeErr.Code != c.wantCloseCode {
+					t.Errorf("unexpected error, got = %v, want close code %d", msg.err, c.wantCloseCode)
+				}
+				if code := cl.readClose(t); code != c.wantCloseCode {
+					t.Errorf("unexpected close code, got = %d, want = %d", code, c.wantCloseCode)
+				}
+				return
+			}
+			if msg.err != nil || msg.messageType != c.wantType || string(msg.data) != c.wantData {
+				t.Errorf("unexpected message, type = %d, data = %q, err = %v", msg.messageType, msg.data, msg.err)
+			}
+		})
+	}
+}
+
+func TestConnClose(t *testing.T) {
+	t.Parallel()
+
+	addr, messages := newServer(t, &websocket.Upgrader{})
+	cl, _ := dial(t, addr, "")
+	cl.writeFrame(t, true, websocket.CloseMessage, append([]byte{0x03, 0xe9}, "bye"...), true)
+
+	var closeErr *websocket.CloseError
+	if msg := <-messages; !errors.As(msg.err, &closeErr) || closeErr.Code != websocket.CloseGoingAway || closeErr.Text != "bye" {
+		t.Errorf("unexpected error, got = %v", msg.err)
+	}
+	// 受け取った close フレームのコードをそのまま返す
+	if code := cl.readClose(t); code != websocket.CloseGoingAway {
+		t.Errorf("unexpected close code, got = %d", code)
+	}
+}
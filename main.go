@@ -1,13 +1,16 @@
 package main
 
 import (
+	"context"
 	"log"
 	"net/http"
 	"os"
+	"strconv"
 	"time"
 
 	"github.com/TechBowl-japan/go-stations/db"
 	"github.com/TechBowl-japan/go-stations/handler/router"
+	"github.com/TechBowl-japan/go-stations/service"
 )
 
 func main() {
@@ -20,8 +23,9 @@ func main() {
 func realMain() error {
 	// config values
 	const (
-		defaultPort   = ":8080"
-		defaultDBPath = ".sqlite3/todo.db"
+		defaultPort     = ":8080"
+		defaultDBPath   = ".sqlite3/todo.db"
+		defaultTimeZone = "Asia/Tokyo"
 	)
 
 	port := os.Getenv("PORT")
@@ -34,9 +38,34 @@ func realMain() error {
 		dbPath = defaultDBPath
 	}
 
-	// set time zone
+	timeZone := os.Getenv("TIME_ZONE")
+	if timeZone == "" {
+		timeZone = defaultTimeZone
+	}
+
+	// parse the retention period of the trash
+	// NOTE: ゴミ箱の TODO を完全に削除するまでの期間で、既定値は service.DefaultTrashRetention
 	var err error
-	time.Local, err = time.LoadLocation("Asia/Tokyo")
+	retention := service.DefaultTrashRetention
+	if v := os.Getenv("TRASH_RETENTION"); v != "" {
+		retention, err = time.ParseDuration(v)
+		if err != nil {
+			return err
+		}
+	}
+
+	// NOTE: 開発環境などで localhost の Webhook に配信する場合だけ true にする
+	webhookAllowPrivate := false
+	if v := os.Getenv("WEBHOOK_ALLOW_PRIVATE_NETWORKS"); v != "" {
+		webhookAllowPrivate, err = strconv.ParseBool(v)
+		if err != nil {
+			return err
+		}
+	}
+
+	// set time zone
+	// NOTE: 期限の「今日」などの日付の境界は time.Local を基準に判定される
+	time.Local, err = time.LoadLocation(timeZone)
 	if err != nil {
 		return err
 	}
@@ -48,8 +77,18 @@ func realMain() error {
 	}
 	defer todoDB.Close()
 
+	// purge the trash periodically
+	go purgeTrash(context.Background(), service.NewTODOService(todoDB), retention, time.Hour)
+
+	// deliver the queued webhooks
+	webhookSvc := service.NewWebhookService(todoDB)
+	if webhookAllowPrivate {
+		webhookSvc.AllowPrivateNetworks()
+	}
+	go deliverWebhooks(context.Background(), webhookSvc, time.Second)
+
 	// NOTE: 新しいエンドポイントの登録はrouter.NewRouterの内部で行うようにする
-	mux := router.NewRouter(todoDB)
+	mux := router.NewRouterWithConfig(todoDB, &router.Config{TrashRetention: retention})
 
 	// start http server using mux and port
 	srv := &http.Server{
@@ -67,3 +106,50 @@ func realMain() error {
 
 	return nil
 }
+
+// purgeTrash permanently deletes TODOs that have been in the trash longer than retention, every interval.
+// A non-positive retention disables the purge.
+func purgeTrash(ctx context.Context, svc *service.TODOService, retention, interval time.Duration) {
+	if retention <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		purged, err := svc.PurgeTODO(ctx, time.Now().Add(-retention))
+		if err != nil {
+			log.Println("main: failed to purge the trash, err =", err)
+		} else if purged > 0 {
+			log.Println("main: purged", purged, "todos from the trash")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// deliverWebhooks attempts the webhook deliveries that are due, every interval.
+// It checks again without waiting as long as deliveries were due, so that a backlog drains quickly.
+func deliverWebhooks(ctx context.Context, svc *service.WebhookService, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		attempted, err := svc.DeliverWebhooks(ctx, time.Now())
+		if err != nil {
+			log.Println("main: failed to deliver webhooks, err =", err)
+		}
+		if err == nil && attempted > 0 {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
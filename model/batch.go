@@ -0,0 +1,79 @@
+package model
+
+import "encoding/json"
+
+// BatchMode は一括操作が失敗した場合の扱いを表します。
+type BatchMode string
+
+const (
+	// BatchAtomic は 1 件でも操作が失敗した場合にすべての操作を取り消します。
+	BatchAtomic BatchMode = "atomic"
+	// BatchBestEffort は失敗した操作だけを取り消し、残りの操作を続けます。
+	BatchBestEffort BatchMode = "best_effort"
+)
+
+// BatchOperationType は一括操作の 1 件の種類を表します。
+type BatchOperationType string
+
+const (
+	// BatchCreate は Data を POST /todos のリクエストとして TODO を作成します。
+	BatchCreate BatchOperationType = "create"
+	// BatchUpdate は Data を PUT /todos のリクエストとして TODO を更新します。
+	BatchUpdate BatchOperationType = "update"
+	// BatchDelete は Data を DELETE /todos のリクエストとして TODO をゴミ箱に移します。
+	BatchDelete BatchOperationType = "delete"
+	// BatchComplete は TODOID の TODO を完了にします。
+	BatchComplete BatchOperationType = "complete"
+)
+
+// BatchOperation は一括操作の 1 件です。
+type BatchOperation struct {
+	Op     BatchOperationType `json:"op" validate:"required,oneof=create update delete complete"`
+	TODOID int64              `json:"todo_id" validate:"min=1"`
+	Data   json.RawMessage    `json:"data"`
+
+	// 以下は Data を検証した結果で、Op に対応するものだけが設定されます。
+	Create *CreateTODORequest `json:"-"`
+	Update *UpdateTODORequest `json:"-"`
+	Delete *DeleteTODORequest `json:"-"`
+}
+
+// BatchResultStatus は一括操作の 1 件の結果を表します。
+type BatchResultStatus string
+
+const (
+	// BatchSucceeded は操作が成功し、その結果がコミットされたことを表します。
+	BatchSucceeded BatchResultStatus = "succeeded"
+	// BatchFailed は操作が失敗したことを表します。
+	BatchFailed BatchResultStatus = "failed"
+	// BatchRolledBack は操作は成功したものの、atomic で他の操作が失敗したため取り消されたことを表します。
+	BatchRolledBack BatchResultStatus = "rolled_back"
+	// BatchSkipped は atomic で先に他の操作が失敗したため実行されなかったことを表します。
+	BatchSkipped BatchResultStatus = "skipped"
+)
+
+// BatchResult は一括操作の 1 件の結果です。Index はリクエストの operations での位置です。
+// TODO と Next は操作が成功した場合に、complete のレスポンスと同じ値が設定されます。
+type BatchResult struct {
+	Index  int               `json:"index"`
+	Status BatchResultStatus `json:"status"`
+	TODO   *Todo             `json:"todo,omitempty"`
+	Next   *Todo             `json:"next,omitempty"`
+	Error  *Problem          `json:"error,omitempty"`
+	// Err は失敗した操作のエラーで、handler が Error に変換します。
+	Err error `json:"-"`
+}
+
+// BatchTODORequest は POST /todos/batch へのリクエストです。
+// Operations は順番に 1 つのトランザクションで実行されます。
+type BatchTODORequest struct {
+	Mode       BatchMode         `json:"mode" validate:"oneof=atomic best_effort"`
+	Operations []*BatchOperation `json:"operations" validate:"required,max=1000"`
+}
+
+// BatchTODOResponse は POST /todos/batch へのレスポンスです。
+// Committed は 1 件以上の操作の結果がコミットされたかどうかを表します。
+type BatchTODOResponse struct {
+	Committed bool           `json:"committed"`
+	Results   []*BatchResult `json:"results"`
+}
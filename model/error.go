@@ -1,8 +1,129 @@
 package model
 
+import (
+	"fmt"
+	"strings"
+)
+
 // ErrNotFound は TODO が存在しない場合に返されるエラー
-type ErrNotFound struct{}
+// TODO 以外のリソースの場合は Resource にその名前を入れます。
+type ErrNotFound struct {
+	Resource string
+}
 
 func (e *ErrNotFound) Error() string {
-	return "todo not found"
+	if e.Resource == "" {
+		return "todo not found"
+	}
+	return e.Resource + " not found"
+}
+
+// ErrBadRequest はリクエストの形式が不正な場合に返されるエラー
+type ErrBadRequest struct {
+	Message string
+}
+
+func (e *ErrBadRequest) Error() string {
+	return e.Message
+}
+
+// ErrForbidden はリクエストが許可されていない場合に返されるエラー
+type ErrForbidden struct {
+	Message string
+}
+
+func (e *ErrForbidden) Error() string {
+	return e.Message
+}
+
+// ErrMethodNotAllowed はエンドポイントが対応していないメソッドでリクエストされた場合に返されるエラー
+// Allowed にはエンドポイントが対応しているメソッドを入れます。
+type ErrMethodNotAllowed struct {
+	Allowed []string
+}
+
+func (e *ErrMethodNotAllowed) Error() string {
+	return "method not allowed"
+}
+
+// ErrUnsupportedMediaType は対応していない Content-Type でリクエストされた場合に返されるエラー
+type ErrUnsupportedMediaType struct {
+	Message string
+}
+
+func (e *ErrUnsupportedMediaType) Error() string {
+	return e.Message
+}
+
+// ErrNotAcceptable は Accept ヘッダーで指定された形式でレスポンスを返せない場合に返されるエラー
+type ErrNotAcceptable struct {
+	Message string
+}
+
+func (e *ErrNotAcceptable) Error() string {
+	return e.Message
+}
+
+// ErrConflict はリソースの現在の状態と矛盾する操作が行われた場合に返されるエラー
+type ErrConflict struct {
+	Message string
+}
+
+func (e *ErrConflict) Error() string {
+	return e.Message
+}
+
+// ErrValidation はリクエストの値が不正な場合に返されるエラー
+type ErrValidation struct {
+	Field   string
+	Message string
+}
+
+func (e *ErrValidation) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+// ErrValidationFailed はリクエストの複数の値が不正な場合に、その全てをまとめて返すエラー
+type ErrValidationFailed struct {
+	Errors []*ErrValidation
+}
+
+func (e *ErrValidationFailed) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// ErrRequestTooLarge はリクエストボディが上限を超えている場合に返されるエラー
+type ErrRequestTooLarge struct {
+	Limit int64
+}
+
+func (e *ErrRequestTooLarge) Error() string {
+	return fmt.Sprintf("request body must not exceed %d bytes", e.Limit)
+}
+
+// ErrPreconditionFailed は If-Match で指定されたバージョンが現在のものと異なる場合に返されるエラー
+type ErrPreconditionFailed struct{}
+
+func (e *ErrPreconditionFailed) Error() string {
+	return "precondition failed"
+}
+
+// ErrIdempotencyKeyReused は Idempotency-Key が異なるリクエストに再利用された場合に返されるエラー
+type ErrIdempotencyKeyReused struct{}
+
+func (e *ErrIdempotencyKeyReused) Error() string {
+	return "idempotency key was used for a different request"
+}
+
+// ErrUnavailable はサーバーの構成によって機能が利用できない場合に返されるエラー
+type ErrUnavailable struct {
+	Message string
+}
+
+func (e *ErrUnavailable) Error() string {
+	return e.Message
 }
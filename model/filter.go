@@ -0,0 +1,457 @@
+package model
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FilterExpr は GET /todos の filter を構文解析した式です。
+// *FilterAnd, *FilterOr, *FilterNot, *FilterCondition のいずれかです。
+type FilterExpr interface {
+	filterExpr()
+}
+
+// FilterAnd は Left と Right の両方に一致する条件です。
+type FilterAnd struct {
+	Left, Right FilterExpr
+}
+
+// FilterOr は Left と Right のいずれかに一致する条件です。
+type FilterOr struct {
+	Left, Right FilterExpr
+}
+
+// FilterNot は Expr に一致しない条件です。
+type FilterNot struct {
+	Expr FilterExpr
+}
+
+// FilterOp は FilterCondition の比較演算子を表します。
+type FilterOp string
+
+const (
+	FilterEq       FilterOp = "="
+	FilterNe       FilterOp = "!="
+	FilterLt       FilterOp = "<"
+	FilterLe       FilterOp = "<="
+	FilterGt       FilterOp = ">"
+	FilterGe       FilterOp = ">="
+	FilterContains FilterOp = "~"
+)
+
+// FilterCondition は TODO の項目 Field と値 Value の比較です。
+// Value は項目に応じて int64, string, bool, Priority, time.Time のいずれかで、null の場合は nil です。
+// Date は値が日付だけで指定されたことを表し、Value はその日の 0 時 (time.Local) になります。
+type FilterCondition struct {
+	Field string
+	Op    FilterOp
+	Value interface{}
+	Date  bool
+}
+
+func (*FilterAnd) filterExpr()       {}
+func (*FilterOr) filterExpr()        {}
+func (*FilterNot) filterExpr()       {}
+func (*FilterCondition) filterExpr() {}
+
+// filterFieldType は絞り込める項目の値の種類を表します。
+type filterFieldType int
+
+const (
+	filterInt filterFieldType = iota
+	filterString
+	filterBool
+	filterPriority
+	filterTime
+	// filterTag は TODO がタグを持つかどうかを表します。
+	filterTag
+)
+
+// filterField は絞り込める項目の定義です。
+type filterField struct {
+	typ      filterFieldType
+	nullable bool
+}
+
+// filterFields は filter で指定できる項目の一覧です。
+var filterFields = map[string]filterField{
+	"id":           {typ: filterInt},
+	"subject":      {typ: filterString},
+	"description":  {typ: filterString},
+	"done":         {typ: filterBool},
+	"priority":     {typ: filterPriority},
+	"due_at":       {typ: filterTime, nullable: true},
+	"completed_at": {typ: filterTime, nullable: true},
+	"created_at":   {typ: filterTime},
+	"updated_at":   {typ: filterTime},
+	"project_id":   {typ: filterInt, nullable: true},
+	"parent_id":    {typ: filterInt, nullable: true},
+	"tag":          {typ: filterTag},
+}
+
+// sortFields は sort で指定できる項目の一覧です。
+var sortFields = map[string]bool{
+	"id":           true,
+	"subject":      true,
+	"done":         true,
+	"priority":     true,
+	"due_at":       true,
+	"completed_at": true,
+	"created_at":   true,
+	"updated_at":   true,
+}
+
+// FilterFields は filter で指定できる項目の名前を返します。
+func FilterFields() []string {
+	return sortedKeys(filterFields)
+}
+
+// SortFields は sort で指定できる項目の名前を返します。
+func SortFields() []string {
+	return sortedKeys(sortFields)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// maxFilterDepth は filter の括弧と NOT の入れ子の上限です。
+const maxFilterDepth = 32
+
+// ParseFilter は filter の文字列を構文解析します。空の場合は nil を返します。
+// 不正な場合は位置 (1 始まりの文字数) を含む *ErrValidation を返します。
+//
+//	expr       = and { "OR" and }
+//	and        = unary { "AND" unary }
+//	unary      = "NOT" unary | "(" expr ")" | condition
+//	condition  = field op value
+//	op         = ":" | "=" | "!=" | "<" | "<=" | ">" | ">=" | "~"
+//	value      = "null" | 空白と括弧を含まない文字列 | 二重引用符で囲んだ文字列
+//
+// キーワードの大文字と小文字は区別しません。":" は "=" と同じで、"~" は部分一致 (英字の大文字と小文字を区別しない) です。
+// 日時は 2006-01-02 の日付か RFC 3339 の時刻で、日付の "=" はその日のうちに一致します。
+func ParseFilter(s string) (FilterExpr, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+
+	p := &filterParser{s: s}
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	if p.pos < len(p.s) {
+		return nil, p.errorf(p.pos, "unexpected %q", p.s[p.pos:])
+	}
+	return expr, nil
+}
+
+// filterParser は ParseFilter の再帰下降パーサーです。
+type filterParser struct {
+	s     string
+	pos   int
+	depth int
+}
+
+func (p *filterParser) errorf(pos int, format string, args ...interface{}) error {
+	return &ErrValidation{
+		Field:   "filter",
+		Message: fmt.Sprintf(format, args...) + fmt.Sprintf(" at position %d", pos+1),
+	}
+}
+
+func (p *filterParser) skipSpace() {
+	for p.pos < len(p.s) && (p.s[p.pos] == ' ' || p.s[p.pos] == '\t') {
+		p.pos++
+	}
+}
+
+// keyword は空白、括弧または終端が続く場合にキーワード kw を大文字と小文字を区別せずに読み込みます。
+func (p *filterParser) keyword(kw string) bool {
+	p.skipSpace()
+	end := p.pos + len(kw)
+	if end > len(p.s) || !strings.EqualFold(p.s[p.pos:end], kw) {
+		return false
+	}
+	if end < len(p.s) && !strings.ContainsRune(" \t()", rune(p.s[end])) {
+		return false
+	}
+	p.pos = end
+	return true
+}
+
+func (p *filterParser) parseOr() (FilterExpr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("OR") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &FilterOr{Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseAnd() (FilterExpr, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("AND") {
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &FilterAnd{Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseUnary() (FilterExpr, error) {
+	start := p.pos
+	if p.keyword("NOT") {
+		if p.depth++; p.depth > maxFilterDepth {
+			return nil, p.errorf(start, "too deeply nested")
+		}
+		expr, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		p.depth--
+		return &FilterNot{Expr: expr}, nil
+	}
+
+	p.skipSpace()
+	if p.pos < len(p.s) && p.s[p.pos] == '(' {
+		open := p.pos
+		if p.depth++; p.depth > maxFilterDepth {
+			return nil, p.errorf(open, "too deeply nested")
+		}
+		p.pos++
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		p.skipSpace()
+		if p.pos >= len(p.s) || p.s[p.pos] != ')' {
+			return nil, p.errorf(open, "unclosed parenthesis")
+		}
+		p.pos++
+		p.depth--
+		return expr, nil
+	}
+
+	return p.parseCondition()
+}
+
+func (p *filterParser) parseCondition() (FilterExpr, error) {
+	p.skipSpace()
+	start := p.pos
+	for p.pos < len(p.s) && isFieldNameChar(p.s[p.pos]) {
+		p.pos++
+	}
+	name := p.s[start:p.pos]
+	if name == "" {
+		if p.pos >= len(p.s) {
+			return nil, p.errorf(start, "expected a condition")
+		}
+		return nil, p.errorf(start, "expected a field but got %q", p.s[p.pos:p.pos+1])
+	}
+	field, ok := filterFields[name]
+	if !ok {
+		return nil, p.errorf(start, "unknown field %q; filterable fields are %s", name, strings.Join(FilterFields(), ", "))
+	}
+
+	p.skipSpace()
+	opPos := p.pos
+	op, ok := p.readOp()
+	if !ok {
+		return nil, p.errorf(opPos, "expected an operator after %s", name)
+	}
+
+	p.skipSpace()
+	valuePos := p.pos
+	raw, quoted, err := p.readValue()
+	if err != nil {
+		return nil, err
+	}
+	cond, msg := newFilterCondition(name, field, op, raw, quoted)
+	if msg != "" {
+		return nil, p.errorf(valuePos, "%s", msg)
+	}
+	return cond, nil
+}
+
+// isFieldNameChar は c が項目名に使える文字かどうかを返します。
+func isFieldNameChar(c byte) bool {
+	return c == '_' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9'
+}
+
+// readOp は比較演算子を読み込みます。
+func (p *filterParser) readOp() (FilterOp, bool) {
+	rest := p.s[p.pos:]
+	for _, op := range []FilterOp{FilterNe, FilterLe, FilterGe, FilterEq, FilterLt, FilterGt, FilterContains} {
+		if strings.HasPrefix(rest, string(op)) {
+			p.pos += len(op)
+			return op, true
+		}
+	}
+	if strings.HasPrefix(rest, ":") {
+		p.pos++
+		return FilterEq, true
+	}
+	return "", false
+}
+
+// readValue は \" と \\ をエスケープとする引用符で囲まれた文字列か、空白または括弧までの語を読み込みます。
+func (p *filterParser) readValue() (value string, quoted bool, err error) {
+	start := p.pos
+	if p.pos < len(p.s) && p.s[p.pos] == '"' {
+		var b strings.Builder
+		for p.pos++; p.pos < len(p.s); p.pos++ {
+			c := p.s[p.pos]
+			switch {
+			case c == '\\' && p.pos+1 < len(p.s):
+				p.pos++
+				b.WriteByte(p.s[p.pos])
+			case c == '"':
+				p.pos++
+				return b.String(), true, nil
+			default:
+				b.WriteByte(c)
+			}
+		}
+		return "", false, p.errorf(start, "unterminated string")
+	}
+
+	for p.pos < len(p.s) && !strings.ContainsRune(" \t()", rune(p.s[p.pos])) {
+		p.pos++
+	}
+	if p.pos == start {
+		return "", false, p.errorf(start, "expected a value")
+	}
+	return p.s[start:p.pos], false, nil
+}
+
+// newFilterCondition は raw を項目 field の値に変換し、op がその項目に使えるかを確認します。
+// 不正な場合はその理由を msg に返します。
+func newFilterCondition(name string, field filterField, op FilterOp, raw string, quoted bool) (cond *FilterCondition, msg string) {
+	cond = &FilterCondition{Field: name, Op: op}
+
+	if !quoted && raw == "null" {
+		if !field.nullable {
+			return nil, name + " cannot be null"
+		}
+		if op != FilterEq && op != FilterNe {
+			return nil, "null can only be compared with = or !="
+		}
+		return cond, ""
+	}
+
+	switch field.typ {
+	case filterString:
+		if op != FilterEq && op != FilterNe && op != FilterContains {
+			return nil, name + " can only be compared with =, != or ~"
+		}
+	case filterBool, filterTag:
+		if op != FilterEq && op != FilterNe {
+			return nil, name + " can only be compared with = or !="
+		}
+	default:
+		if op == FilterContains {
+			return nil, name + " cannot be compared with ~"
+		}
+	}
+
+	switch field.typ {
+	case filterInt:
+		v, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return nil, name + " must be an integer"
+		}
+		cond.Value = v
+	case filterString, filterTag:
+		cond.Value = raw
+	case filterBool:
+		switch raw {
+		case "true":
+			cond.Value = true
+		case "false":
+			cond.Value = false
+		default:
+			return nil, name + " must be true or false"
+		}
+	case filterPriority:
+		priority := Priority(raw)
+		if !priority.Valid() {
+			return nil, name + " must be one of none, low, medium, high, urgent"
+		}
+		cond.Value = PriorityFromRank(priority.Rank())
+	case filterTime:
+		if t, err := time.ParseInLocation("2006-01-02", raw, time.Local); err == nil {
+			cond.Value, cond.Date = t, true
+		} else if t, err := time.Parse(time.RFC3339, raw); err == nil {
+			cond.Value = t
+		} else {
+			return nil, name + " must be a date such as 2006-01-02 or an RFC 3339 time"
+		}
+	}
+	return cond, ""
+}
+
+// SortKey は TODO 一覧を並べる項目の 1 つです。
+type SortKey struct {
+	Field string
+	Desc  bool
+}
+
+// ParseSort は sort の文字列を解析します。空の場合は nil を返します。
+// sort はカンマ区切りの項目で、先頭に "-" を付けると降順になります。
+// 期限や完了日時がない TODO はどの日時よりも後として並びます。
+func ParseSort(s string) ([]SortKey, error) {
+	if TODOSort(s) == TODOSortID {
+		return nil, nil
+	}
+
+	var keys []SortKey
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		key := SortKey{Field: strings.TrimPrefix(part, "-"), Desc: strings.HasPrefix(part, "-")}
+		switch {
+		case key.Field == "":
+			return nil, &ErrValidation{Field: "sort", Message: "must not contain an empty field"}
+		case !sortFields[key.Field]:
+			return nil, &ErrValidation{Field: "sort", Message: fmt.Sprintf("unknown field %q; sortable fields are %s", key.Field, strings.Join(SortFields(), ", "))}
+		case seen[key.Field]:
+			return nil, &ErrValidation{Field: "sort", Message: fmt.Sprintf("field %q is specified more than once", key.Field)}
+		}
+		seen[key.Field] = true
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// FormatSort は keys を ParseSort で解析できる文字列にします。
+func FormatSort(keys []SortKey) string {
+	parts := make([]string, len(keys))
+	for i, key := range keys {
+		parts[i] = key.Field
+		if key.Desc {
+			parts[i] = "-" + key.Field
+		}
+	}
+	return strings.Join(parts, ",")
+}
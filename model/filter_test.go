@@ -0,0 +1,159 @@
+package model_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/TechBowl-japan/go-stations/model"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseFilter(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		filter string
+		want   model.FilterExpr
+	}{
+		"Empty": {
+			filter: "",
+			want:   nil,
+		},
+		"Colon is equality": {
+			filter: "done:false",
+			want:   &model.FilterCondition{Field: "done", Op: model.FilterEq, Value: false},
+		},
+		"AND binds tighter than OR": {
+			filter: "id=1 OR id>=5 AND priority>low",
+			want: &model.FilterOr{
+				Left: &model.FilterCondition{Field: "id", Op: model.FilterEq, Value: int64(1)},
+				Right: &model.FilterAnd{
+					Left:  &model.FilterCondition{Field: "id", Op: model.FilterGe, Value: int64(5)},
+					Right: &model.FilterCondition{Field: "priority", Op: model.FilterGt, Value: model.PriorityLow},
+				},
+			},
+		},
+		"Parentheses and NOT": {
+			filter: `NOT (subject~"a b" OR tag:work) AND due_at!=null`,
+			want: &model.FilterAnd{
+				Left: &model.FilterNot{Expr: &model.FilterOr{
+					Left:  &model.FilterCondition{Field: "subject", Op: model.FilterContains, Value: "a b"},
+					Right: &model.FilterCondition{Field: "tag", Op: model.FilterEq, Value: "work"},
+				}},
+				Right: &model.FilterCondition{Field: "due_at", Op: model.FilterNe},
+			},
+		},
+		"Date and time": {
+			filter: "due_at<2026-11-01 and created_at>=2026-10-01T09:00:00Z",
+			want: &model.FilterAnd{
+				Left:  &model.FilterCondition{Field: "due_at", Op: model.FilterLt, Value: time.Date(2026, 11, 1, 0, 0, 0, 0, time.Local), Date: true},
+				Right: &model.FilterCondition{Field: "created_at", Op: model.FilterGe, Value: time.Date(2026, 10, 1, 9, 0, 0, 0, time.UTC)},
+			},
+		},
+	}
+
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := model.ParseFilter(c.filter)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(c.want, got); diff != "" {
+				t.Errorf("unexpected filter (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestParseFilterError(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]string{
+		"Unknown field":           "owner=me",
+		"Missing operator":        "done",
+		"Missing value":           "done:",
+		"Unclosed parenthesis":    "(done:true",
+		"Unclosed quote":          `subject:"a`,
+		"Trailing token":          "done:true done:false",
+		"Invalid bool":            "done:yes",
+		"Invalid priority":        "priority:top",
+		"Invalid time":            "due_at<tomorrow",
+		"Ordering a string":       "subject<a",
+		"Contains on a number":    "id~1",
+		"Null for a non-nullable": "created_at=null",
+		"Ordering null":           "due_at<null",
+		"Too deep":                "NOT NOT NOT NOT NOT NOT NOT NOT NOT NOT NOT NOT NOT NOT NOT NOT NOT NOT NOT NOT NOT NOT NOT NOT NOT NOT NOT NOT NOT NOT NOT NOT NOT done:true",
+	}
+
+	for name, filter := range cases {
+		filter := filter
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := model.ParseFilter(filter)
+			var errValidation *model.ErrValidation
+			if !errors.As(err, &errValidation) || errValidation.Field != "filter" {
+				t.Errorf("unexpected error, got = %v", err)
+			}
+		})
+	}
+}
+
+func TestParseSort(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		sort    string
+		want    []model.SortKey
+		wantErr bool
+	}{
+		"Empty": {
+			sort: "",
+		},
+		"Single field": {
+			sort: "priority",
+			want: []model.SortKey{{Field: "priority"}},
+		},
+		"Multiple fields": {
+			sort: "-priority, due_at",
+			want: []model.SortKey{{Field: "priority", Desc: true}, {Field: "due_at"}},
+		},
+		"Unknown field": {
+			sort:    "description",
+			wantErr: true,
+		},
+		"Duplicate field": {
+			sort:    "id,-id",
+			wantErr: true,
+		},
+		"Empty field": {
+			sort:    "id,",
+			wantErr: true,
+		},
+	}
+
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := model.ParseSort(c.sort)
+			if c.wantErr {
+				if err == nil {
+					t.Errorf("expected an error, got = %v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(c.want, got); diff != "" {
+				t.Errorf("unexpected sort (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package model
+
+import "time"
+
+// TODOAction は TODO に対する変更の種類を表します。
+type TODOAction string
+
+const (
+	// TODOActionCreate は TODO の作成を表します。
+	TODOActionCreate TODOAction = "create"
+	// TODOActionUpdate は TODO の更新を表します。完了状態の変更や親子関係の付け替えも含みます。
+	TODOActionUpdate TODOAction = "update"
+	// TODOActionDelete は TODO をゴミ箱に移したことを表します。
+	TODOActionDelete TODOAction = "delete"
+	// TODOActionRestore は TODO をゴミ箱から復元したことを表します。
+	TODOActionRestore TODOAction = "restore"
+	// TODOActionPurge は TODO を完全に削除したことを表します。
+	TODOActionPurge TODOAction = "purge"
+	// TODOActionRevert は TODO を過去のリビジョンに戻したことを表します。
+	TODOActionRevert TODOAction = "revert"
+)
+
+// TODOEvent は TODO の変更履歴の 1 件を表します。ID はリビジョンとして revert に指定できます。
+// OldValue は作成時に、NewValue は完全な削除時に nil になります。
+type TODOEvent struct {
+	ID        int64      `json:"id"`
+	TODOID    int64      `json:"todo_id"`
+	Action    TODOAction `json:"action"`
+	Actor     string     `json:"actor,omitempty"`
+	OldValue  *Todo      `json:"old_value,omitempty"`
+	NewValue  *Todo      `json:"new_value,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// ReadTODOHistoryRequest は GET /todos/{id}/history へのリクエストです。
+type ReadTODOHistoryRequest struct {
+	ID     int64 `json:"id"`
+	PrevID int64 `form:"prev_id"`
+	Size   int64 `form:"size" validate:"min=0,max=100"`
+}
+
+// ReadTODOHistoryResponse は GET /todos/{id}/history へのレスポンスです。
+type ReadTODOHistoryResponse struct {
+	Events []*TODOEvent `json:"events"`
+}
+
+// RevertTODORequest は POST /todos/{id}/revert へのリクエストです。
+// Revision で指定した変更の直後の状態に TODO を戻します。
+type RevertTODORequest struct {
+	ID       int64 `json:"-"`
+	Revision int64 `json:"revision" validate:"required,min=1"`
+	// IfMatch は If-Match ヘッダーで指定されたバージョンです。nil の場合は確認しません。
+	IfMatch []int64 `json:"-"`
+}
+
+// RevertTODOResponse は POST /todos/{id}/revert へのレスポンスです。
+type RevertTODOResponse struct {
+	TODO Todo `json:"todo"`
+}
@@ -0,0 +1,10 @@
+package model
+
+import "net/http"
+
+// IdempotentResponse は Idempotency-Key 付きのリクエストに対して保存されたレスポンスを表します。
+type IdempotentResponse struct {
+	Status int
+	Header http.Header
+	Body   []byte
+}
@@ -0,0 +1,71 @@
+package model
+
+import "time"
+
+// ImportFormat は TODO を取り込む形式を表します。
+type ImportFormat string
+
+const (
+	// ImportCSV は 1 行目に項目名を持つ CSV (text/csv) を表します。GET /todos/export の CSV と同じ項目を読み込みます。
+	ImportCSV ImportFormat = "csv"
+	// ImportJSON は TODO の JSON の配列 (application/json) を表します。
+	ImportJSON ImportFormat = "json"
+	// ImportTodoTxt は 1 行に 1 つの TODO を書く todo.txt 形式 (text/plain) を表します。
+	ImportTodoTxt ImportFormat = "todotxt"
+)
+
+// ImportTODOItem は取り込む TODO の 1 件です。
+// Line は CSV と todo.txt では行番号、JSON では値が始まる行番号です。
+type ImportTODOItem struct {
+	CreateTODORequest
+	Done bool `json:"done"`
+	// CompletedAt は Done の場合の完了日時です。nil の場合は取り込んだ日時になります。
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	// Project は所属させるプロジェクトの名前です。同じ名前のプロジェクトがない場合は作成します。
+	Project string `json:"-" validate:"trim,max=100"`
+
+	Line int `json:"-"`
+	// Err は行を読み込めなかった場合のエラーで、設定されている場合は取り込みません。
+	Err error `json:"-"`
+}
+
+// ImportResultStatus は取り込む TODO の 1 件の結果を表します。
+type ImportResultStatus string
+
+const (
+	// ImportCreated は TODO が作成され、コミットされたことを表します。
+	ImportCreated ImportResultStatus = "created"
+	// ImportValid は TODO を作成できるものの、dry run か他の行の失敗によりコミットされなかったことを表します。
+	ImportValid ImportResultStatus = "valid"
+	// ImportFailed は TODO を作成できなかったことを表します。
+	ImportFailed ImportResultStatus = "failed"
+)
+
+// ImportResult は取り込む TODO の 1 件の結果です。
+// TODO は作成された、またはコミットされた場合に作成される TODO で、コミットされていない場合の ID は仮のものです。
+type ImportResult struct {
+	Line   int                `json:"line"`
+	Status ImportResultStatus `json:"status"`
+	TODO   *Todo              `json:"todo,omitempty"`
+	Error  *Problem           `json:"error,omitempty"`
+	// Err は失敗した行のエラーで、handler が Error に変換します。
+	Err error `json:"-"`
+}
+
+// ImportTODORequest は POST /todos/import へのリクエストです。
+// Items はすべて 1 つのトランザクションで作成され、1 件でも失敗した場合はどれも作成されません。
+type ImportTODORequest struct {
+	Format ImportFormat `form:"format" validate:"oneof=csv json todotxt"`
+	DryRun bool         `form:"dry_run"`
+	// Items は本文から読み込んだ TODO です。
+	Items []*ImportTODOItem `json:"-"`
+}
+
+// ImportTODOResponse は POST /todos/import へのレスポンスです。
+// Committed は TODO が作成されたかどうか、Failed は失敗した行の数を表します。
+type ImportTODOResponse struct {
+	DryRun    bool            `json:"dry_run"`
+	Committed bool            `json:"committed"`
+	Failed    int             `json:"failed"`
+	Results   []*ImportResult `json:"results"`
+}
@@ -0,0 +1,57 @@
+package model
+
+import (
+	"bytes"
+	"encoding/json"
+	"time"
+)
+
+// Optional は部分更新で指定されたかどうかを区別できる値を表します。
+// JSON でキーが存在すれば Set が true になり、null の場合 Value はゼロ値になります。
+type Optional[T any] struct {
+	Set   bool
+	Value T
+}
+
+// UnmarshalJSON は json.Unmarshaler の実装です。
+func (o *Optional[T]) UnmarshalJSON(b []byte) error {
+	o.Set = true
+	if bytes.Equal(b, []byte("null")) {
+		var zero T
+		o.Value = zero
+		return nil
+	}
+	return json.Unmarshal(b, &o.Value)
+}
+
+// TODOPatch は RFC 7396 JSON Merge Patch による TODO の部分更新を表します。
+// 指定されなかった項目は変更せず、null を指定した項目は未設定に戻します。
+type TODOPatch struct {
+	Subject     Optional[string] `json:"subject" validate:"required,trim,max=200"`
+	Description Optional[string] `json:"description" validate:"max=10000"`
+	TODOAttributesPatch
+}
+
+// TODOAttributesPatch は TODOAttributes の部分更新で、TODOPatch と UpdateTODORequest で使います。
+// 指定されなかった属性は変更せず、null を指定した属性は未設定に戻します。
+type TODOAttributesPatch struct {
+	DueAt      Optional[*time.Time] `json:"due_at"`
+	Priority   Optional[Priority]   `json:"priority" validate:"oneof=none low medium high urgent"`
+	Tags       Optional[[]string]   `json:"tags" validate:"max=20,dive,required,trim,max=50"`
+	ProjectID  Optional[*int64]     `json:"project_id" validate:"min=1"`
+	ParentID   Optional[*int64]     `json:"parent_id" validate:"min=1"`
+	Recurrence Optional[string]     `json:"recurrence" validate:"trim,max=200"`
+}
+
+// PatchTODORequest は PATCH /todos/{id} へのリクエストです。
+type PatchTODORequest struct {
+	ID int64 `json:"-"`
+	TODOPatch
+	// IfMatch は If-Match ヘッダーで指定されたバージョンです。nil の場合は確認しません。
+	IfMatch []int64 `json:"-"`
+}
+
+// PatchTODOResponse は PATCH /todos/{id} へのレスポンスです。
+type PatchTODOResponse struct {
+	TODO Todo `json:"todo"`
+}
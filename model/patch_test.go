@@ -0,0 +1,59 @@
+package model_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/TechBowl-japan/go-stations/model"
+)
+
+func TestTODOPatchUnmarshal(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		body            string
+		wantSubject     model.Optional[string]
+		wantDescription model.Optional[string]
+		wantDueAtSet    bool
+		wantDueAtValue  bool
+	}{
+		"Absent fields are not set": {
+			body: `{}`,
+		},
+		"Present field is set": {
+			body:        `{"subject":"a"}`,
+			wantSubject: model.Optional[string]{Set: true, Value: "a"},
+		},
+		"Null clears the field": {
+			body:            `{"description":null,"due_at":null}`,
+			wantDescription: model.Optional[string]{Set: true},
+			wantDueAtSet:    true,
+		},
+		"Time value is parsed": {
+			body:           `{"due_at":"2026-10-17T09:00:00Z"}`,
+			wantDueAtSet:   true,
+			wantDueAtValue: true,
+		},
+	}
+
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var patch model.TODOPatch
+			if err := json.Unmarshal([]byte(c.body), &patch); err != nil {
+				t.Fatalf("failed to unmarshal: %v", err)
+			}
+			if patch.Subject != c.wantSubject {
+				t.Errorf("unexpected subject, got = %+v, want = %+v", patch.Subject, c.wantSubject)
+			}
+			if patch.Description != c.wantDescription {
+				t.Errorf("unexpected description, got = %+v, want = %+v", patch.Description, c.wantDescription)
+			}
+			if patch.DueAt.Set != c.wantDueAtSet || (patch.DueAt.Value != nil) != c.wantDueAtValue {
+				t.Errorf("unexpected due_at, got = %+v", patch.DueAt)
+			}
+		})
+	}
+}
@@ -0,0 +1,18 @@
+package model
+
+// Problem は RFC 9457 の problem details 形式のエラーレスポンスです。
+// Code はエラーの種類を表す機械可読なコードです。
+type Problem struct {
+	Type   string          `json:"type"`
+	Title  string          `json:"title"`
+	Status int             `json:"status"`
+	Detail string          `json:"detail,omitempty"`
+	Code   string          `json:"code"`
+	Errors []*ProblemField `json:"errors,omitempty"`
+}
+
+// ProblemField は Problem に含まれる項目ごとの検証エラーです。
+type ProblemField struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
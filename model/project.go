@@ -0,0 +1,93 @@
+package model
+
+import "time"
+
+// Project は TODO をまとめるプロジェクトを表します。
+type Project struct {
+	ID          int64     `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// ProjectDeletePolicy はプロジェクトを削除する際に所属する TODO をどう扱うかを表します。
+type ProjectDeletePolicy string
+
+const (
+	// ProjectDeleteRestrict は TODO が所属している場合に削除を拒否します。
+	ProjectDeleteRestrict ProjectDeletePolicy = "restrict"
+	// ProjectDeleteCascade は所属する TODO もゴミ箱に移します。
+	ProjectDeleteCascade ProjectDeletePolicy = "cascade"
+	// ProjectDeleteDetach は所属する TODO をプロジェクトから外して残します。
+	ProjectDeleteDetach ProjectDeletePolicy = "detach"
+)
+
+// Valid は p が既知の ProjectDeletePolicy かどうかを返します。空文字列は ProjectDeleteRestrict として扱います。
+func (p ProjectDeletePolicy) Valid() bool {
+	switch p {
+	case "", ProjectDeleteRestrict, ProjectDeleteCascade, ProjectDeleteDetach:
+		return true
+	}
+	return false
+}
+
+// CreateProjectRequest は POST /projects へのリクエストです。
+type CreateProjectRequest struct {
+	Name        string `json:"name" validate:"required,trim,max=100"`
+	Description string `json:"description" validate:"max=10000"`
+}
+
+// CreateProjectResponse は POST /projects へのレスポンスです。
+type CreateProjectResponse struct {
+	Project Project `json:"project"`
+}
+
+// ReadProjectRequest は GET /projects へのリクエストです。
+type ReadProjectRequest struct {
+	PrevID int64 `form:"prev_id"`
+	Size   int64 `form:"size" validate:"min=0,max=100"`
+}
+
+// ReadProjectResponse は GET /projects へのレスポンスです。
+type ReadProjectResponse struct {
+	Projects []*Project `json:"projects"`
+}
+
+// ReadProjectByIDRequest は GET /projects/{id} へのリクエストです。
+type ReadProjectByIDRequest struct {
+	ID int64 `json:"id"`
+}
+
+// ReadProjectByIDResponse は GET /projects/{id} へのレスポンスです。
+type ReadProjectByIDResponse struct {
+	Project Project `json:"project"`
+}
+
+// UpdateProjectRequest は PUT /projects/{id} へのリクエストです。
+type UpdateProjectRequest struct {
+	ID          int64  `json:"id"`
+	Name        string `json:"name" validate:"required,trim,max=100"`
+	Description string `json:"description" validate:"max=10000"`
+}
+
+// UpdateProjectResponse は PUT /projects/{id} へのレスポンスです。
+type UpdateProjectResponse struct {
+	Project Project `json:"project"`
+}
+
+// DeleteProjectRequest は DELETE /projects/{id} へのリクエストです。
+type DeleteProjectRequest struct {
+	ID     int64               `json:"id"`
+	Policy ProjectDeletePolicy `form:"policy" validate:"oneof=restrict cascade detach"`
+}
+
+// DeleteProjectResponse は DELETE /projects/{id} へのレスポンスです。
+type DeleteProjectResponse struct {
+}
+
+// ReadProjectTODORequest は GET /projects/{id}/todos へのリクエストです。
+type ReadProjectTODORequest struct {
+	ProjectID int64 `json:"project_id"`
+	ReadTODORequest
+}
@@ -0,0 +1,225 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RecurrenceFrequency は繰り返しの単位を表します。
+type RecurrenceFrequency string
+
+const (
+	RecurrenceDaily   RecurrenceFrequency = "DAILY"
+	RecurrenceWeekly  RecurrenceFrequency = "WEEKLY"
+	RecurrenceMonthly RecurrenceFrequency = "MONTHLY"
+)
+
+// RecurrenceRule は iCalendar の RRULE のうち FREQ (DAILY/WEEKLY/MONTHLY), INTERVAL, BYDAY, UNTIL, COUNT
+// のみをサポートする繰り返しルールを表します。
+type RecurrenceRule struct {
+	Freq     RecurrenceFrequency
+	Interval int
+	// ByDay は WEEKLY の場合に繰り返す曜日です。空の場合は元の予定と同じ曜日になります。
+	ByDay []time.Weekday
+	// Until が zero でない場合、これより後の予定は作られません。
+	Until time.Time
+	// untilDate は UNTIL が日付のみ (YYYYMMDD) で指定されたことを表します。
+	// この場合 Until はその日の 0 時 (UTC) を保持し、設定されたタイムゾーンでその日の終わりまでを含みます。
+	untilDate bool
+	// Count が正の場合、この予定を含めた残りの回数を表します。
+	Count int
+}
+
+var weekdayCodes = map[string]time.Weekday{
+	"SU": time.Sunday,
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+}
+
+const (
+	untilDateTimeLayout = "20060102T150405Z"
+	untilDateLayout     = "20060102"
+)
+
+// ParseRecurrenceRule は "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE" のような RRULE 文字列を解析します。
+// 先頭の "RRULE:" は省略できます。
+func ParseRecurrenceRule(s string) (*RecurrenceRule, error) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "RRULE:")
+	if s == "" {
+		return nil, errors.New("empty recurrence rule")
+	}
+
+	rule := RecurrenceRule{Interval: 1}
+	for _, part := range strings.Split(s, ";") {
+		key, value, ok := strings.Cut(part, "=")
+		if !ok || value == "" {
+			return nil, fmt.Errorf("malformed recurrence rule part %q", part)
+		}
+		switch strings.ToUpper(key) {
+		case "FREQ":
+			rule.Freq = RecurrenceFrequency(strings.ToUpper(value))
+			switch rule.Freq {
+			case RecurrenceDaily, RecurrenceWeekly, RecurrenceMonthly:
+			default:
+				return nil, fmt.Errorf("unsupported FREQ %q", value)
+			}
+		case "INTERVAL":
+			interval, err := strconv.Atoi(value)
+			if err != nil || interval < 1 {
+				return nil, fmt.Errorf("invalid INTERVAL %q", value)
+			}
+			rule.Interval = interval
+		case "BYDAY":
+			for _, code := range strings.Split(value, ",") {
+				day, ok := weekdayCodes[strings.ToUpper(code)]
+				if !ok {
+					return nil, fmt.Errorf("invalid BYDAY %q", code)
+				}
+				rule.ByDay = append(rule.ByDay, day)
+			}
+		case "UNTIL":
+			if until, err := time.Parse(untilDateTimeLayout, value); err == nil {
+				rule.Until = until
+			} else if until, err := time.Parse(untilDateLayout, value); err == nil {
+				rule.Until = until
+				rule.untilDate = true
+			} else {
+				return nil, fmt.Errorf("invalid UNTIL %q", value)
+			}
+		case "COUNT":
+			count, err := strconv.Atoi(value)
+			if err != nil || count < 1 {
+				return nil, fmt.Errorf("invalid COUNT %q", value)
+			}
+			rule.Count = count
+		default:
+			return nil, fmt.Errorf("unsupported recurrence rule part %q", key)
+		}
+	}
+
+	if rule.Freq == "" {
+		return nil, errors.New("FREQ is required")
+	}
+	if len(rule.ByDay) > 0 && rule.Freq != RecurrenceWeekly {
+		return nil, errors.New("BYDAY is only supported with FREQ=WEEKLY")
+	}
+	if !rule.Until.IsZero() && rule.Count > 0 {
+		return nil, errors.New("UNTIL and COUNT must not be used together")
+	}
+
+	return &rule, nil
+}
+
+// String は r を RRULE 文字列にします。
+func (r *RecurrenceRule) String() string {
+	parts := []string{"FREQ=" + string(r.Freq)}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if len(r.ByDay) > 0 {
+		codes := make([]string, len(r.ByDay))
+		for i, day := range r.ByDay {
+			codes[i] = strings.ToUpper(day.String()[:2])
+		}
+		parts = append(parts, "BYDAY="+strings.Join(codes, ","))
+	}
+	if !r.Until.IsZero() {
+		if r.untilDate {
+			parts = append(parts, "UNTIL="+r.Until.Format(untilDateLayout))
+		} else {
+			parts = append(parts, "UNTIL="+r.Until.UTC().Format(untilDateTimeLayout))
+		}
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	return strings.Join(parts, ";")
+}
+
+// Next は after の予定の次の予定の日時と、その予定に引き継ぐルールを返します。
+// 日付の計算は loc の暦で行うため、夏時間の切り替えをまたいでも時刻 (壁時計) は変わりません。
+// UNTIL を過ぎる場合や COUNT を使い切った場合は ok が false になります。
+func (r *RecurrenceRule) Next(after time.Time, loc *time.Location) (next time.Time, rest *RecurrenceRule, ok bool) {
+	if r.Count == 1 {
+		return time.Time{}, nil, false
+	}
+
+	after = after.In(loc)
+	switch r.Freq {
+	case RecurrenceDaily:
+		next = after.AddDate(0, 0, r.Interval)
+	case RecurrenceWeekly:
+		next = r.nextWeekly(after)
+	case RecurrenceMonthly:
+		next = r.nextMonthly(after)
+	}
+	if next.IsZero() {
+		return time.Time{}, nil, false
+	}
+
+	if !r.Until.IsZero() {
+		until := r.Until
+		if r.untilDate {
+			y, m, d := r.Until.Date()
+			until = time.Date(y, m, d+1, 0, 0, 0, 0, loc).Add(-time.Nanosecond)
+		}
+		if next.After(until) {
+			return time.Time{}, nil, false
+		}
+	}
+
+	rest = &RecurrenceRule{}
+	*rest = *r
+	if rest.Count > 0 {
+		rest.Count--
+	}
+	return next, rest, true
+}
+
+// nextWeekly は after の週から Interval の倍数の週にある ByDay の曜日のうち、after より後の最初の日を返します。
+// 週は RRULE の既定の WKST=MO と同じく月曜日に始まります。
+func (r *RecurrenceRule) nextWeekly(after time.Time) time.Time {
+	if len(r.ByDay) == 0 {
+		return after.AddDate(0, 0, 7*r.Interval)
+	}
+
+	days := make(map[time.Weekday]bool, len(r.ByDay))
+	for _, day := range r.ByDay {
+		days[day] = true
+	}
+	weekStart := func(t time.Time) time.Time {
+		offset := (int(t.Weekday()) + 6) % 7
+		y, m, d := t.Date()
+		return time.Date(y, m, d-offset, 12, 0, 0, 0, time.UTC)
+	}
+	base := weekStart(after)
+	for i := 1; i <= 7*(r.Interval+1); i++ {
+		candidate := after.AddDate(0, 0, i)
+		weeks := int(weekStart(candidate).Sub(base).Hours()/24) / 7
+		if weeks%r.Interval == 0 && days[candidate.Weekday()] {
+			return candidate
+		}
+	}
+	return time.Time{}
+}
+
+// nextMonthly は Interval か月後の同じ日を返します。
+// その日がない月 (31 日など) は RRULE と同じく飛ばします。
+func (r *RecurrenceRule) nextMonthly(after time.Time) time.Time {
+	y, m, d := after.Date()
+	hh, mm, ss := after.Clock()
+	for i := 1; i <= 12; i++ {
+		candidate := time.Date(y, m+time.Month(i*r.Interval), d, hh, mm, ss, after.Nanosecond(), after.Location())
+		if candidate.Day() == d {
+			return candidate
+		}
+	}
+	return time.Time{}
+}
@@ -0,0 +1,193 @@
+package model_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/TechBowl-japan/go-stations/model"
+)
+
+func TestRecurrenceRuleNext(t *testing.T) {
+	t.Parallel()
+
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Fatalf("failed to load location: %v", err)
+	}
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatalf("failed to load location: %v", err)
+	}
+
+	cases := map[string]struct {
+		rule     string
+		loc      *time.Location
+		after    time.Time
+		want     time.Time
+		wantRest string
+		wantNone bool
+	}{
+		"Daily in Tokyo": {
+			rule:     "FREQ=DAILY",
+			loc:      tokyo,
+			after:    time.Date(2026, 3, 7, 9, 0, 0, 0, tokyo),
+			want:     time.Date(2026, 3, 8, 9, 0, 0, 0, tokyo),
+			wantRest: "FREQ=DAILY",
+		},
+		"Daily keeps wall clock across DST start in New York": {
+			rule:     "FREQ=DAILY",
+			loc:      newYork,
+			after:    time.Date(2026, 3, 7, 9, 0, 0, 0, newYork),
+			want:     time.Date(2026, 3, 8, 9, 0, 0, 0, newYork),
+			wantRest: "FREQ=DAILY",
+		},
+		"Weekly keeps wall clock across DST end in New York": {
+			rule:     "FREQ=WEEKLY",
+			loc:      newYork,
+			after:    time.Date(2026, 10, 29, 18, 30, 0, 0, newYork),
+			want:     time.Date(2026, 11, 5, 18, 30, 0, 0, newYork),
+			wantRest: "FREQ=WEEKLY",
+		},
+		"Weekly is computed in the given location": {
+			// 2026-03-08 00:30 JST は 2026-03-07 (土) 15:30 UTC
+			rule:     "FREQ=WEEKLY;BYDAY=SU",
+			loc:      tokyo,
+			after:    time.Date(2026, 3, 7, 15, 30, 0, 0, time.UTC),
+			want:     time.Date(2026, 3, 15, 0, 30, 0, 0, tokyo),
+			wantRest: "FREQ=WEEKLY;BYDAY=SU",
+		},
+		"Weekly by day within the same week": {
+			rule:     "FREQ=WEEKLY;BYDAY=MO,WE,FR",
+			loc:      tokyo,
+			after:    time.Date(2026, 10, 12, 10, 0, 0, 0, tokyo), // 月曜日
+			want:     time.Date(2026, 10, 14, 10, 0, 0, 0, tokyo),
+			wantRest: "FREQ=WEEKLY;BYDAY=MO,WE,FR",
+		},
+		"Biweekly by day skips a week": {
+			rule:     "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,FR",
+			loc:      tokyo,
+			after:    time.Date(2026, 10, 16, 10, 0, 0, 0, tokyo), // 金曜日
+			want:     time.Date(2026, 10, 26, 10, 0, 0, 0, tokyo),
+			wantRest: "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,FR",
+		},
+		"Biweekly by day across DST start in New York": {
+			rule:     "FREQ=WEEKLY;INTERVAL=2;BYDAY=TU",
+			loc:      newYork,
+			after:    time.Date(2026, 3, 3, 8, 0, 0, 0, newYork),
+			want:     time.Date(2026, 3, 17, 8, 0, 0, 0, newYork),
+			wantRest: "FREQ=WEEKLY;INTERVAL=2;BYDAY=TU",
+		},
+		"Monthly skips months without the day": {
+			rule:     "FREQ=MONTHLY",
+			loc:      tokyo,
+			after:    time.Date(2026, 1, 31, 9, 0, 0, 0, tokyo),
+			want:     time.Date(2026, 3, 31, 9, 0, 0, 0, tokyo),
+			wantRest: "FREQ=MONTHLY",
+		},
+		"Monthly with interval across DST end in New York": {
+			rule:     "FREQ=MONTHLY;INTERVAL=3",
+			loc:      newYork,
+			after:    time.Date(2026, 9, 15, 9, 0, 0, 0, newYork),
+			want:     time.Date(2026, 12, 15, 9, 0, 0, 0, newYork),
+			wantRest: "FREQ=MONTHLY;INTERVAL=3",
+		},
+		"Count is decremented": {
+			rule:     "FREQ=DAILY;COUNT=3",
+			loc:      tokyo,
+			after:    time.Date(2026, 10, 1, 9, 0, 0, 0, tokyo),
+			want:     time.Date(2026, 10, 2, 9, 0, 0, 0, tokyo),
+			wantRest: "FREQ=DAILY;COUNT=2",
+		},
+		"Last count": {
+			rule:     "FREQ=DAILY;COUNT=1",
+			loc:      tokyo,
+			after:    time.Date(2026, 10, 1, 9, 0, 0, 0, tokyo),
+			wantNone: true,
+		},
+		"Until date includes the whole day in the location": {
+			rule:     "FREQ=DAILY;UNTIL=20261002",
+			loc:      newYork,
+			after:    time.Date(2026, 10, 1, 23, 0, 0, 0, newYork),
+			want:     time.Date(2026, 10, 2, 23, 0, 0, 0, newYork),
+			wantRest: "FREQ=DAILY;UNTIL=20261002",
+		},
+		"After until": {
+			rule:     "FREQ=WEEKLY;UNTIL=20261005T000000Z",
+			loc:      tokyo,
+			after:    time.Date(2026, 10, 1, 9, 0, 0, 0, tokyo),
+			wantNone: true,
+		},
+	}
+
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			rule, err := model.ParseRecurrenceRule(c.rule)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			got, rest, ok := rule.Next(c.after, c.loc)
+			if c.wantNone {
+				if ok {
+					t.Errorf("unexpected next occurrence, got = %s", got)
+				}
+				return
+			}
+			if !ok {
+				t.Fatal("next occurrence not found")
+			}
+			if !got.Equal(c.want) {
+				t.Errorf("unexpected next occurrence, got = %s, want = %s", got, c.want)
+			}
+			if rest.String() != c.wantRest {
+				t.Errorf("unexpected rest rule, got = %s, want = %s", rest, c.wantRest)
+			}
+		})
+	}
+}
+
+func TestParseRecurrenceRule(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		rule    string
+		want    string
+		wantErr bool
+	}{
+		"Normalized":       {rule: "RRULE:freq=weekly;byday=mo,fr;interval=1", want: "FREQ=WEEKLY;BYDAY=MO,FR"},
+		"Until date time":  {rule: "FREQ=DAILY;UNTIL=20261231T150000Z", want: "FREQ=DAILY;UNTIL=20261231T150000Z"},
+		"Missing FREQ":     {rule: "INTERVAL=2", wantErr: true},
+		"Unsupported FREQ": {rule: "FREQ=YEARLY", wantErr: true},
+		"Invalid INTERVAL": {rule: "FREQ=DAILY;INTERVAL=0", wantErr: true},
+		"Invalid BYDAY":    {rule: "FREQ=WEEKLY;BYDAY=XX", wantErr: true},
+		"BYDAY with DAILY": {rule: "FREQ=DAILY;BYDAY=MO", wantErr: true},
+		"UNTIL and COUNT":  {rule: "FREQ=DAILY;UNTIL=20261231;COUNT=2", wantErr: true},
+		"Unsupported part": {rule: "FREQ=DAILY;BYHOUR=9", wantErr: true},
+		"Malformed":        {rule: "FREQ", wantErr: true},
+		"Empty":            {rule: "", wantErr: true},
+	}
+
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			rule, err := model.ParseRecurrenceRule(c.rule)
+			if c.wantErr {
+				if err == nil {
+					t.Errorf("expected error, got rule = %s", rule)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if rule.String() != c.want {
+				t.Errorf("unexpected rule, got = %s, want = %s", rule, c.want)
+			}
+		})
+	}
+}
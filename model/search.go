@@ -0,0 +1,25 @@
+package model
+
+// TODOSearchResult は全文検索で見つかった TODO を表します。
+type TODOSearchResult struct {
+	TODO Todo `json:"todo"`
+	// Rank は bm25 による関連度で、小さいほど検索語に関連しています。
+	Rank float64 `json:"rank"`
+	// SubjectHighlight は HTML エスケープした件名の検索語を <mark> で囲んだものです。
+	SubjectHighlight string `json:"subject_highlight"`
+	// DescriptionSnippet は HTML エスケープした説明の抜粋の検索語を <mark> で囲んだものです。
+	DescriptionSnippet string `json:"description_snippet"`
+}
+
+// SearchTODORequest は GET /todos/search へのリクエストです。
+// Query は SQLite FTS5 のクエリ構文 ("フレーズ", 前方一致の prefix*, AND / OR / NOT) で指定します。
+type SearchTODORequest struct {
+	Query  string `form:"q" validate:"required,max=500"`
+	Size   int64  `form:"size" validate:"min=0,max=100"`
+	Offset int64  `form:"offset" validate:"min=0"`
+}
+
+// SearchTODOResponse は GET /todos/search へのレスポンスです。
+type SearchTODOResponse struct {
+	Results []*TODOSearchResult `json:"results"`
+}
@@ -0,0 +1,105 @@
+package model
+
+import "encoding/json"
+
+// SocketCommandType は WebSocket でクライアントが送るコマンドの種類を表します。
+type SocketCommandType string
+
+const (
+	// SocketSubscribe は Filter に一致する TODO の変更の購読を始めます。
+	SocketSubscribe SocketCommandType = "subscribe"
+	// SocketUnsubscribe は ID の購読をやめます。
+	SocketUnsubscribe SocketCommandType = "unsubscribe"
+	// SocketCreate は Data を POST /todos のリクエストとして TODO を作成します。
+	SocketCreate SocketCommandType = "create"
+	// SocketUpdate は Data を PUT /todos のリクエストとして TODO を更新します。
+	SocketUpdate SocketCommandType = "update"
+	// SocketPatch は Data を JSON Merge Patch として TODOID の TODO を部分的に更新します。
+	SocketPatch SocketCommandType = "patch"
+	// SocketDelete は Data を DELETE /todos のリクエストとして TODO をゴミ箱に移します。
+	SocketDelete SocketCommandType = "delete"
+	// SocketComplete は TODOID の TODO を完了にします。
+	SocketComplete SocketCommandType = "complete"
+	// SocketReopen は TODOID の TODO を未完了に戻します。
+	SocketReopen SocketCommandType = "reopen"
+)
+
+// SocketCommand は WebSocket でクライアントから送られるメッセージです。
+// ID はクライアントが決める識別子で、購読やコマンドの結果のメッセージにそのまま返されます。
+type SocketCommand struct {
+	Type   SocketCommandType `json:"type" validate:"required,oneof=subscribe unsubscribe create update patch delete complete reopen"`
+	ID     string            `json:"id" validate:"max=100"`
+	Filter TODOFilter        `json:"filter"`
+	TODOID int64             `json:"todo_id" validate:"min=1"`
+	Data   json.RawMessage   `json:"data"`
+}
+
+// TODOFilter は WebSocket で購読する TODO の条件を表します。指定しない条件では絞り込みません。
+type TODOFilter struct {
+	ProjectID *int64     `json:"project_id,omitempty" validate:"min=1"`
+	Status    TODOStatus `json:"status,omitempty" validate:"oneof=open done"`
+	// Tags はすべてのタグを持つ TODO に一致します。
+	Tags []string `json:"tags,omitempty" validate:"max=20,dive,required,trim"`
+}
+
+// Match は todo が f の条件に一致するかどうかを返します。ゴミ箱にある TODO は一致しません。
+func (f *TODOFilter) Match(todo *Todo) bool {
+	if todo == nil || todo.DeletedAt != nil {
+		return false
+	}
+	if f.ProjectID != nil && (todo.ProjectID == nil || *todo.ProjectID != *f.ProjectID) {
+		return false
+	}
+	switch f.Status {
+	case TODOStatusOpen:
+		if todo.Done {
+			return false
+		}
+	case TODOStatusDone:
+		if !todo.Done {
+			return false
+		}
+	}
+	for _, tag := range f.Tags {
+		found := false
+		for _, t := range todo.Tags {
+			if t == tag {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// SocketMessageType は WebSocket でサーバーが送るメッセージの種類を表します。
+type SocketMessageType string
+
+const (
+	// SocketSubscribed は購読を始めたことを表します。
+	SocketSubscribed SocketMessageType = "subscribed"
+	// SocketUnsubscribed は購読をやめたことを表します。
+	SocketUnsubscribed SocketMessageType = "unsubscribed"
+	// SocketEvent は購読している条件に一致する TODO の変更を表します。
+	SocketEvent SocketMessageType = "event"
+	// SocketResult はコマンドの結果を表します。
+	SocketResult SocketMessageType = "result"
+	// SocketError はコマンドの失敗を表します。
+	SocketError SocketMessageType = "error"
+)
+
+// SocketMessage は WebSocket でサーバーから送られるメッセージです。
+type SocketMessage struct {
+	Type SocketMessageType `json:"type"`
+	ID   string            `json:"id,omitempty"`
+	// Event は変更の履歴で、Patch は変更前から変更後への JSON Merge Patch です。
+	// 変更によって購読の条件から外れた TODO では Removed が true になります。
+	Event   *TODOEvent      `json:"event,omitempty"`
+	Patch   json.RawMessage `json:"patch,omitempty"`
+	Removed bool            `json:"removed,omitempty"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *Problem        `json:"error,omitempty"`
+}
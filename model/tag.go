@@ -0,0 +1,15 @@
+package model
+
+// Tag はタグとそのタグが付けられた TODO の数を表します。
+type Tag struct {
+	Name  string `json:"name"`
+	Count int64  `json:"count"`
+}
+
+// ReadTagRequest は GET /tags へのリクエストです。
+type ReadTagRequest struct{}
+
+// ReadTagResponse は GET /tags へのレスポンスです。
+type ReadTagResponse struct {
+	Tags []*Tag `json:"tags"`
+}
@@ -1,22 +1,195 @@
 package model
 
 import (
+	"encoding/json"
 	"time"
 )
 
 // Todo はTODO情報を表します。
 type Todo struct {
-	ID          int64     `json:"id"`
-	Subject     string    `json:"subject"`
-	Description string    `json:"description"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID          int64      `json:"id"`
+	Subject     string     `json:"subject"`
+	Description string     `json:"description"`
+	Done        bool       `json:"done"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	DueAt       *time.Time `json:"due_at,omitempty"`
+	Priority    Priority   `json:"priority,omitempty"`
+	Tags        []string   `json:"tags,omitempty"`
+	ProjectID   *int64     `json:"project_id,omitempty"`
+	ParentID    *int64     `json:"parent_id,omitempty"`
+	Recurrence  string     `json:"recurrence,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	// DeletedAt はゴミ箱に移された日時で、ゴミ箱にない TODO では nil です。
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+	// Version は更新のたびに増える版数で、ETag として返します。
+	Version int64 `json:"-"`
+	// Project と Subtasks は expand で展開した場合だけ設定されます。
+	Project  *Project       `json:"project,omitempty"`
+	Subtasks *SubtaskCounts `json:"subtasks,omitempty"`
+}
+
+// Priority は TODO の優先度を表します。
+type Priority string
+
+const (
+	// PriorityNone は優先度が設定されていないことを表します。
+	PriorityNone   Priority = ""
+	PriorityLow    Priority = "low"
+	PriorityMedium Priority = "medium"
+	PriorityHigh   Priority = "high"
+	PriorityUrgent Priority = "urgent"
+)
+
+// priorities は優先度の低い順に並べた Priority の一覧です。
+var priorities = []Priority{PriorityNone, PriorityLow, PriorityMedium, PriorityHigh, PriorityUrgent}
+
+// Valid は p が既知の Priority かどうかを返します。"none" は PriorityNone として扱います。
+func (p Priority) Valid() bool {
+	return p.Rank() >= 0
+}
+
+// Rank は p の優先度の高さを 0 (none) から 4 (urgent) で返します。未知の値の場合は -1 を返します。
+func (p Priority) Rank() int {
+	if p == "none" {
+		return 0
+	}
+	for i, v := range priorities {
+		if p == v {
+			return i
+		}
+	}
+	return -1
+}
+
+// PriorityFromRank は Rank の逆変換です。範囲外の場合は PriorityNone を返します。
+func PriorityFromRank(rank int) Priority {
+	if rank < 0 || rank >= len(priorities) {
+		return PriorityNone
+	}
+	return priorities[rank]
+}
+
+// TODOStatus は TODO の完了状態による絞り込み条件を表します。
+type TODOStatus string
+
+const (
+	// TODOStatusAll は完了状態で絞り込まないことを表します。
+	TODOStatusAll TODOStatus = ""
+	// TODOStatusOpen は未完了の TODO を表します。
+	TODOStatusOpen TODOStatus = "open"
+	// TODOStatusDone は完了済みの TODO を表します。
+	TODOStatusDone TODOStatus = "done"
+)
+
+// Valid は s が既知の TODOStatus かどうかを返します。
+func (s TODOStatus) Valid() bool {
+	switch s {
+	case TODOStatusAll, TODOStatusOpen, TODOStatusDone:
+		return true
+	}
+	return false
+}
+
+// TODODue は TODO の期限による絞り込み条件を表します。
+type TODODue string
+
+const (
+	// TODODueAny は期限で絞り込まないことを表します。
+	TODODueAny TODODue = ""
+	// TODODueOverdue は期限を過ぎた未完了の TODO を表します。
+	TODODueOverdue TODODue = "overdue"
+	// TODODueToday は期限が今日の TODO を表します。
+	TODODueToday TODODue = "today"
+)
+
+// Valid は d が既知の TODODue かどうかを返します。
+func (d TODODue) Valid() bool {
+	switch d {
+	case TODODueAny, TODODueOverdue, TODODueToday:
+		return true
+	}
+	return false
+}
+
+// TODOSort は TODO 一覧の並び順の指定で、ParseSort で解釈します。
+// 優先度の高い順、同じ優先度の中では期限の近い順 (期限なしは最後) にするには "-priority,due_at" を指定します。
+type TODOSort string
+
+const (
+	// TODOSortID は id の昇順を表します。
+	TODOSortID TODOSort = ""
+)
+
+// TagMatch は複数のタグで絞り込む際の一致条件を表します。
+type TagMatch string
+
+const (
+	// TagMatchAll は指定したすべてのタグを持つ TODO に一致します。
+	TagMatchAll TagMatch = "all"
+	// TagMatchAny は指定したタグのいずれかを持つ TODO に一致します。
+	TagMatchAny TagMatch = "any"
+)
+
+// Valid は m が既知の TagMatch かどうかを返します。空文字列は TagMatchAll として扱います。
+func (m TagMatch) Valid() bool {
+	switch m {
+	case "", TagMatchAll, TagMatchAny:
+		return true
+	}
+	return false
+}
+
+// TODOQuery は TODO 一覧を読み込む際の条件を表します。
+type TODOQuery struct {
+	PrevID int64
+	Size   int64
+	Status TODOStatus
+	Due    TODODue
+	// DueWithinDays が正の場合、現在から DueWithinDays 日後の終わりまでに期限を迎える TODO に絞り込みます。
+	DueWithinDays int64
+	// Filter は ParseFilter で解析した条件で、nil の場合は絞り込みません。
+	Filter FilterExpr
+	// Order は並び順で、空の場合は id の昇順です。どの並び順でも最後は id の昇順で並べます。
+	Order     []SortKey
+	Tags      []string
+	TagMatch  TagMatch
+	ProjectID *int64
+	ParentID  *int64
+	// Cursor は前のページの NextCursor または PrevCursor で、指定した場合は PrevID の代わりにページの境界を表します。
+	// Order が空の場合は Cursor を作った際の並び順になります。
+	Cursor string
+	// CountTotal が true の場合、条件に一致する TODO の件数を TODOPage.TotalCount に設定します。
+	CountTotal bool
+	// View は読み込む項目と展開する関連データで、nil の場合はタグを含むすべての項目を読み込みます。
+	View *TODOView
+}
+
+// TODOPage は TODO 一覧の 1 ページです。
+// NextCursor と PrevCursor は前後のページを読み込むためのカーソルで、そのページがない場合は空です。
+type TODOPage struct {
+	Todos      []*Todo
+	NextCursor string
+	PrevCursor string
+	TotalCount *int64
+}
+
+// TODOAttributes は作成・更新時に指定できる TODO の任意項目を表します。
+// Recurrence は RecurrenceRule の形式の繰り返しルールで、完了すると次の予定が作られます。
+type TODOAttributes struct {
+	DueAt      *time.Time `json:"due_at,omitempty"`
+	Priority   Priority   `json:"priority,omitempty" validate:"oneof=none low medium high urgent"`
+	Tags       []string   `json:"tags,omitempty" validate:"max=20,dive,required,trim,max=50"`
+	ProjectID  *int64     `json:"project_id,omitempty" validate:"min=1"`
+	ParentID   *int64     `json:"parent_id,omitempty" validate:"min=1"`
+	Recurrence string     `json:"recurrence,omitempty" validate:"trim,max=200"`
 }
 
 // CreateTODORequest は POST /todos へのリクエストです。
 type CreateTODORequest struct {
-	Subject     string `json:"subject"`
-	Description string `json:"description"`
+	Subject     string `json:"subject" validate:"required,trim,max=200"`
+	Description string `json:"description" validate:"max=10000"`
+	TODOAttributes
 }
 
 // CreateTODOResponse は POST /todos へのレスポンスです。
@@ -25,21 +198,100 @@ type CreateTODOResponse struct {
 }
 
 // UpdateTODORequest は PUT /todos へのリクエストです。
+// Subject と Description は常に置き換えますが、属性は指定されなかったものを変更しません。
+// 属性を持たない以前のクライアントが PUT しても、設定済みの属性が消えないようにするためです。
 type UpdateTODORequest struct {
-	ID          int64  `json:"id"`
-	Subject     string `json:"subject"`
-	Description string `json:"description"`
+	ID          int64  `json:"id" validate:"required"`
+	Subject     string `json:"subject" validate:"required,trim,max=200"`
+	Description string `json:"description" validate:"max=10000"`
+	TODOAttributesPatch
+	// IfMatch は If-Match ヘッダーで指定されたバージョンです。nil の場合は確認しません。
+	IfMatch []int64 `json:"-"`
+}
+
+// Patch は r を同じ更新を表す TODOPatch に変換します。
+func (r *UpdateTODORequest) Patch() *TODOPatch {
+	return &TODOPatch{
+		Subject:             Optional[string]{Set: true, Value: r.Subject},
+		Description:         Optional[string]{Set: true, Value: r.Description},
+		TODOAttributesPatch: r.TODOAttributesPatch,
+	}
 }
 
 // ReadTODORequest は GET /todos へのリクエストです。
 type ReadTODORequest struct {
-	PrevID int64 `form:"prev_id"`
-	Size   int64 `form:"size"`
+	PrevID        int64      `form:"prev_id"`
+	Size          int64      `form:"size" validate:"min=0,max=100"`
+	Status        TODOStatus `form:"status" validate:"oneof=open done"`
+	Due           TODODue    `form:"due" validate:"oneof=overdue today"`
+	DueWithinDays int64      `form:"due_within" validate:"min=0"`
+	Sort          TODOSort   `form:"sort" validate:"max=200"`
+	Filter        string     `form:"filter" validate:"max=1000"`
+	Tags          []string   `form:"tag" validate:"max=20"`
+	TagMatch      TagMatch   `form:"tag_match" validate:"oneof=all any"`
+	ProjectID     *int64     `form:"project_id"`
+	Cursor        string     `form:"cursor" validate:"max=1000"`
+	TotalCount    bool       `form:"total_count"`
+	Fields        string     `form:"fields" validate:"max=500"`
+	Expand        string     `form:"expand" validate:"max=100"`
+
+	// Where、Order と View は Filter、Sort と Fields、Expand を解析した結果です。
+	Where FilterExpr `json:"-"`
+	Order []SortKey  `json:"-"`
+	View  *TODOView  `json:"-"`
+}
+
+// ReadTODOByIDRequest は GET /todos/{id} へのリクエストです。
+type ReadTODOByIDRequest struct {
+	ID     int64  `json:"id"`
+	Fields string `form:"fields" validate:"max=500"`
+	Expand string `form:"expand" validate:"max=100"`
+	// View は Fields と Expand を解析した結果です。
+	View *TODOView `json:"-"`
+}
+
+// ReadTODOByIDResponse は GET /todos/{id} へのレスポンスです。
+type ReadTODOByIDResponse struct {
+	TODO Todo `json:"todo"`
+	// View が fields を指定している場合、TODO は選んだ項目だけを返します。
+	View *TODOView `json:"-"`
+}
+
+// MarshalJSON は View で選んだ項目だけの TODO を返します。
+func (r ReadTODOByIDResponse) MarshalJSON() ([]byte, error) {
+	todo, err := r.View.MarshalTODO(&r.TODO)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(struct {
+		TODO json.RawMessage `json:"todo"`
+	}{todo})
 }
 
 // ReadTODOResponse は GET /todos へのレスポンスです。
 type ReadTODOResponse struct {
-	Todos []*Todo `json:"todos"`
+	Todos      []*Todo `json:"todos"`
+	NextCursor string  `json:"next_cursor,omitempty"`
+	PrevCursor string  `json:"prev_cursor,omitempty"`
+	TotalCount *int64  `json:"total_count,omitempty"`
+	// View が fields を指定している場合、Todos の各 TODO は選んだ項目だけを返します。
+	View *TODOView `json:"-"`
+}
+
+// MarshalJSON は View で選んだ項目だけの TODO を返します。
+func (r ReadTODOResponse) MarshalJSON() ([]byte, error) {
+	type plain ReadTODOResponse
+	if r.View == nil || len(r.View.Fields) == 0 {
+		return json.Marshal(plain(r))
+	}
+	todos, err := r.View.marshalTODOs(r.Todos)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(struct {
+		Todos []json.RawMessage `json:"todos"`
+		plain
+	}{todos, plain(r)})
 }
 
 // UpdateTODOResponse は PUT /todos へのレスポンスです。
@@ -47,11 +299,133 @@ type UpdateTODOResponse struct {
 	TODO Todo `json:"todo"`
 }
 
+// legacyTodo は PUT /todos のレスポンスで使う TODO の形です。
+// 以前のクライアントは id, subject, description と日時だけの TODO を期待しているため、Done も未完了の場合は省略します。
+type legacyTodo struct {
+	ID          int64          `json:"id"`
+	Subject     string         `json:"subject"`
+	Description string         `json:"description"`
+	Done        bool           `json:"done,omitempty"`
+	CompletedAt *time.Time     `json:"completed_at,omitempty"`
+	DueAt       *time.Time     `json:"due_at,omitempty"`
+	Priority    Priority       `json:"priority,omitempty"`
+	Tags        []string       `json:"tags,omitempty"`
+	ProjectID   *int64         `json:"project_id,omitempty"`
+	ParentID    *int64         `json:"parent_id,omitempty"`
+	Recurrence  string         `json:"recurrence,omitempty"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	DeletedAt   *time.Time     `json:"deleted_at,omitempty"`
+	Version     int64          `json:"-"`
+	Project     *Project       `json:"project,omitempty"`
+	Subtasks    *SubtaskCounts `json:"subtasks,omitempty"`
+}
+
+// MarshalJSON は以前のクライアントとの互換性のため、未設定の項目を省略した TODO を返します。
+func (r UpdateTODOResponse) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		TODO legacyTodo `json:"todo"`
+	}{legacyTodo(r.TODO)})
+}
+
+// ChildDeletePolicy は TODO を削除する際に子 TODO をどう扱うかを表します。
+type ChildDeletePolicy string
+
+const (
+	// ChildDeleteReparent は子 TODO を削除する TODO の親に付け替えて残します。
+	ChildDeleteReparent ChildDeletePolicy = "reparent"
+	// ChildDeleteCascade は子孫の TODO もすべて削除します。
+	ChildDeleteCascade ChildDeletePolicy = "cascade"
+)
+
+// Valid は p が既知の ChildDeletePolicy かどうかを返します。空文字列は ChildDeleteReparent として扱います。
+func (p ChildDeletePolicy) Valid() bool {
+	switch p {
+	case "", ChildDeleteReparent, ChildDeleteCascade:
+		return true
+	}
+	return false
+}
+
 // DeleteTODORequest は DELETE /todos へのリクエストです。
 type DeleteTODORequest struct {
-	IDs []int64 `json:"ids"`
+	IDs      []int64           `json:"ids" validate:"required,max=100"`
+	Children ChildDeletePolicy `json:"children,omitempty" validate:"oneof=reparent cascade"`
+	// IfMatch は If-Match ヘッダーで指定されたバージョンです。nil の場合は確認しません。
+	IfMatch []int64 `json:"-"`
 }
 
 // DeleteTODOResponse は DELETE /todos へのレスポンスです。
 type DeleteTODOResponse struct {
 }
+
+// CompleteTODORequest は POST /todos/{id}/complete へのリクエストです。
+type CompleteTODORequest struct {
+	ID int64 `json:"id"`
+}
+
+// CompleteTODOResponse は POST /todos/{id}/complete へのレスポンスです。
+type CompleteTODOResponse struct {
+	TODO Todo `json:"todo"`
+	// Next は繰り返しの TODO を完了したときに作られた次の予定です。
+	Next *Todo `json:"next,omitempty"`
+}
+
+// ReopenTODORequest は POST /todos/{id}/reopen へのリクエストです。
+type ReopenTODORequest struct {
+	ID int64 `json:"id"`
+}
+
+// ReopenTODOResponse は POST /todos/{id}/reopen へのレスポンスです。
+type ReopenTODOResponse struct {
+	TODO Todo `json:"todo"`
+}
+
+// TODOProgress は子孫の TODO の完了状況を表します。
+type TODOProgress struct {
+	Done  int64 `json:"done"`
+	Total int64 `json:"total"`
+}
+
+// TODOTree は TODO とその子孫を木構造で表します。
+type TODOTree struct {
+	Todo
+	Progress TODOProgress `json:"progress"`
+	Children []*TODOTree  `json:"children"`
+}
+
+// ReadTODOChildrenRequest は GET /todos/{id}/children へのリクエストです。
+type ReadTODOChildrenRequest struct {
+	ID int64 `json:"id"`
+	ReadTODORequest
+}
+
+// ExportTODORequest は GET /todos/export へのリクエストです。
+// ReadTODORequest の絞り込みと並び順に一致するすべての TODO を書き出し、ページ分割の指定は無視します。
+type ExportTODORequest struct {
+	ReadTODORequest
+	// Format は書き出す形式で、空の場合は Accept ヘッダーで決めます。
+	Format ExportFormat `form:"format" validate:"oneof=csv ndjson markdown"`
+}
+
+// ExportFormat は TODO を書き出す形式を表します。
+type ExportFormat string
+
+const (
+	// ExportCSV は 1 行目に項目名を持つ CSV (text/csv) を表します。
+	ExportCSV ExportFormat = "csv"
+	// ExportNDJSON は 1 行に 1 つの TODO の JSON (application/x-ndjson) を表します。
+	ExportNDJSON ExportFormat = "ndjson"
+	// ExportMarkdown は Markdown のチェックリスト (text/markdown) を表します。
+	ExportMarkdown ExportFormat = "markdown"
+)
+
+// ReadTODOTreeRequest は GET /todos/{id}/tree へのリクエストです。
+type ReadTODOTreeRequest struct {
+	ID int64 `json:"id"`
+}
+
+// ReadTODOTreeResponse は GET /todos/{id}/tree へのレスポンスです。
+type ReadTODOTreeResponse struct {
+	Tree *TODOTree `json:"tree"`
+}
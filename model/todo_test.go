@@ -0,0 +1,56 @@
+package model_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/TechBowl-japan/go-stations/model"
+)
+
+func TestUpdateTODOResponseMarshalJSON(t *testing.T) {
+	t.Parallel()
+
+	at := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	cases := map[string]struct {
+		todo model.Todo
+		want string
+	}{
+		"Open todo keeps the legacy shape": {
+			todo: model.Todo{ID: 1, Subject: "subject", CreatedAt: at, UpdatedAt: at, Version: 2},
+			want: `{"todo":{"id":1,"subject":"subject","description":"","created_at":"2026-10-01T00:00:00Z","updated_at":"2026-10-01T00:00:00Z"}}`,
+		},
+		"Done todo": {
+			todo: model.Todo{ID: 1, Subject: "subject", Done: true, CompletedAt: &at, CreatedAt: at, UpdatedAt: at},
+			want: `{"todo":{"id":1,"subject":"subject","description":"","done":true,"completed_at":"2026-10-01T00:00:00Z","created_at":"2026-10-01T00:00:00Z","updated_at":"2026-10-01T00:00:00Z"}}`,
+		},
+	}
+
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := json.Marshal(&model.UpdateTODOResponse{TODO: c.todo})
+			if err != nil {
+				t.Fatalf("failed to marshal response: %v", err)
+			}
+			if string(got) != c.want {
+				t.Errorf("unexpected json, got = %s, want = %s", got, c.want)
+			}
+
+			// 他のレスポンスでは未完了でも done を返す
+			todo, err := json.Marshal(&c.todo)
+			if err != nil {
+				t.Fatalf("failed to marshal todo: %v", err)
+			}
+			var fields map[string]any
+			if err := json.Unmarshal(todo, &fields); err != nil {
+				t.Fatalf("failed to decode todo: %v", err)
+			}
+			if fields["done"] != c.todo.Done {
+				t.Errorf("unexpected done, got = %v", fields["done"])
+			}
+		})
+	}
+}
@@ -0,0 +1,38 @@
+package model
+
+import "time"
+
+// ReadTrashRequest は GET /todos/trash へのリクエストです。
+type ReadTrashRequest struct {
+	PrevID int64 `form:"prev_id"`
+	Size   int64 `form:"size" validate:"min=0,max=100"`
+}
+
+// ReadTrashResponse は GET /todos/trash へのレスポンスです。
+type ReadTrashResponse struct {
+	Todos []*Todo `json:"todos"`
+}
+
+// RestoreTODORequest は POST /todos/restore へのリクエストです。
+type RestoreTODORequest struct {
+	IDs []int64 `json:"ids" validate:"required,max=100"`
+}
+
+// RestoreTODOResponse は POST /todos/restore へのレスポンスです。
+// Todos には ids で指定したうち復元された TODO が含まれます。
+type RestoreTODOResponse struct {
+	Todos []*Todo `json:"todos"`
+}
+
+// PurgeTODORequest は POST /todos/purge へのリクエストです。
+// OlderThan より前にゴミ箱に移された TODO を完全に削除します。nil の場合はゴミ箱の保持期間を使います。
+// 誤って最近の TODO を削除しないよう、保持期間より短い OlderThan は Force が true の場合だけ受け付けます。
+type PurgeTODORequest struct {
+	OlderThan *time.Duration `form:"older_than" validate:"min=0"`
+	Force     bool           `form:"force"`
+}
+
+// PurgeTODOResponse は POST /todos/purge へのレスポンスです。
+type PurgeTODOResponse struct {
+	Purged int64 `json:"purged"`
+}
@@ -0,0 +1,214 @@
+package model
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Validate はリクエストの構造体 v を validate タグに従って検証し、
+// 違反があればそれら全てをまとめた *ErrValidationFailed を返します。
+// v がポインタの場合、trim ルールによって文字列の前後の空白が取り除かれます。
+//
+// validate タグはカンマ区切りのルールで、required 以外のルールはゼロ値の項目には適用されません。
+//
+//	required   ゼロ値でないこと (文字列は空白のみも不可、スライスは要素が 1 つ以上)
+//	trim       文字列の前後の空白を取り除く
+//	max=N      文字列は N 文字以下、スライスは N 要素以下、数値は N 以下
+//	min=N      数値が N 以上
+//	oneof=A B  空白区切りの値のいずれかであること
+//	dive       以降のルールをスライスの各要素に適用する
+//
+// 埋め込まれた構造体の項目は同じ階層の項目として検証します。
+// Optional の項目は値が指定された場合のみ Value を検証します。
+func Validate(v interface{}) error {
+	return JoinValidation(Violations(v))
+}
+
+// Violations は v の validate タグに反する項目を全て返します。
+func Violations(v interface{}) []*ErrValidation {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil
+	}
+	if !rv.CanAddr() {
+		// Optional の検証にはアドレスが必要なため、コピーして検証する
+		cp := reflect.New(rv.Type()).Elem()
+		cp.Set(rv)
+		rv = cp
+	}
+	var errs []*ErrValidation
+	validateStruct(rv, &errs)
+	return errs
+}
+
+// JoinValidation は errs が空でなければそれらをまとめた *ErrValidationFailed を返します。
+func JoinValidation(errs []*ErrValidation) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	return &ErrValidationFailed{Errors: errs}
+}
+
+// optionalField は Optional[T] を検証するためのインターフェースです。
+type optionalField interface {
+	validationTarget() (reflect.Value, bool)
+}
+
+func (o *Optional[T]) validationTarget() (reflect.Value, bool) {
+	return reflect.ValueOf(&o.Value).Elem(), o.Set
+}
+
+func validateStruct(rv reflect.Value, errs *[]*ErrValidation) {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		f := rt.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		fv := rv.Field(i)
+		if f.Anonymous && f.Type.Kind() == reflect.Struct {
+			validateStruct(fv, errs)
+			continue
+		}
+		tag, ok := f.Tag.Lookup("validate")
+		if !ok {
+			continue
+		}
+		if fv.CanAddr() {
+			if opt, ok := fv.Addr().Interface().(optionalField); ok {
+				var set bool
+				if fv, set = opt.validationTarget(); !set {
+					continue
+				}
+			}
+		}
+		validateValue(fieldName(f), fv, strings.Split(tag, ","), errs)
+	}
+}
+
+// fieldName はクライアントから見た f の名前を返します。
+func fieldName(f reflect.StructField) string {
+	for _, key := range []string{"json", "form"} {
+		if name, _, _ := strings.Cut(f.Tag.Get(key), ","); name != "" && name != "-" {
+			return name
+		}
+	}
+	return f.Name
+}
+
+func validateValue(name string, v reflect.Value, rules []string, errs *[]*ErrValidation) {
+	// ポインターで与えられた値はゼロ値でも指定されたものとして検証する
+	given := false
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			if containsRule(rules, "required") {
+				*errs = append(*errs, &ErrValidation{Field: name, Message: "is required"})
+			}
+			return
+		}
+		v, given = v.Elem(), true
+	}
+
+	for i, rule := range rules {
+		rule, arg, _ := strings.Cut(strings.TrimSpace(rule), "=")
+		if rule == "dive" {
+			if v.Kind() == reflect.Slice {
+				for j := 0; j < v.Len(); j++ {
+					validateValue(fmt.Sprintf("%s[%d]", name, j), v.Index(j), rules[i+1:], errs)
+				}
+			}
+			return
+		}
+		if msg := applyRule(v, rule, arg, given); msg != "" {
+			*errs = append(*errs, &ErrValidation{Field: name, Message: msg})
+			return
+		}
+	}
+}
+
+func containsRule(rules []string, rule string) bool {
+	for _, r := range rules {
+		if strings.TrimSpace(r) == rule {
+			return true
+		}
+	}
+	return false
+}
+
+// applyRule は v を rule で検証し、違反している場合はそのメッセージを、満たしている場合は "" を返します。
+// ゼロ値は未指定として検証しませんが、given が true の場合は min と max をゼロ値にも適用します。
+func applyRule(v reflect.Value, rule, arg string, given bool) string {
+	switch rule {
+	case "trim":
+		if v.Kind() == reflect.String && v.CanSet() {
+			v.SetString(strings.TrimSpace(v.String()))
+		}
+		return ""
+	case "required":
+		if isBlank(v) {
+			return "is required"
+		}
+		return ""
+	}
+
+	if v.IsZero() && !(given && (rule == "min" || rule == "max")) {
+		return ""
+	}
+	switch rule {
+	case "max":
+		n, _ := strconv.ParseInt(arg, 10, 64)
+		switch v.Kind() {
+		case reflect.String:
+			if int64(utf8.RuneCountInString(v.String())) > n {
+				return fmt.Sprintf("must be at most %d characters", n)
+			}
+		case reflect.Slice:
+			if int64(v.Len()) > n {
+				return fmt.Sprintf("must have at most %d items", n)
+			}
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			if v.Int() > n {
+				return fmt.Sprintf("must be at most %d", n)
+			}
+		}
+	case "min":
+		n, _ := strconv.ParseInt(arg, 10, 64)
+		switch v.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			if v.Int() < n {
+				return fmt.Sprintf("must be at least %d", n)
+			}
+		}
+	case "oneof":
+		values := strings.Fields(arg)
+		if v.Kind() == reflect.String {
+			for _, value := range values {
+				if v.String() == value {
+					return ""
+				}
+			}
+			return "must be one of " + strings.Join(values, ", ")
+		}
+	}
+	return ""
+}
+
+// isBlank は v がゼロ値かどうかを返します。空白だけの文字列もゼロ値として扱います。
+func isBlank(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.String:
+		return strings.TrimSpace(v.String()) == ""
+	case reflect.Slice, reflect.Map:
+		return v.Len() == 0
+	}
+	return v.IsZero()
+}
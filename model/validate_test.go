@@ -0,0 +1,103 @@
+package model_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/TechBowl-japan/go-stations/model"
+)
+
+func TestValidate(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		req        interface{}
+		wantFields []string
+	}{
+		"Valid create request": {
+			req: &model.CreateTODORequest{Subject: "subject"},
+		},
+		"Blank subject": {
+			req:        &model.CreateTODORequest{Subject: "   "},
+			wantFields: []string{"subject"},
+		},
+		"All violations are reported": {
+			req: &model.CreateTODORequest{
+				Subject: strings.Repeat("a", 201),
+				TODOAttributes: model.TODOAttributes{
+					Priority: "asap",
+					Tags:     []string{"ok", ""},
+				},
+			},
+			wantFields: []string{"subject", "priority", "tags[1]"},
+		},
+		"Too many ids": {
+			req:        &model.DeleteTODORequest{IDs: make([]int64, 101)},
+			wantFields: []string{"ids"},
+		},
+		"Unknown enum in query": {
+			req:        &model.ReadTODORequest{Status: "closed", Size: -1},
+			wantFields: []string{"size", "status"},
+		},
+		"Zero project id": {
+			req: &model.CreateTODORequest{
+				Subject:        "subject",
+				TODOAttributes: model.TODOAttributes{ProjectID: new(int64)},
+			},
+			wantFields: []string{"project_id"},
+		},
+		"Unset patch fields are skipped": {
+			req: &model.TODOPatch{},
+		},
+		"Zero project id in patch": {
+			req: &model.TODOPatch{
+				TODOAttributesPatch: model.TODOAttributesPatch{ProjectID: model.Optional[*int64]{Set: true, Value: new(int64)}},
+			},
+			wantFields: []string{"project_id"},
+		},
+		"Null subject in patch": {
+			req:        &model.TODOPatch{Subject: model.Optional[string]{Set: true}},
+			wantFields: []string{"subject"},
+		},
+	}
+
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			err := model.Validate(c.req)
+			if len(c.wantFields) == 0 {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+
+			var errValidationFailed *model.ErrValidationFailed
+			if !errors.As(err, &errValidationFailed) {
+				t.Fatalf("unexpected error, got = %v", err)
+			}
+			var got []string
+			for _, e := range errValidationFailed.Errors {
+				got = append(got, e.Field)
+			}
+			if strings.Join(got, ",") != strings.Join(c.wantFields, ",") {
+				t.Errorf("unexpected fields, got = %v, want = %v", got, c.wantFields)
+			}
+		})
+	}
+}
+
+func TestValidateTrims(t *testing.T) {
+	t.Parallel()
+
+	req := &model.CreateTODORequest{Subject: "  subject  ", TODOAttributes: model.TODOAttributes{Tags: []string{" tag "}}}
+	if err := model.Validate(req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if req.Subject != "subject" || req.Tags[0] != "tag" {
+		t.Errorf("values are not trimmed, got = %q, %q", req.Subject, req.Tags[0])
+	}
+}
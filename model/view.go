@@ -0,0 +1,230 @@
+package model
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// TODOExpansion は TODO に展開して返す関連データです。
+type TODOExpansion string
+
+const (
+	// TODOExpandTags は fields で選ばなかった場合もタグを返すことを表します。
+	TODOExpandTags TODOExpansion = "tags"
+	// TODOExpandProject は所属するプロジェクトを Todo.Project に展開することを表します。
+	TODOExpandProject TODOExpansion = "project"
+	// TODOExpandSubtasks は子 TODO の件数を Todo.Subtasks に展開することを表します。
+	TODOExpandSubtasks TODOExpansion = "subtasks"
+)
+
+// todoViewFieldOrder は fields で選べる Todo の項目で、JSON に出力する順に並べています。
+var todoViewFieldOrder = []string{
+	"id",
+	"subject",
+	"description",
+	"done",
+	"completed_at",
+	"due_at",
+	"priority",
+	"tags",
+	"project_id",
+	"parent_id",
+	"recurrence",
+	"created_at",
+	"updated_at",
+}
+
+// todoViewFields は fields で選べる Todo の項目です。
+var todoViewFields = func() map[string]bool {
+	fields := make(map[string]bool, len(todoViewFieldOrder))
+	for _, f := range todoViewFieldOrder {
+		fields[f] = true
+	}
+	return fields
+}()
+
+// todoExpansions は expand で指定できる関連データです。
+var todoExpansions = map[string]bool{
+	string(TODOExpandTags):     true,
+	string(TODOExpandProject):  true,
+	string(TODOExpandSubtasks): true,
+}
+
+// SubtaskCounts は TODO の子 TODO の件数です。ゴミ箱にある子 TODO は数えません。
+type SubtaskCounts struct {
+	Total int64 `json:"total"`
+	Done  int64 `json:"done"`
+}
+
+// TODOView は TODO を読み込む際に返す項目と展開する関連データを表します。
+type TODOView struct {
+	// Fields は返す項目で、空の場合はすべての項目を返します。id は常に返します。
+	Fields []string
+	Expand []TODOExpansion
+}
+
+// ParseTODOView は fields と expand のカンマ区切りの文字列を解析します。
+func ParseTODOView(fields, expand string) (*TODOView, error) {
+	var view TODOView
+	var err error
+	if view.Fields, err = splitList("fields", fields, todoViewFields); err != nil {
+		return nil, err
+	}
+	expansions, err := splitList("expand", expand, todoExpansions)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range expansions {
+		view.Expand = append(view.Expand, TODOExpansion(e))
+	}
+	return &view, nil
+}
+
+// splitList はパラメーター name のカンマ区切りの文字列 s を分割し、各要素が allowed にあるかを確認します。
+// 重複した要素は取り除きます。
+func splitList(name, s string, allowed map[string]bool) ([]string, error) {
+	if s == "" {
+		return nil, nil
+	}
+	var items []string
+	seen := make(map[string]bool)
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if !allowed[item] {
+			return nil, &ErrValidation{Field: name, Message: fmt.Sprintf("unknown value %q; allowed values are %s", item, strings.Join(sortedKeys(allowed), ", "))}
+		}
+		if !seen[item] {
+			seen[item] = true
+			items = append(items, item)
+		}
+	}
+	return items, nil
+}
+
+// Selects は項目 field を返すかどうかを返します。
+func (v *TODOView) Selects(field string) bool {
+	if v == nil || len(v.Fields) == 0 || field == "id" {
+		return true
+	}
+	for _, f := range v.Fields {
+		if f == field {
+			return true
+		}
+	}
+	return field == "tags" && v.Expands(TODOExpandTags)
+}
+
+// Expands は関連データ e を展開するかどうかを返します。
+func (v *TODOView) Expands(e TODOExpansion) bool {
+	if v == nil {
+		return false
+	}
+	for _, x := range v.Expand {
+		if x == e {
+			return true
+		}
+	}
+	return false
+}
+
+// MarshalTODO は todo を v で選んだ項目と展開した関連データだけの JSON にします。
+// 選んだ項目は値が空でも省略せず、false や null、空の配列として返します。
+func (v *TODOView) MarshalTODO(todo *Todo) (json.RawMessage, error) {
+	if v == nil || len(v.Fields) == 0 {
+		return json.Marshal(todo)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	write := func(key string, value any) error {
+		b, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		if buf.Len() > 1 {
+			buf.WriteByte(',')
+		}
+		name, _ := json.Marshal(key)
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(b)
+		return nil
+	}
+	for _, field := range todoViewFieldOrder {
+		if !v.Selects(field) {
+			continue
+		}
+		if err := write(field, todoFieldValue(todo, field)); err != nil {
+			return nil, err
+		}
+	}
+	if v.Expands(TODOExpandProject) {
+		if err := write(string(TODOExpandProject), todo.Project); err != nil {
+			return nil, err
+		}
+	}
+	if v.Expands(TODOExpandSubtasks) {
+		if err := write(string(TODOExpandSubtasks), todo.Subtasks); err != nil {
+			return nil, err
+		}
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// todoFieldValue は todo の項目 field の値を返します。
+func todoFieldValue(todo *Todo, field string) any {
+	switch field {
+	case "id":
+		return todo.ID
+	case "subject":
+		return todo.Subject
+	case "description":
+		return todo.Description
+	case "done":
+		return todo.Done
+	case "completed_at":
+		return todo.CompletedAt
+	case "due_at":
+		return todo.DueAt
+	case "priority":
+		if todo.Priority == PriorityNone {
+			return nil
+		}
+		return todo.Priority
+	case "tags":
+		if todo.Tags == nil {
+			return []string{}
+		}
+		return todo.Tags
+	case "project_id":
+		return todo.ProjectID
+	case "parent_id":
+		return todo.ParentID
+	case "recurrence":
+		if todo.Recurrence == "" {
+			return nil
+		}
+		return todo.Recurrence
+	case "created_at":
+		return todo.CreatedAt
+	case "updated_at":
+		return todo.UpdatedAt
+	}
+	return nil
+}
+
+// marshalTODOs は todos を v で選んだ項目の JSON の配列にします。
+func (v *TODOView) marshalTODOs(todos []*Todo) ([]json.RawMessage, error) {
+	raws := make([]json.RawMessage, len(todos))
+	for i, todo := range todos {
+		raw, err := v.MarshalTODO(todo)
+		if err != nil {
+			return nil, err
+		}
+		raws[i] = raw
+	}
+	return raws, nil
+}
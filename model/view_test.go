@@ -0,0 +1,79 @@
+package model_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/TechBowl-japan/go-stations/model"
+)
+
+func TestTODOViewMarshalTODO(t *testing.T) {
+	t.Parallel()
+
+	todo := &model.Todo{
+		ID:          1,
+		Subject:     "subject",
+		Description: "description",
+		Tags:        []string{"work"},
+		CreatedAt:   time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC),
+		UpdatedAt:   time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC),
+		Subtasks:    &model.SubtaskCounts{Total: 2, Done: 1},
+	}
+
+	cases := map[string]struct {
+		fields string
+		expand string
+		want   string
+	}{
+		"All fields": {
+			want: `{"id":1,"subject":"subject","description":"description","done":false,"tags":["work"],"created_at":"2026-10-01T00:00:00Z","updated_at":"2026-10-01T00:00:00Z","subtasks":{"total":2,"done":1}}`,
+		},
+		"Selected fields keep id": {
+			fields: "subject",
+			want:   `{"id":1,"subject":"subject"}`,
+		},
+		"Done of an open todo": {
+			fields: "id,done",
+			want:   `{"id":1,"done":false}`,
+		},
+		"Unset fields are not omitted": {
+			fields: "priority,due_at,parent_id,recurrence",
+			want:   `{"id":1,"due_at":null,"priority":null,"parent_id":null,"recurrence":null}`,
+		},
+		"Expansions are kept": {
+			fields: "subject, subject",
+			expand: "tags,subtasks",
+			want:   `{"id":1,"subject":"subject","tags":["work"],"subtasks":{"total":2,"done":1}}`,
+		},
+	}
+
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			view, err := model.ParseTODOView(c.fields, c.expand)
+			if err != nil {
+				t.Fatalf("failed to parse view: %v", err)
+			}
+			got, err := view.MarshalTODO(todo)
+			if err != nil {
+				t.Fatalf("failed to marshal todo: %v", err)
+			}
+			if string(got) != c.want {
+				t.Errorf("unexpected json, got = %s, want = %s", got, c.want)
+			}
+		})
+	}
+}
+
+func TestParseTODOViewError(t *testing.T) {
+	t.Parallel()
+
+	if _, err := model.ParseTODOView("subject,version", ""); err == nil {
+		t.Error("unknown field is accepted")
+	}
+	if _, err := model.ParseTODOView("", "owner"); err == nil {
+		t.Error("unknown expansion is accepted")
+	}
+}
@@ -0,0 +1,127 @@
+package model
+
+import "time"
+
+// Webhook は TODO の変更を通知する送信先を表します。
+// Events が空の場合はすべての種類の変更を通知します。
+type Webhook struct {
+	ID     int64        `json:"id"`
+	URL    string       `json:"url"`
+	Events []TODOAction `json:"events"`
+	Active bool         `json:"active"`
+	// Secret は署名の鍵で、作成時のレスポンスにのみ含まれます。
+	Secret    string    `json:"secret,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// WebhookDeliveryStatus は Webhook の配信の状態を表します。
+type WebhookDeliveryStatus string
+
+const (
+	// WebhookDeliveryPending は配信を待っている、または再試行を待っていることを表します。
+	WebhookDeliveryPending WebhookDeliveryStatus = "pending"
+	// WebhookDeliverySucceeded は送信先が 2xx を返したことを表します。
+	WebhookDeliverySucceeded WebhookDeliveryStatus = "succeeded"
+	// WebhookDeliveryDead は再試行の上限に達して配信を諦めたことを表します。
+	WebhookDeliveryDead WebhookDeliveryStatus = "dead"
+)
+
+// WebhookDelivery は TODO の変更 1 件の Webhook への配信を表します。
+// NextAttemptAt は状態が pending の場合のみ設定されます。
+type WebhookDelivery struct {
+	ID             int64                 `json:"id"`
+	WebhookID      int64                 `json:"webhook_id"`
+	EventID        int64                 `json:"event_id"`
+	Action         TODOAction            `json:"action"`
+	Status         WebhookDeliveryStatus `json:"status"`
+	Attempts       int64                 `json:"attempts"`
+	NextAttemptAt  *time.Time            `json:"next_attempt_at,omitempty"`
+	LastAttemptAt  *time.Time            `json:"last_attempt_at,omitempty"`
+	LastStatusCode int                   `json:"last_status_code,omitempty"`
+	LastError      string                `json:"last_error,omitempty"`
+	CreatedAt      time.Time             `json:"created_at"`
+}
+
+// CreateWebhookRequest は POST /webhooks へのリクエストです。
+// Secret を省略した場合はランダムな値が生成されます。
+type CreateWebhookRequest struct {
+	URL    string       `json:"url" validate:"required,trim,max=2000"`
+	Events []TODOAction `json:"events" validate:"max=6,dive,oneof=create update delete restore purge revert"`
+	Secret string       `json:"secret" validate:"trim,max=200"`
+}
+
+// CreateWebhookResponse は POST /webhooks へのレスポンスです。
+type CreateWebhookResponse struct {
+	Webhook Webhook `json:"webhook"`
+}
+
+// ReadWebhookRequest は GET /webhooks へのリクエストです。
+type ReadWebhookRequest struct {
+	PrevID int64 `form:"prev_id"`
+	Size   int64 `form:"size" validate:"min=0,max=100"`
+}
+
+// ReadWebhookResponse は GET /webhooks へのレスポンスです。
+type ReadWebhookResponse struct {
+	Webhooks []*Webhook `json:"webhooks"`
+}
+
+// ReadWebhookByIDRequest は GET /webhooks/{id} へのリクエストです。
+type ReadWebhookByIDRequest struct {
+	ID int64 `json:"id"`
+}
+
+// ReadWebhookByIDResponse は GET /webhooks/{id} へのレスポンスです。
+type ReadWebhookByIDResponse struct {
+	Webhook Webhook `json:"webhook"`
+}
+
+// UpdateWebhookRequest は PUT /webhooks/{id} へのリクエストです。
+// Active を省略した場合は現在の値のまま、Secret を省略した場合は現在の鍵のままです。
+type UpdateWebhookRequest struct {
+	ID     int64        `json:"-"`
+	URL    string       `json:"url" validate:"required,trim,max=2000"`
+	Events []TODOAction `json:"events" validate:"max=6,dive,oneof=create update delete restore purge revert"`
+	Active *bool        `json:"active"`
+	Secret string       `json:"secret" validate:"trim,max=200"`
+}
+
+// UpdateWebhookResponse は PUT /webhooks/{id} へのレスポンスです。
+type UpdateWebhookResponse struct {
+	Webhook Webhook `json:"webhook"`
+}
+
+// DeleteWebhookRequest は DELETE /webhooks/{id} へのリクエストです。
+type DeleteWebhookRequest struct {
+	ID int64 `json:"id"`
+}
+
+// DeleteWebhookResponse は DELETE /webhooks/{id} へのレスポンスです。
+type DeleteWebhookResponse struct {
+}
+
+// ReadWebhookDeliveryRequest は GET /webhooks/{id}/deliveries へのリクエストです。
+type ReadWebhookDeliveryRequest struct {
+	WebhookID int64                 `json:"-"`
+	Status    WebhookDeliveryStatus `form:"status" validate:"oneof=pending succeeded dead"`
+	PrevID    int64                 `form:"prev_id"`
+	Size      int64                 `form:"size" validate:"min=0,max=100"`
+}
+
+// ReadWebhookDeliveryResponse は GET /webhooks/{id}/deliveries へのレスポンスです。
+type ReadWebhookDeliveryResponse struct {
+	Deliveries []*WebhookDelivery `json:"deliveries"`
+}
+
+// RedeliverWebhookRequest は POST /webhooks/{id}/deliveries/{delivery_id}/redeliver へのリクエストです。
+// 配信を pending に戻し、試行回数を 0 からやり直します。
+type RedeliverWebhookRequest struct {
+	WebhookID  int64 `json:"-"`
+	DeliveryID int64 `json:"-"`
+}
+
+// RedeliverWebhookResponse は POST /webhooks/{id}/deliveries/{delivery_id}/redeliver へのレスポンスです。
+type RedeliverWebhookResponse struct {
+	Delivery WebhookDelivery `json:"delivery"`
+}
@@ -0,0 +1,100 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/TechBowl-japan/go-stations/model"
+)
+
+// errBatchFailed rolls back the transaction of an atomic batch when one of its operations fails.
+var errBatchFailed = errors.New("service: batch operation failed")
+
+// BatchTODO runs ops in order in a single transaction on DB and returns their results in the same order.
+// With model.BatchAtomic, the default, the first failure rolls back every operation and the rest are skipped.
+// With model.BatchBestEffort each operation runs in its own savepoint, so that a failure rolls back only that operation.
+// The errors of the operations are set to the results; only the errors of the transaction itself are returned.
+func (s *TODOService) BatchTODO(ctx context.Context, ops []*model.BatchOperation, mode model.BatchMode) ([]*model.BatchResult, error) {
+	results := make([]*model.BatchResult, len(ops))
+	for i := range ops {
+		results[i] = &model.BatchResult{Index: i, Status: model.BatchSkipped}
+	}
+	bestEffort := mode == model.BatchBestEffort
+
+	err := s.withTx(ctx, func(tx *sql.Tx) error {
+		for i, op := range ops {
+			if bestEffort {
+				if _, err := tx.ExecContext(ctx, `SAVEPOINT batch_operation`); err != nil {
+					return err
+				}
+			}
+
+			result := results[i]
+			todo, next, err := runBatchOperation(ctx, tx, op)
+			if err != nil {
+				result.Status, result.Err = model.BatchFailed, err
+				if !bestEffort {
+					return errBatchFailed
+				}
+				// 失敗した操作の変更だけを取り消す
+				if _, err := tx.ExecContext(ctx, `ROLLBACK TO batch_operation`); err != nil {
+					return err
+				}
+			} else {
+				result.Status, result.TODO, result.Next = model.BatchSucceeded, todo, next
+			}
+
+			if bestEffort {
+				if _, err := tx.ExecContext(ctx, `RELEASE batch_operation`); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if errors.Is(err, errBatchFailed) {
+		for _, result := range results {
+			if result.Status == model.BatchSucceeded {
+				result.Status, result.TODO, result.Next = model.BatchRolledBack, nil, nil
+			}
+		}
+		return results, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// runBatchOperation runs op in tx and returns the TODO it changed, and the next occurrence created by completing it.
+func runBatchOperation(ctx context.Context, tx *sql.Tx, op *model.BatchOperation) (todo, next *model.Todo, err error) {
+	errDataRequired := &model.ErrValidation{Field: "data", Message: "is required"}
+
+	switch op.Op {
+	case model.BatchCreate:
+		if op.Create == nil {
+			return nil, nil, errDataRequired
+		}
+		todo, err = createTODO(ctx, tx, op.Create.Subject, op.Create.Description, &op.Create.TODOAttributes)
+		return todo, nil, err
+	case model.BatchUpdate:
+		if op.Update == nil {
+			return nil, nil, errDataRequired
+		}
+		todo, err = patchTODO(ctx, tx, op.Update.ID, op.Update.Patch(), op.Update.IfMatch)
+		return todo, nil, err
+	case model.BatchDelete:
+		if op.Delete == nil {
+			return nil, nil, errDataRequired
+		}
+		return nil, nil, deleteTODOs(ctx, tx, op.Delete.IDs, op.Delete.Children, op.Delete.IfMatch)
+	case model.BatchComplete:
+		if op.TODOID == 0 {
+			return nil, nil, &model.ErrValidation{Field: "todo_id", Message: "is required"}
+		}
+		return completeTODO(ctx, tx, op.TODOID)
+	}
+	return nil, nil, &model.ErrValidation{Field: "op", Message: "must be one of create, update, delete, complete"}
+}
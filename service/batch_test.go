@@ -0,0 +1,91 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/TechBowl-japan/go-stations/db"
+	"github.com/TechBowl-japan/go-stations/model"
+	"github.com/TechBowl-japan/go-stations/service"
+)
+
+func TestBatchTODO(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		mode     model.BatchMode
+		statuses []model.BatchResultStatus
+		subjects []string
+	}{
+		"atomic": {
+			mode:     model.BatchAtomic,
+			statuses: []model.BatchResultStatus{model.BatchRolledBack, model.BatchRolledBack, model.BatchFailed, model.BatchSkipped},
+			subjects: []string{"existing"},
+		},
+		"best effort": {
+			mode:     model.BatchBestEffort,
+			statuses: []model.BatchResultStatus{model.BatchSucceeded, model.BatchSucceeded, model.BatchFailed, model.BatchSucceeded},
+			subjects: []string{"renamed", "created"},
+		},
+	}
+
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			todoDB, err := db.NewDB(filepath.Join(t.TempDir(), "todo.db"))
+			if err != nil {
+				t.Fatalf("failed to create db: %v", err)
+			}
+			t.Cleanup(func() { todoDB.Close() })
+
+			ctx := context.Background()
+			svc := service.NewTODOService(todoDB)
+			existing, err := svc.CreateTODO(ctx, "existing", "")
+			if err != nil {
+				t.Fatalf("failed to create todo: %v", err)
+			}
+
+			results, err := svc.BatchTODO(ctx, []*model.BatchOperation{
+				{Op: model.BatchUpdate, Update: &model.UpdateTODORequest{ID: existing.ID, Subject: "renamed"}},
+				{Op: model.BatchCreate, Create: &model.CreateTODORequest{Subject: "created"}},
+				{Op: model.BatchComplete, TODOID: 999},
+				// 同じバッチで作成した TODO も操作できる
+				{Op: model.BatchComplete, TODOID: existing.ID + 1},
+			}, c.mode)
+			if err != nil {
+				t.Fatalf("failed to run batch: %v", err)
+			}
+
+			for i, want := range c.statuses {
+				if results[i].Index != i || results[i].Status != want {
+					t.Errorf("unexpected result %d, got = %+v, want status = %s", i, results[i], want)
+				}
+			}
+			var errNotFound *model.ErrNotFound
+			if !errors.As(results[2].Err, &errNotFound) {
+				t.Errorf("unexpected error, got = %v", results[2].Err)
+			}
+
+			todos, err := svc.ReadTODO(ctx, 0, 10)
+			if err != nil {
+				t.Fatalf("failed to read todos: %v", err)
+			}
+			var subjects []string
+			for _, todo := range todos {
+				subjects = append(subjects, todo.Subject)
+			}
+			if len(subjects) != len(c.subjects) {
+				t.Fatalf("unexpected todos, got = %v, want = %v", subjects, c.subjects)
+			}
+			for i := range subjects {
+				if subjects[i] != c.subjects[i] {
+					t.Errorf("unexpected todos, got = %v, want = %v", subjects, c.subjects)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,227 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"github.com/TechBowl-japan/go-stations/model"
+)
+
+// cursorKeyName is the name of the key signing the cursors of ListTODOPage in secrets.
+const cursorKeyName = "todo_cursor"
+
+// todoCursor is the content of a cursor of ListTODOPage.
+type todoCursor struct {
+	// ID is the TODO at the boundary of the page the cursor was made from.
+	ID int64 `json:"id"`
+	// Keys are the sort keys of ID, so that the next page is read even when ID is purged meanwhile.
+	// Cursors made before the keys were added have none, and the keys are read from ID.
+	Keys []interface{} `json:"keys,omitempty"`
+	// Backward is set for the cursor reading the page before ID instead of after it.
+	Backward bool `json:"backward,omitempty"`
+	// Sort is the order of the pages, formatted by model.FormatSort.
+	Sort string `json:"sort,omitempty"`
+}
+
+// errInvalidCursor is returned for a cursor that was not made by ListTODOPage.
+var errInvalidCursor = &model.ErrValidation{Field: "cursor", Message: "is invalid"}
+
+// ListTODOPage reads a page of TODOs matching q on DB with the cursors of the pages before and after it.
+// The page starts after q.Cursor, or after q.PrevID when q.Cursor is empty.
+// The cursors are opaque and signed with a key kept on DB, so that they stay valid across restarts.
+// q.View chooses the columns to read and the related data to expand.
+func (s *TODOService) ListTODOPage(ctx context.Context, q *model.TODOQuery) (*model.TODOPage, error) {
+	key, err := readCursorKey(ctx, s.db)
+	if err != nil {
+		return nil, err
+	}
+
+	cursor := todoCursor{ID: q.PrevID, Sort: model.FormatSort(q.Order)}
+	if q.Cursor != "" {
+		if cursor, err = decodeTODOCursor(key, q.Cursor); err != nil {
+			return nil, err
+		}
+		order, err := model.ParseSort(cursor.Sort)
+		if err != nil {
+			return nil, errInvalidCursor
+		}
+		if len(q.Order) > 0 && model.FormatSort(q.Order) != cursor.Sort {
+			return nil, &model.ErrValidation{Field: "cursor", Message: "was made for another sort"}
+		}
+		// 並び順はカーソルを作った際のものを使う
+		cursorQuery := *q
+		cursorQuery.Order = order
+		q = &cursorQuery
+	}
+
+	l, err := newTODOListQuery(q)
+	if err != nil {
+		return nil, err
+	}
+	var after *keyset
+	switch {
+	case cursor.Keys != nil:
+		if len(cursor.Keys) != len(l.order) {
+			return nil, errInvalidCursor
+		}
+		after = &keyset{keys: cursor.Keys}
+	case cursor.ID > 0:
+		field := "prev_id"
+		if q.Cursor != "" {
+			field = "cursor"
+		}
+		if after, err = readKeyset(ctx, s.db, l.order, cursor.ID, field); err != nil {
+			return nil, err
+		}
+	}
+	// 前のページは逆順に読み、読んだ後で元の順に戻す
+	columns := l.order
+	if cursor.Backward {
+		columns = reverseOrder(columns)
+	}
+
+	size := pageSize(q)
+	todos, keys, err := l.readPage(ctx, s.db, columns, after, size+1)
+	if err != nil {
+		return nil, err
+	}
+	// 1 件多く読めた場合は読んだ向きにまだページがある
+	more := int64(len(todos)) > size
+	if more {
+		todos, keys = todos[:size], keys[:size]
+	}
+	var behind bool
+	if after != nil {
+		if behind, err = l.existsBefore(ctx, s.db, columns, after); err != nil {
+			return nil, err
+		}
+	}
+	hasNext, hasPrev := more, behind
+	if cursor.Backward {
+		for i, j := 0, len(todos)-1; i < j; i, j = i+1, j-1 {
+			todos[i], todos[j] = todos[j], todos[i]
+			keys[i], keys[j] = keys[j], keys[i]
+		}
+		hasNext, hasPrev = behind, more
+	}
+
+	if err := expandTODOs(ctx, s.db, todos, q.View); err != nil {
+		return nil, err
+	}
+
+	page := &model.TODOPage{Todos: todos}
+	if len(todos) > 0 {
+		last := len(todos) - 1
+		if hasNext {
+			page.NextCursor = encodeTODOCursor(key, todoCursor{ID: todos[last].ID, Keys: keys[last].keys, Sort: cursor.Sort})
+		}
+		if hasPrev {
+			page.PrevCursor = encodeTODOCursor(key, todoCursor{ID: todos[0].ID, Keys: keys[0].keys, Backward: true, Sort: cursor.Sort})
+		}
+	}
+	if q.CountTotal {
+		count, err := l.count(ctx, s.db)
+		if err != nil {
+			return nil, err
+		}
+		page.TotalCount = &count
+	}
+	return page, nil
+}
+
+// existsBefore reports whether a TODO matches l up to after in the order of columns,
+// that is, whether there is a page before the TODOs after it.
+func (l *todoListQuery) existsBefore(ctx context.Context, q queryer, columns []sortColumn, after *keyset) (bool, error) {
+	cond, args := after.condition(columns)
+	query := `SELECT EXISTS (SELECT 1 FROM todos WHERE ` + strings.Join(l.where, " AND ") + ` AND NOT ` + cond + `)`
+	var exists bool
+	err := q.QueryRowContext(ctx, query, append(l.args[:len(l.args):len(l.args)], args...)...).Scan(&exists)
+	return exists, err
+}
+
+// count returns the number of TODOs matching l.
+func (l *todoListQuery) count(ctx context.Context, q queryer) (int64, error) {
+	var count int64
+	err := q.QueryRowContext(ctx, `SELECT COUNT(*) FROM todos WHERE `+strings.Join(l.where, " AND "), l.args...).Scan(&count)
+	return count, err
+}
+
+// reverseOrder returns columns in the opposite direction.
+func reverseOrder(columns []sortColumn) []sortColumn {
+	reversed := make([]sortColumn, len(columns))
+	for i, c := range columns {
+		reversed[i] = sortColumn{expr: c.expr, desc: !c.desc}
+	}
+	return reversed
+}
+
+// readCursorKey reads the key signing the cursors, creating it on the first call.
+func readCursorKey(ctx context.Context, db *sql.DB) ([]byte, error) {
+	const query = `SELECT value FROM secrets WHERE name = ?`
+
+	var key []byte
+	err := db.QueryRowContext(ctx, query, cursorKeyName).Scan(&key)
+	if errors.Is(err, sql.ErrNoRows) {
+		// 同時に作られた場合は先に作られた鍵を使う
+		if _, err := db.ExecContext(ctx, `INSERT OR IGNORE INTO secrets (name, value) VALUES (?, randomblob(32))`, cursorKeyName); err != nil {
+			return nil, err
+		}
+		err = db.QueryRowContext(ctx, query, cursorKeyName).Scan(&key)
+	}
+	return key, err
+}
+
+// encodeTODOCursor returns c as base64url JSON followed by a dot and its base64url HMAC-SHA256 keyed with key.
+func encodeTODOCursor(key []byte, c todoCursor) string {
+	payload, _ := json.Marshal(c)
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(signCursor(key, encoded))
+}
+
+// decodeTODOCursor verifies and decodes s made by encodeTODOCursor.
+func decodeTODOCursor(key []byte, s string) (todoCursor, error) {
+	var c todoCursor
+	encoded, signature, ok := strings.Cut(s, ".")
+	if !ok {
+		return c, errInvalidCursor
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(mac, signCursor(key, encoded)) {
+		return c, errInvalidCursor
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return c, errInvalidCursor
+	}
+	// 整数のキーを float64 にせずに比較できるよう、数値は json.Number として読む
+	dec := json.NewDecoder(bytes.NewReader(payload))
+	dec.UseNumber()
+	if dec.Decode(&c) != nil || c.ID <= 0 {
+		return c, errInvalidCursor
+	}
+	for i, k := range c.Keys {
+		n, ok := k.(json.Number)
+		if !ok {
+			continue
+		}
+		if v, err := n.Int64(); err == nil {
+			c.Keys[i] = v
+		} else if c.Keys[i], err = n.Float64(); err != nil {
+			return c, errInvalidCursor
+		}
+	}
+	return c, nil
+}
+
+func signCursor(key []byte, encoded string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(encoded))
+	return mac.Sum(nil)
+}
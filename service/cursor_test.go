@@ -0,0 +1,181 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/TechBowl-japan/go-stations/db"
+	"github.com/TechBowl-japan/go-stations/model"
+	"github.com/TechBowl-japan/go-stations/service"
+)
+
+func TestListTODOPage(t *testing.T) {
+	t.Parallel()
+
+	todoDB, err := db.NewDB(filepath.Join(t.TempDir(), "todo.db"))
+	if err != nil {
+		t.Fatalf("failed to create db: %v", err)
+	}
+	t.Cleanup(func() { todoDB.Close() })
+
+	ctx := context.Background()
+	svc := service.NewTODOService(todoDB)
+
+	for _, todo := range []struct {
+		subject  string
+		priority model.Priority
+	}{
+		{"a", model.PriorityLow},
+		{"b", model.PriorityHigh},
+		{"c", model.PriorityLow},
+		{"d", model.PriorityUrgent},
+		{"e", model.PriorityHigh},
+	} {
+		if _, err := svc.CreateTODOWithAttributes(ctx, todo.subject, "", &model.TODOAttributes{Priority: todo.priority}); err != nil {
+			t.Fatalf("failed to create todo: %v", err)
+		}
+	}
+
+	order, err := model.ParseSort("-priority")
+	if err != nil {
+		t.Fatalf("failed to parse sort: %v", err)
+	}
+	subjects := func(page *model.TODOPage) string {
+		var s string
+		for _, todo := range page.Todos {
+			s += todo.Subject
+		}
+		return s
+	}
+
+	first, err := svc.ListTODOPage(ctx, &model.TODOQuery{Size: 2, Order: order, CountTotal: true})
+	if err != nil {
+		t.Fatalf("failed to list todos: %v", err)
+	}
+	if subjects(first) != "db" || first.NextCursor == "" || first.PrevCursor != "" || first.TotalCount == nil || *first.TotalCount != 5 {
+		t.Fatalf("unexpected first page, got = %s %+v", subjects(first), first)
+	}
+
+	// 2 ページ目以降は並び順を指定しなくてもカーソルの並び順で読む
+	second, err := svc.ListTODOPage(ctx, &model.TODOQuery{Size: 2, Cursor: first.NextCursor})
+	if err != nil {
+		t.Fatalf("failed to list todos: %v", err)
+	}
+	if subjects(second) != "ea" || second.NextCursor == "" || second.PrevCursor == "" {
+		t.Fatalf("unexpected second page, got = %s %+v", subjects(second), second)
+	}
+
+	last, err := svc.ListTODOPage(ctx, &model.TODOQuery{Size: 2, Cursor: second.NextCursor})
+	if err != nil {
+		t.Fatalf("failed to list todos: %v", err)
+	}
+	if subjects(last) != "c" || last.NextCursor != "" || last.PrevCursor == "" {
+		t.Fatalf("unexpected last page, got = %s %+v", subjects(last), last)
+	}
+
+	back, err := svc.ListTODOPage(ctx, &model.TODOQuery{Size: 2, Cursor: last.PrevCursor})
+	if err != nil {
+		t.Fatalf("failed to list todos: %v", err)
+	}
+	if subjects(back) != "ea" || back.NextCursor == "" || back.PrevCursor == "" {
+		t.Fatalf("unexpected previous page, got = %s %+v", subjects(back), back)
+	}
+
+	var errValidation *model.ErrValidation
+	if _, err := svc.ListTODOPage(ctx, &model.TODOQuery{Cursor: first.NextCursor + "x"}); !errors.As(err, &errValidation) {
+		t.Errorf("tampered cursor is accepted, err = %v", err)
+	}
+	if _, err := svc.ListTODOPage(ctx, &model.TODOQuery{Cursor: first.NextCursor, Order: []model.SortKey{{Field: "subject"}}}); !errors.As(err, &errValidation) {
+		t.Errorf("cursor is accepted for another sort, err = %v", err)
+	}
+
+	// 別のサービスでも同じ鍵でカーソルを検証できる
+	if _, err := service.NewTODOService(todoDB).ListTODOPage(ctx, &model.TODOQuery{Cursor: first.NextCursor}); err != nil {
+		t.Errorf("cursor is rejected by another service, err = %v", err)
+	}
+}
+
+func TestListTODOPurgedBoundary(t *testing.T) {
+	t.Parallel()
+
+	todoDB, err := db.NewDB(filepath.Join(t.TempDir(), "todo.db"))
+	if err != nil {
+		t.Fatalf("failed to create db: %v", err)
+	}
+	t.Cleanup(func() { todoDB.Close() })
+
+	ctx := context.Background()
+	svc := service.NewTODOService(todoDB)
+	for _, subject := range []string{"e", "d", "c", "b", "a"} {
+		if _, err := svc.CreateTODO(ctx, subject, ""); err != nil {
+			t.Fatalf("failed to create todo: %v", err)
+		}
+	}
+	purge := func(id int64) {
+		t.Helper()
+		if err := svc.DeleteTODO(ctx, []int64{id}); err != nil {
+			t.Fatalf("failed to delete todo: %v", err)
+		}
+		if _, err := svc.PurgeTODO(ctx, time.Now().Add(time.Minute)); err != nil {
+			t.Fatalf("failed to purge todos: %v", err)
+		}
+	}
+	ids := func(todos []*model.Todo) []int64 {
+		ids := make([]int64, len(todos))
+		for i, todo := range todos {
+			ids[i] = todo.ID
+		}
+		return ids
+	}
+
+	order, err := model.ParseSort("subject")
+	if err != nil {
+		t.Fatalf("failed to parse sort: %v", err)
+	}
+	first, err := svc.ListTODOPage(ctx, &model.TODOQuery{Size: 2, Order: order})
+	if err != nil {
+		t.Fatalf("failed to list todos: %v", err)
+	}
+	purge(2)
+	purge(4)
+
+	// 境界の TODO が削除されても、カーソルに含まれる並び順のキーの後から読む
+	second, err := svc.ListTODOPage(ctx, &model.TODOQuery{Size: 2, Cursor: first.NextCursor})
+	if err != nil {
+		t.Fatalf("failed to list todos: %v", err)
+	}
+	if got := ids(second.Todos); !cmp.Equal(got, []int64{3, 1}) {
+		t.Errorf("unexpected second page, got = %v", got)
+	}
+
+	// id だけで並べる場合は、prev_id の TODO が存在しなくても id の後から読む
+	todos, err := svc.ReadTODO(ctx, 2, 5)
+	if err != nil {
+		t.Fatalf("failed to read todos: %v", err)
+	}
+	if got := ids(todos); !cmp.Equal(got, []int64{3, 5}) {
+		t.Errorf("unexpected todos after prev_id, got = %v", got)
+	}
+
+	// 他の並び順では並び順のキーが分からないため、存在しない prev_id は拒否する
+	for name, list := range map[string]func() error{
+		"List": func() error {
+			_, err := svc.ListTODO(ctx, &model.TODOQuery{Size: 2, PrevID: 2, Order: order})
+			return err
+		},
+		"Page": func() error {
+			_, err := svc.ListTODOPage(ctx, &model.TODOQuery{Size: 2, PrevID: 4, Order: order})
+			return err
+		},
+	} {
+		var errValidation *model.ErrValidation
+		if err := list(); !errors.As(err, &errValidation) || errValidation.Field != "prev_id" {
+			t.Errorf("%s: unexpected error, got = %v", name, err)
+		}
+	}
+}
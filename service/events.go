@@ -0,0 +1,106 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+
+	"github.com/TechBowl-japan/go-stations/model"
+)
+
+// An eventBroker wakes up the watchers of TODO events when new events are committed.
+// The events themselves are read from todo_events, so a slow watcher never loses one.
+type eventBroker struct {
+	mu       sync.Mutex
+	watchers map[chan struct{}]struct{}
+}
+
+// brokers holds the eventBroker of each *sql.DB.
+var brokers sync.Map
+
+// brokerFor returns the eventBroker shared by all services on db,
+// so that changes made through any of them wake up every watcher.
+func brokerFor(db *sql.DB) *eventBroker {
+	b, _ := brokers.LoadOrStore(db, &eventBroker{watchers: make(map[chan struct{}]struct{})})
+	return b.(*eventBroker)
+}
+
+// subscribe returns a channel signaled after new events are committed, and a function to stop the subscription.
+func (b *eventBroker) subscribe() (<-chan struct{}, func()) {
+	ch := make(chan struct{}, 1)
+
+	b.mu.Lock()
+	b.watchers[ch] = struct{}{}
+	b.mu.Unlock()
+
+	return ch, func() {
+		b.mu.Lock()
+		delete(b.watchers, ch)
+		b.mu.Unlock()
+	}
+}
+
+// notify signals every watcher without blocking; a pending signal already covers the new events.
+func (b *eventBroker) notify() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for ch := range b.watchers {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// withTx runs fn in a transaction like withTx, and wakes up the watchers of TODO events once it is committed.
+func (s *TODOService) withTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	if err := withTx(ctx, s.db, fn); err != nil {
+		return err
+	}
+	s.events.notify()
+	return nil
+}
+
+// LatestTODOEventID returns the id of the latest TODO event on DB, or 0 when there is none.
+func (s *TODOService) LatestTODOEventID(ctx context.Context) (int64, error) {
+	var id int64
+	err := s.db.QueryRowContext(ctx, `SELECT IFNULL(MAX(id), 0) FROM todo_events`).Scan(&id)
+	return id, err
+}
+
+// WatchTODOEvents calls fn with every TODO event after lastID in order, waiting for new events
+// until ctx is done or fn returns an error. It returns the error of fn or ctx.
+func (s *TODOService) WatchTODOEvents(ctx context.Context, lastID int64, fn func(*model.TODOEvent) error) error {
+	const (
+		// 1 度に読み込むイベントの数
+		batchSize = 100
+		read      = `SELECT id, todo_id, action, actor, old_value, new_value, created_at FROM todo_events WHERE id > ? ORDER BY id ASC LIMIT ?`
+	)
+
+	// 読み込みの間にコミットされたイベントを取りこぼさないよう、先に購読する
+	wake, stop := s.events.subscribe()
+	defer stop()
+
+	for {
+		events, err := readTODOEvents(ctx, s.db, read, lastID, batchSize)
+		if err != nil {
+			return err
+		}
+		for _, event := range events {
+			if err := fn(event); err != nil {
+				return err
+			}
+			lastID = event.ID
+		}
+		if len(events) == batchSize {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-wake:
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/TechBowl-japan/go-stations/db"
+	"github.com/TechBowl-japan/go-stations/model"
+	"github.com/TechBowl-japan/go-stations/service"
+)
+
+func TestWatchTODOEvents(t *testing.T) {
+	t.Parallel()
+
+	todoDB, err := db.NewDB(filepath.Join(t.TempDir(), "todo.db"))
+	if err != nil {
+		t.Fatalf("failed to create db: %v", err)
+	}
+	t.Cleanup(func() { todoDB.Close() })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	svc := service.NewTODOService(todoDB)
+
+	missed, err := svc.CreateTODO(ctx, "missed", "")
+	if err != nil {
+		t.Fatalf("failed to create todo: %v", err)
+	}
+
+	events := make(chan *model.TODOEvent)
+	errCh := make(chan error, 1)
+	go func() {
+		// 購読前に作られた TODO のイベントも lastID から再開して受け取れる
+		errCh <- svc.WatchTODOEvents(ctx, 0, func(event *model.TODOEvent) error {
+			events <- event
+			return nil
+		})
+	}()
+
+	// 別のサービスからの変更も通知される
+	live, err := service.NewTODOService(todoDB).CreateTODO(ctx, "live", "")
+	if err != nil {
+		t.Fatalf("failed to create todo: %v", err)
+	}
+
+	for _, want := range []int64{missed.ID, live.ID} {
+		select {
+		case event := <-events:
+			if event.TODOID != want || event.Action != model.TODOActionCreate {
+				t.Errorf("unexpected event, got = %+v, want todo_id = %d", event, want)
+			}
+		case <-ctx.Done():
+			t.Fatalf("event for todo %d is not delivered", want)
+		}
+	}
+
+	cancel()
+	if err := <-errCh; !errors.Is(err, context.Canceled) {
+		t.Errorf("unexpected error, got = %v", err)
+	}
+}
@@ -0,0 +1,44 @@
+package service
+
+import (
+	"context"
+
+	"github.com/TechBowl-japan/go-stations/model"
+)
+
+// exportBatchSize is the number of TODOs ExportTODO reads with a query.
+const exportBatchSize = 100
+
+// ExportTODO calls fn with every TODO matching q on DB, in the order of q.Order.
+// The TODOs are read in batches with keyset pagination, so that they are never all held in memory
+// and the export continues even when the last TODO of a batch is deleted meanwhile.
+// q.PrevID, q.Cursor and q.Size are ignored. An error of fn stops the export and is returned.
+func (s *TODOService) ExportTODO(ctx context.Context, q *model.TODOQuery, fn func(todo *model.Todo) error) error {
+	l, err := newTODOListQuery(q)
+	if err != nil {
+		return err
+	}
+
+	var after *keyset
+	for {
+		todos, keys, err := l.readPage(ctx, s.db, l.order, after, exportBatchSize)
+		if err != nil {
+			return err
+		}
+		if err := expandTODOs(ctx, s.db, todos, q.View); err != nil {
+			return err
+		}
+
+		for _, todo := range todos {
+			if err := fn(todo); err != nil {
+				return err
+			}
+		}
+		if len(todos) < exportBatchSize {
+			return nil
+		}
+		// 次のバッチは最後に読んだ TODO の並び順のキーの後から読む。
+		// その TODO が書き出しの途中で削除されても続きを読める
+		after = keys[len(keys)-1]
+	}
+}
@@ -0,0 +1,257 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/TechBowl-japan/go-stations/model"
+)
+
+// A filterColumn is the column compared by a field of model.FilterCondition.
+type filterColumn struct {
+	name string
+}
+
+// filterColumns maps the filterable fields of model.ParseFilter, except tag, to their columns.
+var filterColumns = map[string]filterColumn{
+	"id":           {name: "id"},
+	"subject":      {name: "subject"},
+	"description":  {name: "description"},
+	"done":         {name: "done"},
+	"priority":     {name: "priority"},
+	"due_at":       {name: "due_at"},
+	"completed_at": {name: "completed_at"},
+	"created_at":   {name: "created_at"},
+	"updated_at":   {name: "updated_at"},
+	"project_id":   {name: "project_id"},
+	"parent_id":    {name: "parent_id"},
+}
+
+// sortExprs maps the sortable fields of model.ParseSort to expressions without NULL,
+// so that keyset pagination can compare them. Missing times sort after any time.
+var sortExprs = map[string]string{
+	// 時刻は UTC の文字列で保存されているため '9999' はどの時刻よりも後になる
+	"id":           "id",
+	"subject":      "subject",
+	"done":         "done",
+	"priority":     "priority",
+	"due_at":       "IFNULL(due_at, '9999')",
+	"completed_at": "IFNULL(completed_at, '9999')",
+	"created_at":   "created_at",
+	"updated_at":   "updated_at",
+}
+
+// likeEscaper escapes the wildcards of LIKE, with '\' as the escape character.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// compileFilter compiles expr into a SQL condition on todos and its arguments.
+// Only the whitelisted columns appear in the condition; every value is passed as an argument.
+func compileFilter(expr model.FilterExpr) (string, []interface{}, error) {
+	switch e := expr.(type) {
+	case *model.FilterAnd:
+		return compileBinaryFilter("AND", e.Left, e.Right)
+	case *model.FilterOr:
+		return compileBinaryFilter("OR", e.Left, e.Right)
+	case *model.FilterNot:
+		cond, args, err := compileFilter(e.Expr)
+		if err != nil {
+			return "", nil, err
+		}
+		return "NOT " + cond, args, nil
+	case *model.FilterCondition:
+		return compileFilterCondition(e)
+	}
+	return "", nil, fmt.Errorf("service: unknown filter expression %T", expr)
+}
+
+func compileBinaryFilter(op string, left, right model.FilterExpr) (string, []interface{}, error) {
+	l, largs, err := compileFilter(left)
+	if err != nil {
+		return "", nil, err
+	}
+	r, rargs, err := compileFilter(right)
+	if err != nil {
+		return "", nil, err
+	}
+	return "(" + l + " " + op + " " + r + ")", append(largs, rargs...), nil
+}
+
+// compileFilterCondition compiles a single comparison. Comparisons never evaluate to NULL,
+// so that NOT matches exactly the TODOs the comparison does not: "!=" is true for a missing value
+// and the other operators are false.
+func compileFilterCondition(c *model.FilterCondition) (string, []interface{}, error) {
+	if c.Field == "tag" {
+		const hasTag = `id IN (SELECT tt.todo_id FROM todo_tags tt JOIN tags t ON t.id = tt.tag_id WHERE t.name = ?)`
+		tag := strings.TrimSpace(fmt.Sprint(c.Value))
+		if c.Op == model.FilterNe {
+			return "NOT " + hasTag, []interface{}{tag}, nil
+		}
+		return hasTag, []interface{}{tag}, nil
+	}
+
+	column, ok := filterColumns[c.Field]
+	if !ok {
+		return "", nil, fmt.Errorf("service: unknown filter field %q", c.Field)
+	}
+	name := column.name
+
+	if c.Value == nil {
+		if c.Op == model.FilterNe {
+			return name + " IS NOT NULL", nil, nil
+		}
+		return name + " IS NULL", nil, nil
+	}
+
+	var arg interface{}
+	switch v := c.Value.(type) {
+	case bool:
+		arg = 0
+		if v {
+			arg = 1
+		}
+	case model.Priority:
+		arg = v.Rank()
+	case time.Time:
+		if c.Date {
+			return compileDateCondition(name, c.Op, startOfDay(v))
+		}
+		arg = dbTime(v)
+	default:
+		arg = v
+	}
+
+	switch c.Op {
+	case model.FilterEq:
+		return name + " IS ?", []interface{}{arg}, nil
+	case model.FilterNe:
+		return name + " IS NOT ?", []interface{}{arg}, nil
+	case model.FilterContains:
+		return fmt.Sprintf(`IFNULL(%s LIKE ? ESCAPE '\', 0)`, name), []interface{}{"%" + likeEscaper.Replace(fmt.Sprint(arg)) + "%"}, nil
+	case model.FilterLt, model.FilterLe, model.FilterGt, model.FilterGe:
+		return fmt.Sprintf("IFNULL(%s %s ?, 0)", name, c.Op), []interface{}{arg}, nil
+	}
+	return "", nil, fmt.Errorf("service: unknown filter operator %q", c.Op)
+}
+
+// compileDateCondition compiles a comparison with the whole day starting at day,
+// so that "=" matches any time in the day and "<=" includes the day.
+func compileDateCondition(name string, op model.FilterOp, day time.Time) (string, []interface{}, error) {
+	start, end := dbTime(day), dbTime(day.AddDate(0, 0, 1))
+	switch op {
+	case model.FilterEq:
+		return fmt.Sprintf("IFNULL(%[1]s >= ? AND %[1]s < ?, 0)", name), []interface{}{start, end}, nil
+	case model.FilterNe:
+		return fmt.Sprintf("NOT IFNULL(%[1]s >= ? AND %[1]s < ?, 0)", name), []interface{}{start, end}, nil
+	case model.FilterLt:
+		return fmt.Sprintf("IFNULL(%s < ?, 0)", name), []interface{}{start}, nil
+	case model.FilterLe:
+		return fmt.Sprintf("IFNULL(%s < ?, 0)", name), []interface{}{end}, nil
+	case model.FilterGt:
+		return fmt.Sprintf("IFNULL(%s >= ?, 0)", name), []interface{}{end}, nil
+	case model.FilterGe:
+		return fmt.Sprintf("IFNULL(%s >= ?, 0)", name), []interface{}{start}, nil
+	}
+	return "", nil, fmt.Errorf("service: unknown filter operator %q", op)
+}
+
+// A sortColumn is an expression of ORDER BY.
+type sortColumn struct {
+	expr string
+	desc bool
+}
+
+// compileOrder converts order to the expressions of ORDER BY, ending with id to make the order total.
+func compileOrder(order []model.SortKey) ([]sortColumn, error) {
+	columns := make([]sortColumn, 0, len(order)+1)
+	hasID := false
+	for _, key := range order {
+		expr, ok := sortExprs[key.Field]
+		if !ok {
+			return nil, fmt.Errorf("service: unknown sort field %q", key.Field)
+		}
+		columns = append(columns, sortColumn{expr: expr, desc: key.Desc})
+		hasID = hasID || key.Field == "id"
+	}
+	if !hasID {
+		columns = append(columns, sortColumn{expr: "id"})
+	}
+	return columns, nil
+}
+
+// orderByClause returns the ORDER BY clause of columns.
+func orderByClause(columns []sortColumn) string {
+	exprs := make([]string, len(columns))
+	for i, c := range columns {
+		exprs[i] = c.expr
+		if c.desc {
+			exprs[i] += " DESC"
+		}
+	}
+	return " ORDER BY " + strings.Join(exprs, ", ")
+}
+
+// A keyset is the position in the order of a list of TODOs after which a page starts.
+type keyset struct {
+	// keys are the sort keys of the TODO at the position, one for each sort column.
+	keys []interface{}
+}
+
+// condition returns the condition selecting the rows after k in the order of columns, with its arguments.
+func (k *keyset) condition(columns []sortColumn) (string, []interface{}) {
+	var (
+		terms []string
+		args  []interface{}
+	)
+	for i, c := range columns {
+		var parts []string
+		for j := 0; j < i; j++ {
+			parts = append(parts, columns[j].expr+" = ?")
+			args = append(args, k.keys[j])
+		}
+		op := ">"
+		if c.desc {
+			op = "<"
+		}
+		parts = append(parts, c.expr+" "+op+" ?")
+		args = append(args, k.keys[i])
+		terms = append(terms, "("+strings.Join(parts, " AND ")+")")
+	}
+	return "(" + strings.Join(terms, " OR ") + ")", args
+}
+
+// sortKeyColumns returns the select list of the sort keys of columns.
+// The keys are selected with the unary + so that the driver returns them as stored instead of converting times,
+// and they compare with the stored values when passed back as arguments.
+func sortKeyColumns(columns []sortColumn) string {
+	exprs := make([]string, len(columns))
+	for i, c := range columns {
+		exprs[i] = "+" + c.expr
+	}
+	return strings.Join(exprs, ", ")
+}
+
+// readKeyset reads the keyset of the TODO id in the order of columns. field is the parameter id was given in.
+// The keys of a TODO that no longer exists, such as a purged one, are unknown, so it is rejected
+// unless the TODOs are sorted by id only and the id itself is the key.
+func readKeyset(ctx context.Context, q queryer, columns []sortColumn, id int64, field string) (*keyset, error) {
+	keys := make([]interface{}, len(columns))
+	dest := make([]interface{}, len(columns))
+	for i := range keys {
+		dest[i] = &keys[i]
+	}
+	err := q.QueryRowContext(ctx, `SELECT `+sortKeyColumns(columns)+` FROM todos WHERE id = ?`, id).Scan(dest...)
+	if errors.Is(err, sql.ErrNoRows) {
+		if len(columns) == 1 && columns[0].expr == "id" {
+			return &keyset{keys: []interface{}{id}}, nil
+		}
+		return nil, &model.ErrValidation{Field: field, Message: fmt.Sprintf("refers to TODO %d, which no longer exists; start again from the first page", id)}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &keyset{keys: keys}, nil
+}
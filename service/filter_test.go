@@ -0,0 +1,128 @@
+package service_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/TechBowl-japan/go-stations/db"
+	"github.com/TechBowl-japan/go-stations/model"
+	"github.com/TechBowl-japan/go-stations/service"
+)
+
+func TestListTODOFilter(t *testing.T) {
+	t.Parallel()
+
+	todoDB, err := db.NewDB(filepath.Join(t.TempDir(), "todo.db"))
+	if err != nil {
+		t.Fatalf("failed to create db: %v", err)
+	}
+	t.Cleanup(func() { todoDB.Close() })
+
+	ctx := context.Background()
+	svc := service.NewTODOService(todoDB)
+
+	day := func(d int) *time.Time {
+		t := time.Date(2026, 11, d, 12, 0, 0, 0, time.Local)
+		return &t
+	}
+	for _, attrs := range []struct {
+		subject string
+		attrs   model.TODOAttributes
+	}{
+		{"a", model.TODOAttributes{Priority: model.PriorityHigh, DueAt: day(3), Tags: []string{"work"}}},
+		{"b", model.TODOAttributes{Priority: model.PriorityHigh, DueAt: day(1)}},
+		{"c", model.TODOAttributes{Priority: model.PriorityLow, DueAt: day(1), Tags: []string{"work"}}},
+		{"d", model.TODOAttributes{Priority: model.PriorityHigh}},
+		{"e_%", model.TODOAttributes{Priority: model.PriorityUrgent, DueAt: day(20)}},
+	} {
+		if _, err := svc.CreateTODOWithAttributes(ctx, attrs.subject, "", &attrs.attrs); err != nil {
+			t.Fatalf("failed to create todo: %v", err)
+		}
+	}
+	if _, _, err := svc.CompleteTODO(ctx, 3); err != nil {
+		t.Fatalf("failed to complete todo: %v", err)
+	}
+
+	cases := map[string]struct {
+		filter string
+		sort   string
+		want   []string
+	}{
+		"Filter and multiple sort keys": {
+			filter: "done:false AND due_at<2026-11-10",
+			sort:   "-priority,due_at",
+			want:   []string{"b", "a"},
+		},
+		"Date equality covers the day": {
+			filter: "due_at:2026-11-01",
+			want:   []string{"b", "c"},
+		},
+		"Date upper bound includes the day": {
+			filter: "due_at<=2026-11-03",
+			sort:   "-subject",
+			want:   []string{"c", "b", "a"},
+		},
+		"Missing due date sorts last": {
+			filter: "priority>=high",
+			sort:   "due_at",
+			want:   []string{"b", "a", "e_%", "d"},
+		},
+		"Null and negation": {
+			filter: "NOT (due_at=null OR tag:work)",
+			want:   []string{"b", "e_%"},
+		},
+		"Contains escapes wildcards": {
+			filter: `subject~"_%"`,
+			want:   []string{"e_%"},
+		},
+		"Priority then due date": {
+			sort: "-priority,due_at",
+			want: []string{"e_%", "b", "a", "d", "c"},
+		},
+	}
+
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			filter, err := model.ParseFilter(c.filter)
+			if err != nil {
+				t.Fatalf("failed to parse filter: %v", err)
+			}
+			order, err := model.ParseSort(c.sort)
+			if err != nil {
+				t.Fatalf("failed to parse sort: %v", err)
+			}
+
+			// 1 件ずつ読み、keyset pagination が並び順に従うことも確かめる
+			var (
+				got    []string
+				prevID int64
+			)
+			for i := 0; i <= len(c.want); i++ {
+				todos, err := svc.ListTODO(ctx, &model.TODOQuery{PrevID: prevID, Size: 1, Filter: filter, Order: order})
+				if err != nil {
+					t.Fatalf("failed to list todos: %v", err)
+				}
+				if len(todos) == 0 {
+					break
+				}
+				got = append(got, todos[0].Subject)
+				prevID = todos[0].ID
+			}
+
+			if len(got) != len(c.want) {
+				t.Fatalf("unexpected todos, got = %v, want = %v", got, c.want)
+			}
+			for i := range got {
+				if got[i] != c.want[i] {
+					t.Errorf("unexpected todos, got = %v, want = %v", got, c.want)
+					break
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,231 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/TechBowl-japan/go-stations/model"
+)
+
+// actorKey is the context key of the actor recorded in the TODO history.
+type actorKey struct{}
+
+// WithActor returns a copy of ctx whose changes are recorded in the TODO history as made by actor.
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// actorFromContext returns the actor set with WithActor, or "" when it is unknown.
+func actorFromContext(ctx context.Context) string {
+	actor, _ := ctx.Value(actorKey{}).(string)
+	return actor
+}
+
+// snapshotTODOs reads the TODOs ids, including the ones in the trash, keyed by id.
+func snapshotTODOs(ctx context.Context, q queryer, ids []int64) (map[int64]*model.Todo, error) {
+	snapshots := make(map[int64]*model.Todo, len(ids))
+	if len(ids) == 0 {
+		return snapshots, nil
+	}
+
+	todos, err := readTODOs(ctx, q, fmt.Sprintf(`SELECT `+todoColumns+` FROM todos WHERE id IN (%s)`, placeholders(len(ids))), int64Args(ids)...)
+	if err != nil {
+		return nil, err
+	}
+	for _, todo := range todos {
+		snapshots[todo.ID] = todo
+	}
+
+	return snapshots, nil
+}
+
+// recordTODOEvent records that the actor of ctx changed the TODO id from oldValue to newValue,
+// and queues its delivery to the webhooks subscribing to action.
+func recordTODOEvent(ctx context.Context, q queryer, action model.TODOAction, id int64, oldValue, newValue *model.Todo) error {
+	const (
+		insert = `INSERT INTO todo_events(todo_id, action, actor, old_value, new_value) VALUES(?, ?, ?, ?, ?)`
+	)
+
+	oldJSON, err := marshalSnapshot(oldValue)
+	if err != nil {
+		return err
+	}
+	newJSON, err := marshalSnapshot(newValue)
+	if err != nil {
+		return err
+	}
+
+	res, err := q.ExecContext(ctx, insert, id, action, actorFromContext(ctx), oldJSON, newJSON)
+	if err != nil {
+		return err
+	}
+	eventID, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	return enqueueWebhookDeliveries(ctx, q, eventID, action)
+}
+
+// recordTODOEvents records action for each of ids with its snapshots before and after the change.
+func recordTODOEvents(ctx context.Context, q queryer, action model.TODOAction, ids []int64, before, after map[int64]*model.Todo) error {
+	for _, id := range ids {
+		if err := recordTODOEvent(ctx, q, action, id, before[id], after[id]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// marshalSnapshot converts todo to the JSON stored in todo_events, or NULL when todo is nil.
+func marshalSnapshot(todo *model.Todo) (interface{}, error) {
+	if todo == nil {
+		return nil, nil
+	}
+	b, err := json.Marshal(todo)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// unmarshalSnapshot converts a JSON stored in todo_events back to a TODO.
+func unmarshalSnapshot(value sql.NullString) (*model.Todo, error) {
+	if !value.Valid {
+		return nil, nil
+	}
+	var todo model.Todo
+	if err := json.Unmarshal([]byte(value.String), &todo); err != nil {
+		return nil, err
+	}
+	return &todo, nil
+}
+
+// scanTODOEvent scans a row selected from todo_events.
+func scanTODOEvent(row rowScanner) (*model.TODOEvent, error) {
+	var (
+		event    model.TODOEvent
+		oldValue sql.NullString
+		newValue sql.NullString
+	)
+	if err := row.Scan(&event.ID, &event.TODOID, &event.Action, &event.Actor, &oldValue, &newValue, &event.CreatedAt); err != nil {
+		return nil, err
+	}
+
+	var err error
+	if event.OldValue, err = unmarshalSnapshot(oldValue); err != nil {
+		return nil, err
+	}
+	if event.NewValue, err = unmarshalSnapshot(newValue); err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+// readTODOEvents runs query selecting the columns of todo_events and reads the events.
+func readTODOEvents(ctx context.Context, q queryer, query string, args ...interface{}) ([]*model.TODOEvent, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := make([]*model.TODOEvent, 0)
+	for rows.Next() {
+		event, err := scanTODOEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+// ReadTODOHistory reads the change history of the TODO id on DB, oldest first.
+// The history is kept after the TODO is moved to the trash or purged.
+func (s *TODOService) ReadTODOHistory(ctx context.Context, id, prevID, size int64) ([]*model.TODOEvent, error) {
+	const (
+		exists = `SELECT EXISTS(SELECT 1 FROM todos WHERE id = ?) OR EXISTS(SELECT 1 FROM todo_events WHERE todo_id = ?)`
+		read   = `SELECT id, todo_id, action, actor, old_value, new_value, created_at FROM todo_events WHERE todo_id = ? AND id > ? ORDER BY id ASC LIMIT ?`
+	)
+
+	var found bool
+	if err := s.db.QueryRowContext(ctx, exists, id, id).Scan(&found); err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, &model.ErrNotFound{}
+	}
+
+	if size == 0 {
+		size = 5
+	}
+
+	return readTODOEvents(ctx, s.db, read, id, prevID, size)
+}
+
+// RevertTODO reverts the TODO id to its state right after the change revision on DB.
+// The subject, description, completion, due date, priority, tags, project, parent and recurrence are restored;
+// references to projects or parents that no longer exist result in *model.ErrValidation.
+// A non-nil ifMatch requires the TODO to be at one of the listed versions; see checkVersion.
+func (s *TODOService) RevertTODO(ctx context.Context, id, revision int64, ifMatch []int64) (*model.Todo, error) {
+	const (
+		readEvent = `SELECT id, todo_id, action, actor, old_value, new_value, created_at FROM todo_events WHERE id = ? AND todo_id = ?`
+		revert    = `UPDATE todos SET subject = ?, description = ?, done = ?, completed_at = ?, due_at = ?, priority = ?, project_id = ?, parent_id = ?, recurrence = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?`
+	)
+
+	var todo *model.Todo
+	err := s.withTx(ctx, func(tx *sql.Tx) error {
+		current, err := readTODOByID(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if err := checkVersion(ctx, tx, id, ifMatch); err != nil {
+			return err
+		}
+
+		event, err := scanTODOEvent(tx.QueryRowContext(ctx, readEvent, revision, id))
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return &model.ErrNotFound{Resource: "revision"}
+			}
+			return err
+		}
+		target := event.NewValue
+		if target == nil {
+			return &model.ErrValidation{Field: "revision", Message: "revision has no state to revert to"}
+		}
+
+		if err := checkProjectExists(ctx, tx, target.ProjectID); err != nil {
+			return err
+		}
+		if err := checkParent(ctx, tx, id, target.ParentID); err != nil {
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx, revert, target.Subject, target.Description, target.Done, nullableTime(target.CompletedAt), nullableTime(target.DueAt), target.Priority.Rank(), target.ProjectID, target.ParentID, target.Recurrence, id); err != nil {
+			return err
+		}
+		if err := setTODOTags(ctx, tx, id, target.Tags); err != nil {
+			return err
+		}
+
+		todo, err = readTODOByID(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		return recordTODOEvent(ctx, tx, model.TODOActionRevert, id, current, todo)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return todo, nil
+}
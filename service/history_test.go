@@ -0,0 +1,83 @@
+package service_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/TechBowl-japan/go-stations/db"
+	"github.com/TechBowl-japan/go-stations/model"
+	"github.com/TechBowl-japan/go-stations/service"
+)
+
+func TestTODOHistory(t *testing.T) {
+	t.Parallel()
+
+	todoDB, err := db.NewDB(filepath.Join(t.TempDir(), "todo.db"))
+	if err != nil {
+		t.Fatalf("failed to create db: %v", err)
+	}
+	t.Cleanup(func() { todoDB.Close() })
+
+	ctx := service.WithActor(context.Background(), "alice")
+	svc := service.NewTODOService(todoDB)
+
+	todo, err := svc.CreateTODOWithAttributes(ctx, "subject", "", &model.TODOAttributes{Tags: []string{"a"}})
+	if err != nil {
+		t.Fatalf("failed to create todo: %v", err)
+	}
+	if _, err := svc.UpdateTODO(context.Background(), todo.ID, "updated", "description"); err != nil {
+		t.Fatalf("failed to update todo: %v", err)
+	}
+	if _, _, err := svc.CompleteTODO(ctx, todo.ID); err != nil {
+		t.Fatalf("failed to complete todo: %v", err)
+	}
+
+	events, err := svc.ReadTODOHistory(ctx, todo.ID, 0, 10)
+	if err != nil {
+		t.Fatalf("failed to read history: %v", err)
+	}
+	if len(events) != 3 {
+		t.Fatalf("unexpected number of events, got = %d, want = 3", len(events))
+	}
+	if events[0].Action != model.TODOActionCreate || events[0].Actor != "alice" || events[0].OldValue != nil {
+		t.Errorf("unexpected create event, got = %+v", events[0])
+	}
+	if events[1].Actor != "" || events[1].OldValue.Subject != "subject" || events[1].NewValue.Subject != "updated" {
+		t.Errorf("unexpected update event, got = %+v", events[1])
+	}
+
+	reverted, err := svc.RevertTODO(ctx, todo.ID, events[0].ID, nil)
+	if err != nil {
+		t.Fatalf("failed to revert todo: %v", err)
+	}
+	if reverted.Subject != "subject" || reverted.Description != "" || reverted.Done || len(reverted.Tags) != 1 {
+		t.Errorf("unexpected reverted todo, got = %+v", reverted)
+	}
+
+	completeEventID := events[2].ID
+	events, err = svc.ReadTODOHistory(ctx, todo.ID, completeEventID, 10)
+	if err != nil {
+		t.Fatalf("failed to read history: %v", err)
+	}
+	if len(events) != 1 || events[0].Action != model.TODOActionRevert {
+		t.Errorf("unexpected events after revert, got = %+v", events)
+	}
+
+	// 完了した状態に戻すと、完了日時は SQLite が書き込む時刻と同じ形式で保存される
+	completed, err := svc.RevertTODO(ctx, todo.ID, completeEventID, nil)
+	if err != nil {
+		t.Fatalf("failed to revert todo: %v", err)
+	}
+	if !completed.Done || completed.CompletedAt == nil {
+		t.Errorf("unexpected reverted todo, got = %+v", completed)
+	}
+	var completedAt string
+	if err := todoDB.QueryRow(`SELECT +completed_at FROM todos WHERE id = ?`, todo.ID).Scan(&completedAt); err != nil {
+		t.Fatalf("failed to read completed_at: %v", err)
+	}
+	if _, err := time.Parse("2006-01-02 15:04:05", completedAt); err != nil {
+		t.Errorf("unexpected format of completed_at, got = %q", completedAt)
+	}
+}
@@ -0,0 +1,101 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/TechBowl-japan/go-stations/model"
+)
+
+// An IdempotencyService stores responses of requests sent with an Idempotency-Key.
+type IdempotencyService struct {
+	db *sql.DB
+}
+
+// NewIdempotencyService returns new IdempotencyService.
+func NewIdempotencyService(db *sql.DB) *IdempotencyService {
+	return &IdempotencyService{
+		db: db,
+	}
+}
+
+// ReserveIdempotencyKey reserves key for a request whose payload hashes to requestHash for lease.
+// It returns nil when key was newly reserved, and the stored response when the request was already completed.
+// A key reused with another payload results in *model.ErrIdempotencyKeyReused,
+// and a key whose request is still in progress results in *model.ErrConflict.
+// A reservation neither saved nor released within lease expires, so that a key left behind by a stopped server can be retried.
+func (s *IdempotencyService) ReserveIdempotencyKey(ctx context.Context, key, requestHash string, lease time.Duration) (*model.IdempotentResponse, error) {
+	const (
+		purge   = `DELETE FROM idempotency_keys WHERE expires_at <= ?`
+		reserve = `INSERT OR IGNORE INTO idempotency_keys(idempotency_key, request_hash, expires_at) VALUES(?, ?, ?)`
+		read    = `SELECT request_hash, status, header, body FROM idempotency_keys WHERE idempotency_key = ?`
+	)
+
+	now := time.Now()
+	if _, err := s.db.ExecContext(ctx, purge, dbTime(now)); err != nil {
+		return nil, err
+	}
+
+	res, err := s.db.ExecContext(ctx, reserve, key, requestHash, dbTime(now.Add(lease)))
+	if err != nil {
+		return nil, err
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	if rowsAffected == 1 {
+		return nil, nil
+	}
+
+	var (
+		storedHash string
+		header     string
+		resp       model.IdempotentResponse
+	)
+	if err := s.db.QueryRowContext(ctx, read, key).Scan(&storedHash, &resp.Status, &header, &resp.Body); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			// 期限切れで削除された直後。予約し直してもらう
+			return nil, &model.ErrConflict{Message: "idempotency key is being processed"}
+		}
+		return nil, err
+	}
+	if storedHash != requestHash {
+		return nil, &model.ErrIdempotencyKeyReused{}
+	}
+	// status が 0 の間は最初のリクエストがまだ処理中
+	if resp.Status == 0 {
+		return nil, &model.ErrConflict{Message: "idempotency key is being processed"}
+	}
+	if err := json.Unmarshal([]byte(header), &resp.Header); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// SaveIdempotentResponse stores resp as the response of the request reserved with key until ttl elapses.
+func (s *IdempotencyService) SaveIdempotentResponse(ctx context.Context, key string, resp *model.IdempotentResponse, ttl time.Duration) error {
+	const (
+		update = `UPDATE idempotency_keys SET status = ?, header = ?, body = ?, expires_at = ? WHERE idempotency_key = ?`
+	)
+
+	header, err := json.Marshal(resp.Header)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(ctx, update, resp.Status, string(header), resp.Body, dbTime(time.Now().Add(ttl)), key)
+	return err
+}
+
+// ReleaseIdempotencyKey removes the reservation of key so that the request can be retried.
+func (s *IdempotencyService) ReleaseIdempotencyKey(ctx context.Context, key string) error {
+	const (
+		release = `DELETE FROM idempotency_keys WHERE idempotency_key = ? AND status = 0`
+	)
+
+	_, err := s.db.ExecContext(ctx, release, key)
+	return err
+}
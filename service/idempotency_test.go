@@ -0,0 +1,52 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/TechBowl-japan/go-stations/db"
+	"github.com/TechBowl-japan/go-stations/model"
+	"github.com/TechBowl-japan/go-stations/service"
+)
+
+func TestReserveIdempotencyKey(t *testing.T) {
+	t.Parallel()
+
+	todoDB, err := db.NewDB(filepath.Join(t.TempDir(), "todo.db"))
+	if err != nil {
+		t.Fatalf("failed to create db: %v", err)
+	}
+	t.Cleanup(func() { todoDB.Close() })
+
+	ctx := context.Background()
+	svc := service.NewIdempotencyService(todoDB)
+
+	if resp, err := svc.ReserveIdempotencyKey(ctx, "key", "hash", time.Hour); err != nil || resp != nil {
+		t.Fatalf("failed to reserve key, resp = %+v, err = %v", resp, err)
+	}
+	// 処理中の間は再試行できない
+	var errConflict *model.ErrConflict
+	if _, err := svc.ReserveIdempotencyKey(ctx, "key", "hash", time.Hour); !errors.As(err, &errConflict) {
+		t.Errorf("unexpected error for key in progress, got = %v", err)
+	}
+
+	// 期限の切れた予約は、処理中のまま残っていても予約し直せる
+	if _, err := svc.ReserveIdempotencyKey(ctx, "expired", "hash", -time.Second); err != nil {
+		t.Fatalf("failed to reserve key: %v", err)
+	}
+	if resp, err := svc.ReserveIdempotencyKey(ctx, "expired", "hash", time.Hour); err != nil || resp != nil {
+		t.Errorf("failed to reserve expired key, resp = %+v, err = %v", resp, err)
+	}
+
+	// 保存したレスポンスは予約の期限ではなく ttl の間残る
+	if err := svc.SaveIdempotentResponse(ctx, "key", &model.IdempotentResponse{Status: 201, Body: []byte("created")}, time.Hour); err != nil {
+		t.Fatalf("failed to save response: %v", err)
+	}
+	resp, err := svc.ReserveIdempotencyKey(ctx, "key", "hash", -time.Second)
+	if err != nil || resp == nil || resp.Status != 201 || string(resp.Body) != "created" {
+		t.Errorf("unexpected stored response, got = %+v, err = %v", resp, err)
+	}
+}
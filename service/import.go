@@ -0,0 +1,120 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/TechBowl-japan/go-stations/model"
+)
+
+// errImportRolledBack rolls back the transaction of an import that is a dry run or has a failed item.
+var errImportRolledBack = errors.New("service: import rolled back")
+
+// ImportTODO creates TODOs from items in a single transaction on DB and returns their results in the same order.
+// Every item is tried in its own savepoint, so that the errors of all failed items are reported;
+// the TODOs are committed only when no item fails and dryRun is false.
+// The errors of the items are set to the results; only the errors of the transaction itself are returned.
+func (s *TODOService) ImportTODO(ctx context.Context, items []*model.ImportTODOItem, dryRun bool) ([]*model.ImportResult, error) {
+	results := make([]*model.ImportResult, len(items))
+	err := s.withTx(ctx, func(tx *sql.Tx) error {
+		failed := false
+		for i, item := range items {
+			if _, err := tx.ExecContext(ctx, `SAVEPOINT import_item`); err != nil {
+				return err
+			}
+
+			result := &model.ImportResult{Line: item.Line, Status: model.ImportValid}
+			results[i] = result
+			todo, err := importTODO(ctx, tx, item)
+			if err != nil {
+				result.Status, result.Err = model.ImportFailed, err
+				failed = true
+				// 失敗した行の変更だけを取り消し、残りの行の検証を続ける
+				if _, err := tx.ExecContext(ctx, `ROLLBACK TO import_item`); err != nil {
+					return err
+				}
+			} else {
+				result.TODO = todo
+			}
+
+			if _, err := tx.ExecContext(ctx, `RELEASE import_item`); err != nil {
+				return err
+			}
+		}
+		if failed || dryRun {
+			return errImportRolledBack
+		}
+		return nil
+	})
+	if errors.Is(err, errImportRolledBack) {
+		return results, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, result := range results {
+		result.Status = model.ImportCreated
+	}
+	return results, nil
+}
+
+// importTODO creates the TODO of item in tx, completed when item is done, and records its creation.
+func importTODO(ctx context.Context, tx *sql.Tx, item *model.ImportTODOItem) (*model.Todo, error) {
+	const (
+		complete = `UPDATE todos SET done = 1, completed_at = COALESCE(?, CURRENT_TIMESTAMP) WHERE id = ?`
+	)
+
+	attrs := item.TODOAttributes
+	if item.Project != "" {
+		projectID, err := projectIDByName(ctx, tx, item.Project)
+		if err != nil {
+			return nil, err
+		}
+		attrs.ProjectID = &projectID
+	}
+
+	id, err := insertTODO(ctx, tx, item.Subject, item.Description, &attrs)
+	if err != nil {
+		return nil, err
+	}
+	// 完了済みの TODO は繰り返しの次の TODO を作らずに完了にする
+	if item.Done {
+		if _, err := tx.ExecContext(ctx, complete, nullableTime(item.CompletedAt), id); err != nil {
+			return nil, err
+		}
+	}
+
+	todo, err := readTODOByID(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := recordTODOEvent(ctx, tx, model.TODOActionCreate, id, nil, todo); err != nil {
+		return nil, err
+	}
+	return todo, nil
+}
+
+// projectIDByName returns the id of the oldest project named name, creating the project in tx when there is none.
+func projectIDByName(ctx context.Context, tx *sql.Tx, name string) (int64, error) {
+	const (
+		read   = `SELECT id FROM projects WHERE name = ? ORDER BY id ASC LIMIT 1`
+		insert = `INSERT INTO projects(name) VALUES(?)`
+	)
+
+	var id int64
+	err := tx.QueryRowContext(ctx, read, name).Scan(&id)
+	if err == nil {
+		return id, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return 0, err
+	}
+
+	res, err := tx.ExecContext(ctx, insert, name)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
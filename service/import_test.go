@@ -0,0 +1,137 @@
+package service_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/TechBowl-japan/go-stations/db"
+	"github.com/TechBowl-japan/go-stations/model"
+	"github.com/TechBowl-japan/go-stations/service"
+)
+
+func TestImportTODO(t *testing.T) {
+	t.Parallel()
+
+	missingParent := int64(100)
+	cases := map[string]struct {
+		dryRun       bool
+		parentID     *int64
+		wantStatuses []model.ImportResultStatus
+		wantTODOs    int
+		wantProjects int
+	}{
+		"Committed": {
+			wantStatuses: []model.ImportResultStatus{model.ImportCreated, model.ImportCreated},
+			wantTODOs:    2,
+			wantProjects: 2,
+		},
+		"Dry run": {
+			dryRun:       true,
+			wantStatuses: []model.ImportResultStatus{model.ImportValid, model.ImportValid},
+			wantProjects: 1,
+		},
+		"Failed item": {
+			parentID:     &missingParent,
+			wantStatuses: []model.ImportResultStatus{model.ImportValid, model.ImportFailed},
+			wantProjects: 1,
+		},
+	}
+
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			todoDB, err := db.NewDB(filepath.Join(t.TempDir(), "todo.db"))
+			if err != nil {
+				t.Fatalf("failed to create db: %v", err)
+			}
+			t.Cleanup(func() { todoDB.Close() })
+
+			ctx := context.Background()
+			project, err := service.NewProjectService(todoDB).CreateProject(ctx, "home", "")
+			if err != nil {
+				t.Fatalf("failed to create project: %v", err)
+			}
+			svc := service.NewTODOService(todoDB)
+			items := []*model.ImportTODOItem{
+				{CreateTODORequest: model.CreateTODORequest{Subject: "existing project"}, Project: "home", Line: 1},
+				{CreateTODORequest: model.CreateTODORequest{Subject: "new project", TODOAttributes: model.TODOAttributes{ParentID: c.parentID}}, Project: "work", Done: true, Line: 2},
+			}
+
+			results, err := svc.ImportTODO(ctx, items, c.dryRun)
+			if err != nil {
+				t.Fatalf("failed to import todos: %v", err)
+			}
+			for i, result := range results {
+				if result.Status != c.wantStatuses[i] || result.Line != items[i].Line {
+					t.Errorf("unexpected result %d, got = %+v", i, result)
+				}
+			}
+			if todo := results[0].TODO; todo == nil || *todo.ProjectID != project.ID {
+				t.Errorf("unexpected todo, got = %+v", todo)
+			}
+			if todo := results[1].TODO; c.parentID == nil && (todo == nil || !todo.Done || *todo.ProjectID == project.ID) {
+				t.Errorf("unexpected todo, got = %+v", todo)
+			}
+
+			todos, err := svc.ReadTODO(ctx, 0, 10)
+			if err != nil {
+				t.Fatalf("failed to read todos: %v", err)
+			}
+			if len(todos) != c.wantTODOs {
+				t.Errorf("unexpected number of todos, got = %d, want = %d", len(todos), c.wantTODOs)
+			}
+			// コミットされなかった場合は作成したプロジェクトも取り消される
+			projects, err := service.NewProjectService(todoDB).ReadProject(ctx, 0, 10)
+			if err != nil {
+				t.Fatalf("failed to read projects: %v", err)
+			}
+			if len(projects) != c.wantProjects {
+				t.Errorf("unexpected number of projects, got = %d, want = %d", len(projects), c.wantProjects)
+			}
+		})
+	}
+}
+
+func TestImportTODOCompletedAt(t *testing.T) {
+	t.Parallel()
+
+	todoDB, err := db.NewDB(filepath.Join(t.TempDir(), "todo.db"))
+	if err != nil {
+		t.Fatalf("failed to create db: %v", err)
+	}
+	t.Cleanup(func() { todoDB.Close() })
+
+	completedAt := time.Date(2024, 1, 2, 12, 4, 5, 0, time.FixedZone("JST", 9*60*60))
+	items := []*model.ImportTODOItem{
+		{CreateTODORequest: model.CreateTODORequest{Subject: "given"}, Done: true, CompletedAt: &completedAt, Line: 1},
+		{CreateTODORequest: model.CreateTODORequest{Subject: "now"}, Done: true, Line: 2},
+	}
+	if _, err := service.NewTODOService(todoDB).ImportTODO(context.Background(), items, false); err != nil {
+		t.Fatalf("failed to import todos: %v", err)
+	}
+
+	// 指定された完了日時も、指定がなく SQLite が書き込む完了日時も同じ形式で保存される
+	rows, err := todoDB.Query(`SELECT +completed_at FROM todos ORDER BY id`)
+	if err != nil {
+		t.Fatalf("failed to read todos: %v", err)
+	}
+	defer rows.Close()
+	var got []string
+	for rows.Next() {
+		var value string
+		if err := rows.Scan(&value); err != nil {
+			t.Fatalf("failed to scan completed_at: %v", err)
+		}
+		got = append(got, value)
+	}
+	if len(got) != 2 || got[0] != "2024-01-02 03:04:05" {
+		t.Fatalf("unexpected completed_at, got = %q", got)
+	}
+	if _, err := time.Parse("2006-01-02 15:04:05", got[1]); err != nil {
+		t.Errorf("unexpected format of completed_at, got = %q", got[1])
+	}
+}
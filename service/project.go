@@ -0,0 +1,206 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/TechBowl-japan/go-stations/model"
+)
+
+// A ProjectService implements CRUD of project entities.
+type ProjectService struct {
+	db     *sql.DB
+	events *eventBroker
+}
+
+// NewProjectService returns new ProjectService.
+func NewProjectService(db *sql.DB) *ProjectService {
+	return &ProjectService{
+		db:     db,
+		events: brokerFor(db),
+	}
+}
+
+const (
+	selectProjectByIDQuery = `SELECT id, name, description, created_at, updated_at FROM projects WHERE id = ?`
+)
+
+func scanProject(row rowScanner) (*model.Project, error) {
+	var project model.Project
+	if err := row.Scan(&project.ID, &project.Name, &project.Description, &project.CreatedAt, &project.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &project, nil
+}
+
+// readProjectByID reads a project, returning *model.ErrNotFound when it does not exist.
+func readProjectByID(ctx context.Context, q queryer, id int64) (*model.Project, error) {
+	project, err := scanProject(q.QueryRowContext(ctx, selectProjectByIDQuery, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, &model.ErrNotFound{Resource: "project"}
+		}
+		return nil, err
+	}
+	return project, nil
+}
+
+// checkProjectExists returns *model.ErrValidation when a TODO refers to a project that does not exist.
+func checkProjectExists(ctx context.Context, q queryer, id *int64) error {
+	if id == nil {
+		return nil
+	}
+	var exists bool
+	if err := q.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM projects WHERE id = ?)`, *id).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return &model.ErrValidation{Field: "project_id", Message: "project does not exist"}
+	}
+	return nil
+}
+
+// CreateProject creates a project on DB.
+func (s *ProjectService) CreateProject(ctx context.Context, name, description string) (*model.Project, error) {
+	const insert = `INSERT INTO projects(name, description) VALUES(?, ?)`
+
+	res, err := s.db.ExecContext(ctx, insert, name, description)
+	if err != nil {
+		return nil, err
+	}
+
+	lastID, err := res.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+
+	return readProjectByID(ctx, s.db, lastID)
+}
+
+// ReadProject reads projects on DB.
+func (s *ProjectService) ReadProject(ctx context.Context, prevID, size int64) ([]*model.Project, error) {
+	const read = `SELECT id, name, description, created_at, updated_at FROM projects WHERE id > ? ORDER BY id ASC LIMIT ?`
+
+	if size == 0 {
+		size = 5
+	}
+
+	rows, err := s.db.QueryContext(ctx, read, prevID, size)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	projects := make([]*model.Project, 0)
+	for rows.Next() {
+		project, err := scanProject(rows)
+		if err != nil {
+			return nil, err
+		}
+		projects = append(projects, project)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return projects, nil
+}
+
+// ReadProjectByID reads a project on DB.
+func (s *ProjectService) ReadProjectByID(ctx context.Context, id int64) (*model.Project, error) {
+	return readProjectByID(ctx, s.db, id)
+}
+
+// UpdateProject updates a project on DB.
+func (s *ProjectService) UpdateProject(ctx context.Context, id int64, name, description string) (*model.Project, error) {
+	const update = `UPDATE projects SET name = ?, description = ? WHERE id = ?`
+
+	res, err := s.db.ExecContext(ctx, update, name, description, id)
+	if err != nil {
+		return nil, err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+
+	if rowsAffected == 0 {
+		return nil, &model.ErrNotFound{Resource: "project"}
+	}
+
+	return readProjectByID(ctx, s.db, id)
+}
+
+// DeleteProject deletes a project on DB.
+// policy decides what happens to the TODOs in the project; see model.ProjectDeletePolicy.
+// TODOs in the trash never block the deletion and are detached from the project.
+func (s *ProjectService) DeleteProject(ctx context.Context, id int64, policy model.ProjectDeletePolicy) error {
+	const (
+		countTODOs     = `SELECT COUNT(*) FROM todos WHERE project_id = ? AND deleted_at IS NULL`
+		selectTODOs    = `SELECT id FROM todos WHERE project_id = ? AND deleted_at IS NULL`
+		selectAllTODOs = `SELECT id FROM todos WHERE project_id = ?`
+		detachTODOs    = `UPDATE todos SET project_id = NULL WHERE project_id = ?`
+		deleteQuery    = `DELETE FROM projects WHERE id = ?`
+	)
+
+	err := withTx(ctx, s.db, func(tx *sql.Tx) error {
+		if _, err := readProjectByID(ctx, tx, id); err != nil {
+			return err
+		}
+
+		switch policy {
+		case model.ProjectDeleteCascade:
+			ids, err := queryIDs(ctx, tx, selectTODOs, id)
+			if err != nil {
+				return err
+			}
+			// サブタスクが別のプロジェクトにある場合もあるため、子は親に付け替えて残す
+			if _, err := trashTODOs(ctx, tx, ids, model.ChildDeleteReparent, time.Now()); err != nil {
+				return err
+			}
+		case model.ProjectDeleteDetach:
+		default:
+			var count int64
+			if err := tx.QueryRowContext(ctx, countTODOs, id).Scan(&count); err != nil {
+				return err
+			}
+			if count > 0 {
+				return &model.ErrConflict{Message: "project still has todos"}
+			}
+		}
+
+		// ゴミ箱の TODO も含めてプロジェクトから外す
+		detached, err := queryIDs(ctx, tx, selectAllTODOs, id)
+		if err != nil {
+			return err
+		}
+		before, err := snapshotTODOs(ctx, tx, detached)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, detachTODOs, id); err != nil {
+			return err
+		}
+		after, err := snapshotTODOs(ctx, tx, detached)
+		if err != nil {
+			return err
+		}
+		if err := recordTODOEvents(ctx, tx, model.TODOActionUpdate, detached, before, after); err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, deleteQuery, id)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	// 所属していた TODO の変更を通知する
+	s.events.notify()
+	return nil
+}
@@ -0,0 +1,156 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/TechBowl-japan/go-stations/db"
+	"github.com/TechBowl-japan/go-stations/model"
+	"github.com/TechBowl-japan/go-stations/service"
+)
+
+func TestDeleteProject(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		policy model.ProjectDeletePolicy
+		// trashOpen は削除の前に open もゴミ箱に移すかどうか
+		trashOpen   bool
+		wantErr     bool
+		wantTrashed []string
+	}{
+		"Restrict with open todos": {
+			policy:  model.ProjectDeleteRestrict,
+			wantErr: true,
+		},
+		"Restrict ignores trashed todos": {
+			policy:      model.ProjectDeleteRestrict,
+			trashOpen:   true,
+			wantTrashed: []string{"open", "trashed"},
+		},
+		"Cascade": {
+			policy:      model.ProjectDeleteCascade,
+			wantTrashed: []string{"open", "trashed"},
+		},
+		"Detach": {
+			policy:      model.ProjectDeleteDetach,
+			wantTrashed: []string{"trashed"},
+		},
+	}
+
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			todoDB, err := db.NewDB(filepath.Join(t.TempDir(), "todo.db"))
+			if err != nil {
+				t.Fatalf("failed to create db: %v", err)
+			}
+			t.Cleanup(func() { todoDB.Close() })
+
+			ctx := context.Background()
+			svc := service.NewProjectService(todoDB)
+			todoSvc := service.NewTODOService(todoDB)
+
+			project, err := svc.CreateProject(ctx, "release", "")
+			if err != nil {
+				t.Fatalf("failed to create project: %v", err)
+			}
+			for _, subject := range []string{"open", "trashed"} {
+				if _, err := todoSvc.CreateTODOWithAttributes(ctx, subject, "", &model.TODOAttributes{ProjectID: &project.ID}); err != nil {
+					t.Fatalf("failed to create todo: %v", err)
+				}
+			}
+			if _, err := todoSvc.CreateTODO(ctx, "other", ""); err != nil {
+				t.Fatalf("failed to create todo: %v", err)
+			}
+			trash := []int64{2}
+			if c.trashOpen {
+				trash = append(trash, 1)
+			}
+			if err := todoSvc.DeleteTODO(ctx, trash); err != nil {
+				t.Fatalf("failed to delete todo: %v", err)
+			}
+
+			todos, err := todoSvc.ListTODO(ctx, &model.TODOQuery{Size: 10, ProjectID: &project.ID})
+			if err != nil {
+				t.Fatalf("failed to list todos: %v", err)
+			}
+			want := []string{"open"}
+			if c.trashOpen {
+				want = []string{}
+			}
+			if got := subjects(todos); !equalStrings(got, want) {
+				t.Fatalf("unexpected project todos, got = %v, want = %v", got, want)
+			}
+
+			err = svc.DeleteProject(ctx, project.ID, c.policy)
+			if c.wantErr {
+				var errConflict *model.ErrConflict
+				if !errors.As(err, &errConflict) {
+					t.Fatalf("unexpected error, got = %v", err)
+				}
+				if _, err := svc.ReadProjectByID(ctx, project.ID); err != nil {
+					t.Errorf("project is deleted: %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to delete project: %v", err)
+			}
+			var errNotFound *model.ErrNotFound
+			if _, err := svc.ReadProjectByID(ctx, project.ID); !errors.As(err, &errNotFound) {
+				t.Errorf("project is not deleted, err = %v", err)
+			}
+
+			trashed, err := todoSvc.ReadTrash(ctx, 0, 10)
+			if err != nil {
+				t.Fatalf("failed to read trash: %v", err)
+			}
+			if got := subjects(trashed); !equalStrings(got, c.wantTrashed) {
+				t.Errorf("unexpected trash, got = %v, want = %v", got, c.wantTrashed)
+			}
+
+			// ゴミ箱から戻した TODO も含め、削除したプロジェクトに所属する TODO は残らない
+			ids := make([]int64, 0, len(trashed))
+			for _, todo := range trashed {
+				ids = append(ids, todo.ID)
+			}
+			if _, err := todoSvc.RestoreTODO(ctx, ids); err != nil {
+				t.Fatalf("failed to restore todos: %v", err)
+			}
+			todos, err = todoSvc.ListTODO(ctx, &model.TODOQuery{Size: 10})
+			if err != nil {
+				t.Fatalf("failed to list todos: %v", err)
+			}
+			if len(todos) != 3 {
+				t.Fatalf("unexpected todos, got = %v", subjects(todos))
+			}
+			for _, todo := range todos {
+				if todo.ProjectID != nil {
+					t.Errorf("todo %s is still in project %d", todo.Subject, *todo.ProjectID)
+				}
+			}
+		})
+	}
+}
+
+func TestCreateTODOUnknownProject(t *testing.T) {
+	t.Parallel()
+
+	todoDB, err := db.NewDB(filepath.Join(t.TempDir(), "todo.db"))
+	if err != nil {
+		t.Fatalf("failed to create db: %v", err)
+	}
+	t.Cleanup(func() { todoDB.Close() })
+
+	projectID := int64(1)
+	_, err = service.NewTODOService(todoDB).CreateTODOWithAttributes(context.Background(), "orphan", "", &model.TODOAttributes{ProjectID: &projectID})
+	var errValidation *model.ErrValidation
+	if !errors.As(err, &errValidation) || errValidation.Field != "project_id" {
+		t.Errorf("unexpected error, got = %v", err)
+	}
+}
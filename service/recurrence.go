@@ -0,0 +1,49 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/TechBowl-japan/go-stations/model"
+)
+
+// normalizeRecurrence validates a recurrence rule and returns it in its canonical form.
+func normalizeRecurrence(recurrence string) (string, error) {
+	if recurrence == "" {
+		return "", nil
+	}
+	rule, err := model.ParseRecurrenceRule(recurrence)
+	if err != nil {
+		return "", &model.ErrValidation{Field: "recurrence", Message: err.Error()}
+	}
+	return rule.String(), nil
+}
+
+// insertNextOccurrence creates the next occurrence of the recurring TODO todo and returns its id.
+// The due date is shifted in the configured time zone (time.Local); a TODO without due date
+// is shifted from now. It returns 0 when the rule has no more occurrences.
+func insertNextOccurrence(ctx context.Context, tx *sql.Tx, todo *model.Todo) (int64, error) {
+	rule, err := model.ParseRecurrenceRule(todo.Recurrence)
+	if err != nil {
+		return 0, err
+	}
+
+	base := time.Now()
+	if todo.DueAt != nil {
+		base = *todo.DueAt
+	}
+	due, rest, ok := rule.Next(base, time.Local)
+	if !ok {
+		return 0, nil
+	}
+
+	return insertTODO(ctx, tx, todo.Subject, todo.Description, &model.TODOAttributes{
+		DueAt:      &due,
+		Priority:   todo.Priority,
+		Tags:       todo.Tags,
+		ProjectID:  todo.ProjectID,
+		ParentID:   todo.ParentID,
+		Recurrence: rest.String(),
+	})
+}
@@ -0,0 +1,94 @@
+package service_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/TechBowl-japan/go-stations/db"
+	"github.com/TechBowl-japan/go-stations/model"
+	"github.com/TechBowl-japan/go-stations/service"
+)
+
+// TestCompleteTODORecurrence changes time.Local, so it does not run in parallel with the other tests.
+func TestCompleteTODORecurrence(t *testing.T) {
+	cases := map[string]struct {
+		timeZone   string
+		due        string
+		recurrence string
+		wantDue    string
+	}{
+		"Weekday in Asia/Tokyo": {
+			// UTC では日曜日になる月曜日の朝
+			timeZone:   "Asia/Tokyo",
+			due:        "2024-03-11T08:00:00+09:00",
+			recurrence: "FREQ=WEEKLY;BYDAY=MO,WE",
+			wantDue:    "2024-03-13T08:00:00+09:00",
+		},
+		"Across DST in America/New_York": {
+			// 夏時間が始まる日も同じ時刻になる
+			timeZone:   "America/New_York",
+			due:        "2024-03-09T09:00:00-05:00",
+			recurrence: "FREQ=DAILY",
+			wantDue:    "2024-03-10T09:00:00-04:00",
+		},
+	}
+
+	local := time.Local
+	t.Cleanup(func() { time.Local = local })
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			loc, err := time.LoadLocation(c.timeZone)
+			if err != nil {
+				t.Skipf("time zone %s is not available: %v", c.timeZone, err)
+			}
+			time.Local = loc
+
+			todoDB, err := db.NewDB(filepath.Join(t.TempDir(), "todo.db"))
+			if err != nil {
+				t.Fatalf("failed to create db: %v", err)
+			}
+			t.Cleanup(func() { todoDB.Close() })
+
+			ctx := context.Background()
+			svc := service.NewTODOService(todoDB)
+			due, _ := time.Parse(time.RFC3339, c.due)
+			todo, err := svc.CreateTODOWithAttributes(ctx, "recurring", "", &model.TODOAttributes{DueAt: &due, Recurrence: c.recurrence})
+			if err != nil {
+				t.Fatalf("failed to create todo: %v", err)
+			}
+
+			_, next, err := svc.CompleteTODO(ctx, todo.ID)
+			if err != nil {
+				t.Fatalf("failed to complete todo: %v", err)
+			}
+			wantDue, _ := time.Parse(time.RFC3339, c.wantDue)
+			if next == nil || next.DueAt == nil || !next.DueAt.Equal(wantDue) {
+				t.Fatalf("unexpected next occurrence, got = %+v, want due at %s", next, wantDue)
+			}
+
+			// 再開して完了し直しても次の TODO は増えない
+			for i := 0; i < 2; i++ {
+				if _, err := svc.ReopenTODO(ctx, todo.ID); err != nil {
+					t.Fatalf("failed to reopen todo: %v", err)
+				}
+				_, again, err := svc.CompleteTODO(ctx, todo.ID)
+				if err != nil {
+					t.Fatalf("failed to complete todo: %v", err)
+				}
+				if again != nil {
+					t.Errorf("unexpected next occurrence after reopening, got = %+v", again)
+				}
+			}
+			todos, err := svc.ReadTODO(ctx, 0, 10)
+			if err != nil {
+				t.Fatalf("failed to read todos: %v", err)
+			}
+			if len(todos) != 2 {
+				t.Errorf("unexpected number of todos, got = %d, want = 2", len(todos))
+			}
+		})
+	}
+}
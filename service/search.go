@@ -0,0 +1,111 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"html"
+	"strings"
+
+	"github.com/TechBowl-japan/go-stations/model"
+	"github.com/mattn/go-sqlite3"
+)
+
+// SearchTODO searches TODOs by subject and description with SQLite FTS5 on DB.
+// query is an FTS5 query, which supports "phrases", prefix* queries and AND / OR / NOT.
+// Results are ordered by relevance; matches in subject weigh more than in description.
+func (s *TODOService) SearchTODO(ctx context.Context, query string, size, offset int64) ([]*model.TODOSearchResult, error) {
+	search := `SELECT ` + qualifyColumns("t", todoColumns) + `,
+  bm25(todos_fts, 10.0, 1.0) AS rank,
+  highlight(todos_fts, 0, char(2), char(3)),
+  snippet(todos_fts, 1, char(2), char(3), '…', 16)
+FROM todos_fts JOIN todos t ON t.id = todos_fts.rowid
+WHERE todos_fts MATCH ? AND t.deleted_at IS NULL
+ORDER BY rank ASC, t.id ASC
+LIMIT ? OFFSET ?`
+
+	available, err := s.hasFTS(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !available {
+		return nil, &model.ErrUnavailable{Message: "full-text search is not available; build with -tags sqlite_fts5"}
+	}
+
+	if size == 0 {
+		size = 5
+	}
+
+	rows, err := s.db.QueryContext(ctx, search, query, size, offset)
+	if err != nil {
+		return nil, searchError(err)
+	}
+	defer rows.Close()
+
+	results := make([]*model.TODOSearchResult, 0)
+	todos := make([]*model.Todo, 0)
+	for rows.Next() {
+		var (
+			result model.TODOSearchResult
+			extra  = []interface{}{&result.Rank, &result.SubjectHighlight, &result.DescriptionSnippet}
+		)
+		todo, err := scanTODO(&extendedScanner{row: rows, extra: extra})
+		if err != nil {
+			return nil, searchError(err)
+		}
+		result.TODO = *todo
+		result.SubjectHighlight = markMatches(result.SubjectHighlight)
+		result.DescriptionSnippet = markMatches(result.DescriptionSnippet)
+		results = append(results, &result)
+		todos = append(todos, &results[len(results)-1].TODO)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, searchError(err)
+	}
+
+	if err := loadTODOTags(ctx, s.db, todos); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// Delimiters of the matches in the output of highlight and snippet.
+// They are control characters that plain text hardly contains, so that the text can be escaped before adding <mark>.
+const (
+	matchStart = "\x02"
+	matchEnd   = "\x03"
+)
+
+// markMatches HTML-escapes text from highlight or snippet and wraps its matches with <mark> and </mark>.
+func markMatches(text string) string {
+	// 区切り文字が元の文字列に含まれていても、タグ以外の HTML にはならない
+	return strings.NewReplacer(matchStart, "<mark>", matchEnd, "</mark>").Replace(html.EscapeString(text))
+}
+
+// searchError converts an error caused by a malformed FTS5 query into *model.ErrValidation.
+// The search SQL itself is fixed, so a generic SQLITE_ERROR can only come from the query,
+// e.g. "fts5: syntax error near ...", "unterminated string" or "no such column: ...".
+func searchError(err error) error {
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) && sqliteErr.Code == sqlite3.ErrError {
+		return &model.ErrValidation{Field: "q", Message: err.Error()}
+	}
+	return err
+}
+
+// hasFTS reports whether the full-text search index exists, which depends on the SQLite build.
+func (s *TODOService) hasFTS(ctx context.Context) (bool, error) {
+	var exists bool
+	err := s.db.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM sqlite_master WHERE name = 'todos_fts')`).Scan(&exists)
+	return exists, err
+}
+
+// extendedScanner scans the todoColumns of a row followed by extra columns.
+type extendedScanner struct {
+	row   rowScanner
+	extra []interface{}
+}
+
+func (s *extendedScanner) Scan(dest ...interface{}) error {
+	return s.row.Scan(append(dest, s.extra...)...)
+}
@@ -0,0 +1,75 @@
+//go:build sqlite_fts5
+
+package service_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/TechBowl-japan/go-stations/db"
+	"github.com/TechBowl-japan/go-stations/model"
+	"github.com/TechBowl-japan/go-stations/service"
+)
+
+func TestSearchTODO(t *testing.T) {
+	t.Parallel()
+
+	todoDB, err := db.NewDB(filepath.Join(t.TempDir(), "todo.db"))
+	if err != nil {
+		t.Fatalf("failed to create db: %v", err)
+	}
+	t.Cleanup(func() { todoDB.Close() })
+
+	ctx := context.Background()
+	svc := service.NewTODOService(todoDB)
+	todos := []struct {
+		subject, description string
+	}{
+		{"Call the bank", "ask about the milk money"},
+		{"Buy <b>milk</b> & eggs", ""},
+		{"Walk the dog", "no match here"},
+	}
+	for _, todo := range todos {
+		if _, err := svc.CreateTODO(ctx, todo.subject, todo.description); err != nil {
+			t.Fatalf("failed to create todo: %v", err)
+		}
+	}
+	// ゴミ箱の TODO は検索されない
+	trashed, err := svc.CreateTODO(ctx, "milk in the trash", "")
+	if err != nil {
+		t.Fatalf("failed to create todo: %v", err)
+	}
+	if err := svc.DeleteTODO(ctx, []int64{trashed.ID}); err != nil {
+		t.Fatalf("failed to delete todo: %v", err)
+	}
+
+	results, err := svc.SearchTODO(ctx, "milk", 0, 0)
+	if err != nil {
+		t.Fatalf("failed to search: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("unexpected number of results, got = %d", len(results))
+	}
+
+	// 件名の一致は説明の一致より関連度が高い
+	if results[0].TODO.ID != 2 || results[1].TODO.ID != 1 || results[0].Rank >= results[1].Rank {
+		t.Errorf("unexpected ranking, got = %+v, %+v", results[0], results[1])
+	}
+	// 件名はエスケープしてから検索語を <mark> で囲む
+	if got, want := results[0].SubjectHighlight, "Buy &lt;b&gt;<mark>milk</mark>&lt;/b&gt; &amp; eggs"; got != want {
+		t.Errorf("unexpected highlight, got = %q, want = %q", got, want)
+	}
+	if got, want := results[1].DescriptionSnippet, "ask about the <mark>milk</mark> money"; got != want {
+		t.Errorf("unexpected snippet, got = %q, want = %q", got, want)
+	}
+	if got, want := results[1].SubjectHighlight, "Call the bank"; got != want {
+		t.Errorf("unexpected highlight, got = %q, want = %q", got, want)
+	}
+
+	var errValidation *model.ErrValidation
+	if _, err := svc.SearchTODO(ctx, `"unterminated`, 0, 0); !errors.As(err, &errValidation) {
+		t.Errorf("unexpected error for malformed query, got = %v", err)
+	}
+}
@@ -0,0 +1,163 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/TechBowl-japan/go-stations/model"
+)
+
+const (
+	// 指定した TODO (? の位置に id を列挙する) の子孫の id を返す再帰クエリ
+	descendantIDsQuery = `WITH RECURSIVE descendants(id) AS (
+  SELECT id FROM todos WHERE parent_id IN (%s)
+  UNION
+  SELECT t.id FROM todos t JOIN descendants d ON t.parent_id = d.id
+) SELECT id FROM descendants`
+
+	// 1 番目の ? の TODO から親をたどり、2 番目の ? の TODO が祖先 (自身を含む) にあるかを返すクエリ
+	isAncestorQuery = `WITH RECURSIVE ancestors(id) AS (
+  SELECT ?
+  UNION
+  SELECT t.parent_id FROM todos t JOIN ancestors a ON t.id = a.id WHERE t.parent_id IS NOT NULL
+) SELECT EXISTS(SELECT 1 FROM ancestors WHERE id = ?)`
+)
+
+// queryIDs runs query and collects the int64 values of its single column.
+func queryIDs(ctx context.Context, q queryer, query string, args ...interface{}) ([]int64, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
+// checkParent returns *model.ErrValidation when parentID does not refer to an existing TODO outside the trash,
+// or when making it the parent of the TODO id would create a cycle.
+// id is 0 for a TODO that is not created yet.
+func checkParent(ctx context.Context, q queryer, id int64, parentID *int64) error {
+	if parentID == nil {
+		return nil
+	}
+
+	var exists bool
+	if err := q.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM todos WHERE id = ? AND deleted_at IS NULL)`, *parentID).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return &model.ErrValidation{Field: "parent_id", Message: "parent todo does not exist"}
+	}
+
+	if id == 0 {
+		return nil
+	}
+	var cycle bool
+	if err := q.QueryRowContext(ctx, isAncestorQuery, *parentID, id).Scan(&cycle); err != nil {
+		return err
+	}
+	if cycle {
+		return &model.ErrValidation{Field: "parent_id", Message: "parent would create a cycle"}
+	}
+
+	return nil
+}
+
+// reparentChildren moves the children of the TODOs ids to the parent of their parent.
+// When the new parent is also in ids, the move is repeated until no child refers to ids.
+func reparentChildren(ctx context.Context, tx *sql.Tx, ids []int64) error {
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+
+	query := fmt.Sprintf(`UPDATE todos SET parent_id = (SELECT p.parent_id FROM todos p WHERE p.id = todos.parent_id) WHERE parent_id IN (%s)`, placeholders(len(ids)))
+	for {
+		res, err := tx.ExecContext(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+		rowsAffected, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rowsAffected == 0 {
+			return nil
+		}
+	}
+}
+
+// ReadTODOChildren reads the direct children of the TODO id on DB.
+// q filters and paginates the children as in ListTODOPage.
+func (s *TODOService) ReadTODOChildren(ctx context.Context, id int64, q *model.TODOQuery) (*model.TODOPage, error) {
+	if _, err := readTODOByID(ctx, s.db, id); err != nil {
+		return nil, err
+	}
+
+	childQuery := *q
+	childQuery.ParentID = &id
+	return s.ListTODOPage(ctx, &childQuery)
+}
+
+// ReadTODOTree reads the TODO id and all of its descendants on DB.
+func (s *TODOService) ReadTODOTree(ctx context.Context, id int64) (*model.TODOTree, error) {
+	root, err := readTODOByID(ctx, s.db, id)
+	if err != nil {
+		return nil, err
+	}
+
+	query := fmt.Sprintf(`SELECT `+todoColumns+` FROM todos WHERE id IN (%s) AND deleted_at IS NULL ORDER BY id ASC`, fmt.Sprintf(descendantIDsQuery, "?"))
+	rows, err := s.db.QueryContext(ctx, query, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var descendants []*model.Todo
+	for rows.Next() {
+		todo, err := scanTODO(rows)
+		if err != nil {
+			return nil, err
+		}
+		descendants = append(descendants, todo)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if err := loadTODOTags(ctx, s.db, descendants); err != nil {
+		return nil, err
+	}
+
+	children := make(map[int64][]*model.Todo)
+	for _, todo := range descendants {
+		children[*todo.ParentID] = append(children[*todo.ParentID], todo)
+	}
+
+	return buildTODOTree(root, children), nil
+}
+
+// buildTODOTree builds the tree under todo and rolls up the completion progress of its descendants.
+func buildTODOTree(todo *model.Todo, children map[int64][]*model.Todo) *model.TODOTree {
+	tree := &model.TODOTree{Todo: *todo, Children: make([]*model.TODOTree, 0)}
+	for _, child := range children[todo.ID] {
+		subtree := buildTODOTree(child, children)
+		tree.Children = append(tree.Children, subtree)
+		tree.Progress.Total += subtree.Progress.Total + 1
+		tree.Progress.Done += subtree.Progress.Done
+		if child.Done {
+			tree.Progress.Done++
+		}
+	}
+	return tree
+}
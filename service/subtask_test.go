@@ -0,0 +1,180 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/TechBowl-japan/go-stations/db"
+	"github.com/TechBowl-japan/go-stations/model"
+	"github.com/TechBowl-japan/go-stations/service"
+)
+
+// createSubtasks creates the tree below and completes b and c.
+//
+//	root (1)
+//	├── a (2)
+//	│   └── c (4)
+//	└── b (3)
+func createSubtasks(t *testing.T, svc *service.TODOService) {
+	t.Helper()
+
+	ctx := context.Background()
+	for _, todo := range []struct {
+		subject  string
+		parentID int64
+	}{
+		{"root", 0},
+		{"a", 1},
+		{"b", 1},
+		{"c", 2},
+	} {
+		attrs := &model.TODOAttributes{}
+		if todo.parentID != 0 {
+			attrs.ParentID = &todo.parentID
+		}
+		if _, err := svc.CreateTODOWithAttributes(ctx, todo.subject, "", attrs); err != nil {
+			t.Fatalf("failed to create todo: %v", err)
+		}
+	}
+	for _, id := range []int64{3, 4} {
+		if _, _, err := svc.CompleteTODO(ctx, id); err != nil {
+			t.Fatalf("failed to complete todo: %v", err)
+		}
+	}
+}
+
+// parentPatch returns a patch that moves a TODO under parentID.
+func parentPatch(parentID *int64) *model.TODOPatch {
+	var patch model.TODOPatch
+	patch.ParentID = model.Optional[*int64]{Set: true, Value: parentID}
+	return &patch
+}
+
+func TestTODOSubtasks(t *testing.T) {
+	t.Parallel()
+
+	todoDB, err := db.NewDB(filepath.Join(t.TempDir(), "todo.db"))
+	if err != nil {
+		t.Fatalf("failed to create db: %v", err)
+	}
+	t.Cleanup(func() { todoDB.Close() })
+
+	ctx := context.Background()
+	svc := service.NewTODOService(todoDB)
+	createSubtasks(t, svc)
+
+	// 自身や子孫を親にすると循環するため拒否する
+	for name, parentID := range map[string]int64{"Self": 1, "Grandchild": 4, "Missing": 99} {
+		parentID := parentID
+		_, err := svc.PatchTODO(ctx, 1, parentPatch(&parentID), nil)
+		var errValidation *model.ErrValidation
+		if !errors.As(err, &errValidation) || errValidation.Field != "parent_id" {
+			t.Errorf("%s: unexpected error, got = %v", name, err)
+		}
+	}
+	// 兄弟の子にするのは循環しない
+	parentID := int64(3)
+	if _, err := svc.PatchTODO(ctx, 4, parentPatch(&parentID), nil); err != nil {
+		t.Fatalf("failed to move todo: %v", err)
+	}
+	parentID = 2
+	if _, err := svc.PatchTODO(ctx, 4, parentPatch(&parentID), nil); err != nil {
+		t.Fatalf("failed to move todo back: %v", err)
+	}
+
+	children, err := svc.ReadTODOChildren(ctx, 1, &model.TODOQuery{Size: 10})
+	if err != nil {
+		t.Fatalf("failed to read children: %v", err)
+	}
+	if got, want := subjects(children.Todos), []string{"a", "b"}; !equalStrings(got, want) {
+		t.Errorf("unexpected children, got = %v, want = %v", got, want)
+	}
+
+	tree, err := svc.ReadTODOTree(ctx, 1)
+	if err != nil {
+		t.Fatalf("failed to read tree: %v", err)
+	}
+	if tree.Progress != (model.TODOProgress{Done: 2, Total: 3}) || len(tree.Children) != 2 {
+		t.Fatalf("unexpected tree, progress = %+v, children = %d", tree.Progress, len(tree.Children))
+	}
+	a, b := tree.Children[0], tree.Children[1]
+	if a.Subject != "a" || a.Progress != (model.TODOProgress{Done: 1, Total: 1}) || len(a.Children) != 1 || a.Children[0].Subject != "c" {
+		t.Errorf("unexpected subtree a, got = %+v", a)
+	}
+	if b.Subject != "b" || b.Progress != (model.TODOProgress{}) || len(b.Children) != 0 {
+		t.Errorf("unexpected subtree b, got = %+v", b)
+	}
+
+	// ゴミ箱の子孫は進捗に含めない
+	if err := svc.DeleteTODO(ctx, []int64{3}); err != nil {
+		t.Fatalf("failed to delete todo: %v", err)
+	}
+	tree, err = svc.ReadTODOTree(ctx, 1)
+	if err != nil {
+		t.Fatalf("failed to read tree: %v", err)
+	}
+	if tree.Progress != (model.TODOProgress{Done: 1, Total: 2}) {
+		t.Errorf("unexpected progress, got = %+v", tree.Progress)
+	}
+}
+
+func TestDeleteTODOChildPolicy(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		policy      model.ChildDeletePolicy
+		wantTrashed []string
+		// wantParentC は c の親の id で、0 の場合は c もゴミ箱にある
+		wantParentC int64
+	}{
+		"Reparent is the default": {
+			wantTrashed: []string{"a"},
+			wantParentC: 1,
+		},
+		"Cascade": {
+			policy:      model.ChildDeleteCascade,
+			wantTrashed: []string{"a", "c"},
+		},
+	}
+
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			todoDB, err := db.NewDB(filepath.Join(t.TempDir(), "todo.db"))
+			if err != nil {
+				t.Fatalf("failed to create db: %v", err)
+			}
+			t.Cleanup(func() { todoDB.Close() })
+
+			ctx := context.Background()
+			svc := service.NewTODOService(todoDB)
+			createSubtasks(t, svc)
+
+			if err := svc.DeleteTODOWithPolicy(ctx, []int64{2}, c.policy, nil); err != nil {
+				t.Fatalf("failed to delete todo: %v", err)
+			}
+			trashed, err := svc.ReadTrash(ctx, 0, 10)
+			if err != nil {
+				t.Fatalf("failed to read trash: %v", err)
+			}
+			if got := subjects(trashed); !equalStrings(got, c.wantTrashed) {
+				t.Errorf("unexpected trash, got = %v, want = %v", got, c.wantTrashed)
+			}
+
+			if c.wantParentC == 0 {
+				return
+			}
+			todo, err := svc.ReadTODOByID(ctx, 4)
+			if err != nil {
+				t.Fatalf("failed to read todo: %v", err)
+			}
+			if todo.ParentID == nil || *todo.ParentID != c.wantParentC {
+				t.Errorf("unexpected parent, got = %v, want = %d", todo.ParentID, c.wantParentC)
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/TechBowl-japan/go-stations/model"
+)
+
+// A TagService implements reading of tags attached to TODOs.
+type TagService struct {
+	db *sql.DB
+}
+
+// NewTagService returns new TagService.
+func NewTagService(db *sql.DB) *TagService {
+	return &TagService{
+		db: db,
+	}
+}
+
+// ReadTag reads all tags with the number of TODOs outside the trash each tag is attached to.
+func (s *TagService) ReadTag(ctx context.Context) ([]*model.Tag, error) {
+	const read = `SELECT t.name, COUNT(td.id) FROM tags t LEFT JOIN todo_tags tt ON tt.tag_id = t.id LEFT JOIN todos td ON td.id = tt.todo_id AND td.deleted_at IS NULL GROUP BY t.id ORDER BY t.name ASC`
+
+	rows, err := s.db.QueryContext(ctx, read)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := make([]*model.Tag, 0)
+	for rows.Next() {
+		var tag model.Tag
+		if err := rows.Scan(&tag.Name, &tag.Count); err != nil {
+			return nil, err
+		}
+		tags = append(tags, &tag)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return tags, nil
+}
+
+// normalizeTags trims tag names and drops empty and duplicated ones, keeping the original order.
+func normalizeTags(tags []string) []string {
+	var normalized []string
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+	return normalized
+}
+
+// setTODOTags replaces the tags attached to the TODO with tags.
+// Tags that do not exist yet are created.
+func setTODOTags(ctx context.Context, q queryer, todoID int64, tags []string) error {
+	const (
+		clear     = `DELETE FROM todo_tags WHERE todo_id = ?`
+		insertTag = `INSERT OR IGNORE INTO tags(name) VALUES(?)`
+		attach    = `INSERT OR IGNORE INTO todo_tags(todo_id, tag_id) SELECT ?, id FROM tags WHERE name = ?`
+	)
+
+	if _, err := q.ExecContext(ctx, clear, todoID); err != nil {
+		return err
+	}
+	for _, tag := range normalizeTags(tags) {
+		if _, err := q.ExecContext(ctx, insertTag, tag); err != nil {
+			return err
+		}
+		if _, err := q.ExecContext(ctx, attach, todoID, tag); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadTODOTags fills Tags of todos with a single query.
+// Tags of a TODO without any tag stay nil.
+func loadTODOTags(ctx context.Context, q queryer, todos []*model.Todo) error {
+	if len(todos) == 0 {
+		return nil
+	}
+
+	byID := make(map[int64]*model.Todo, len(todos))
+	args := make([]interface{}, 0, len(todos))
+	for _, todo := range todos {
+		byID[todo.ID] = todo
+		args = append(args, todo.ID)
+	}
+
+	query := fmt.Sprintf(`SELECT tt.todo_id, t.name FROM todo_tags tt JOIN tags t ON t.id = tt.tag_id WHERE tt.todo_id IN (%s) ORDER BY t.name ASC`, placeholders(len(args)))
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			todoID int64
+			name   string
+		)
+		if err := rows.Scan(&todoID, &name); err != nil {
+			return err
+		}
+		if todo, ok := byID[todoID]; ok {
+			todo.Tags = append(todo.Tags, name)
+		}
+	}
+
+	return rows.Err()
+}
@@ -0,0 +1,110 @@
+package service_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/TechBowl-japan/go-stations/db"
+	"github.com/TechBowl-japan/go-stations/model"
+	"github.com/TechBowl-japan/go-stations/service"
+)
+
+func TestListTODOTags(t *testing.T) {
+	t.Parallel()
+
+	todoDB, err := db.NewDB(filepath.Join(t.TempDir(), "todo.db"))
+	if err != nil {
+		t.Fatalf("failed to create db: %v", err)
+	}
+	t.Cleanup(func() { todoDB.Close() })
+
+	ctx := context.Background()
+	svc := service.NewTODOService(todoDB)
+	for _, todo := range []struct {
+		subject string
+		tags    []string
+	}{
+		{"backend", []string{"backend"}},
+		{"release", []string{" release-1.4 ", "release-1.4"}},
+		{"both", []string{"release-1.4", "backend"}},
+		{"untagged", nil},
+		{"trashed", []string{"backend", "release-1.4"}},
+	} {
+		if _, err := svc.CreateTODOWithAttributes(ctx, todo.subject, "", &model.TODOAttributes{Tags: todo.tags}); err != nil {
+			t.Fatalf("failed to create todo: %v", err)
+		}
+	}
+	if err := svc.DeleteTODO(ctx, []int64{5}); err != nil {
+		t.Fatalf("failed to delete todo: %v", err)
+	}
+
+	cases := map[string]struct {
+		tags  []string
+		match model.TagMatch
+		want  []string
+	}{
+		"Single tag": {
+			tags: []string{"backend"},
+			want: []string{"backend", "both"},
+		},
+		"All is the default": {
+			tags: []string{"backend", "release-1.4"},
+			want: []string{"both"},
+		},
+		"All ignores duplicated tags": {
+			tags:  []string{"backend", "backend", "release-1.4"},
+			match: model.TagMatchAll,
+			want:  []string{"both"},
+		},
+		"Any": {
+			tags:  []string{"backend", "release-1.4"},
+			match: model.TagMatchAny,
+			want:  []string{"backend", "release", "both"},
+		},
+		"Unknown tag": {
+			tags:  []string{"frontend"},
+			match: model.TagMatchAny,
+			want:  []string{},
+		},
+	}
+
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			todos, err := svc.ListTODO(ctx, &model.TODOQuery{Size: 10, Tags: c.tags, TagMatch: c.match})
+			if err != nil {
+				t.Fatalf("failed to list todos: %v", err)
+			}
+			if got := subjects(todos); !equalStrings(got, c.want) {
+				t.Errorf("unexpected todos, got = %v, want = %v", got, c.want)
+			}
+		})
+	}
+
+	// タグは前後の空白を取り除き、重複を除いて名前順で返す
+	todo, err := svc.ReadTODOByID(ctx, 3)
+	if err != nil {
+		t.Fatalf("failed to read todo: %v", err)
+	}
+	if want := []string{"backend", "release-1.4"}; !equalStrings(todo.Tags, want) {
+		t.Errorf("unexpected tags, got = %v, want = %v", todo.Tags, want)
+	}
+
+	// ゴミ箱の TODO は件数に含めない
+	tags, err := service.NewTagService(todoDB).ReadTag(ctx)
+	if err != nil {
+		t.Fatalf("failed to read tags: %v", err)
+	}
+	want := []model.Tag{{Name: "backend", Count: 2}, {Name: "release-1.4", Count: 2}}
+	if len(tags) != len(want) {
+		t.Fatalf("unexpected tags, got = %+v", tags)
+	}
+	for i := range want {
+		if *tags[i] != want[i] {
+			t.Errorf("unexpected tag, got = %+v, want = %+v", tags[i], want[i])
+		}
+	}
+}
@@ -3,155 +3,684 @@ package service
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/TechBowl-japan/go-stations/model"
 )
 
 // A TODOService implements CRUD of TODO entities.
 type TODOService struct {
-	db *sql.DB
+	db     *sql.DB
+	events *eventBroker
 }
 
 // NewTODOService returns new TODOService.
 func NewTODOService(db *sql.DB) *TODOService {
 	return &TODOService{
-		db: db,
+		db:     db,
+		events: brokerFor(db),
 	}
 }
 
 const (
-	// TODO を更新する SQL
-	updateTODOQuery     = `UPDATE todos SET subject = ?, description = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?`
-	selectTODOByIDQuery = `SELECT id, subject, description, created_at, updated_at FROM todos WHERE id = ?`
+	// todos から読み出すカラム。scanTODO の引数の順序と一致させる
+	todoColumns = `id, subject, description, done, completed_at, due_at, priority, project_id, parent_id, recurrence, created_at, updated_at, version, deleted_at`
+
+	selectTODOByIDQuery = `SELECT ` + todoColumns + ` FROM todos WHERE id = ? AND deleted_at IS NULL`
+
+	// TODO の完了状態を切り替える SQL
+	completeTODOQuery = `UPDATE todos SET done = 1, completed_at = COALESCE(completed_at, CURRENT_TIMESTAMP), next_id = IFNULL(next_id, ?) WHERE id = ? AND deleted_at IS NULL`
+	reopenTODOQuery   = `UPDATE todos SET done = 0, completed_at = NULL WHERE id = ? AND deleted_at IS NULL`
 )
 
+// A queryer is implemented by *sql.DB and *sql.Tx.
+type queryer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// withTx runs fn in a transaction, committing it when fn returns nil and rolling it back otherwise.
+func withTx(ctx context.Context, db *sql.DB, fn func(tx *sql.Tx) error) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// qualifyColumns prefixes each of the comma separated columns with alias.
+func qualifyColumns(alias, columns string) string {
+	fields := strings.Split(columns, ", ")
+	for i, field := range fields {
+		fields[i] = alias + "." + field
+	}
+	return strings.Join(fields, ", ")
+}
+
+// placeholders returns n comma separated placeholders for an IN clause.
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?,", n), ",")
+}
+
+// A rowScanner is implemented by *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanTODO scans a row selected with todoColumns.
+func scanTODO(row rowScanner) (*model.Todo, error) {
+	var (
+		todo        model.Todo
+		completedAt sql.NullTime
+		dueAt       sql.NullTime
+		priority    int
+		projectID   sql.NullInt64
+		parentID    sql.NullInt64
+		createdAt   sql.NullTime
+		updatedAt   sql.NullTime
+		deletedAt   sql.NullTime
+	)
+	if err := row.Scan(&todo.ID, &todo.Subject, &todo.Description, &todo.Done, &completedAt, &dueAt, &priority, &projectID, &parentID, &todo.Recurrence, &createdAt, &updatedAt, &todo.Version, &deletedAt); err != nil {
+		return nil, err
+	}
+	// 作成日時と更新日時は todoViewColumns で選ばなかった場合に NULL になる
+	todo.CreatedAt, todo.UpdatedAt = createdAt.Time, updatedAt.Time
+	todo.Priority = model.PriorityFromRank(priority)
+	if completedAt.Valid {
+		todo.CompletedAt = &completedAt.Time
+	}
+	if dueAt.Valid {
+		todo.DueAt = &dueAt.Time
+	}
+	if projectID.Valid {
+		todo.ProjectID = &projectID.Int64
+	}
+	if parentID.Valid {
+		todo.ParentID = &parentID.Int64
+	}
+	if deletedAt.Valid {
+		todo.DeletedAt = &deletedAt.Time
+	}
+	return &todo, nil
+}
+
+// dbTimeLayout is the format of the times stored in DB, which is the one of CURRENT_TIMESTAMP and DATETIME('now').
+const dbTimeLayout = "2006-01-02 15:04:05"
+
+// dbTime converts t to the value stored in and compared with a DATETIME column.
+// All times are stored in UTC in dbTimeLayout so that they compare correctly as text in SQLite,
+// whether they are written by SQLite or by the service.
+func dbTime(t time.Time) string {
+	return t.UTC().Format(dbTimeLayout)
+}
+
+// nullableTime converts t to the value of a nullable DATETIME column with dbTime.
+func nullableTime(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+	return dbTime(*t)
+}
+
+// startOfDay returns midnight of the day containing t in t's location.
+func startOfDay(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
+
+// readTODOByID reads a TODO with its tags.
+func readTODOByID(ctx context.Context, q queryer, id int64) (*model.Todo, error) {
+	todo, err := scanTODO(q.QueryRowContext(ctx, selectTODOByIDQuery, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, &model.ErrNotFound{}
+		}
+		return nil, err
+	}
+	if err := loadTODOTags(ctx, q, []*model.Todo{todo}); err != nil {
+		return nil, err
+	}
+	return todo, nil
+}
+
+// readTODOs runs query selecting todoColumns and reads the TODOs with their tags.
+func readTODOs(ctx context.Context, q queryer, query string, args ...interface{}) ([]*model.Todo, error) {
+	todos, err := scanTODOs(ctx, q, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	if err := loadTODOTags(ctx, q, todos); err != nil {
+		return nil, err
+	}
+	return todos, nil
+}
+
+// scanTODOs runs query selecting todoColumns, or todoViewColumns, and scans the TODOs without their tags.
+func scanTODOs(ctx context.Context, q queryer, query string, args ...interface{}) ([]*model.Todo, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	todos := make([]*model.Todo, 0)
+	for rows.Next() {
+		todo, err := scanTODO(rows)
+		if err != nil {
+			return nil, err
+		}
+		todos = append(todos, todo)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return todos, nil
+}
+
+// checkVersion returns ErrPreconditionFailed unless the TODO is at one of versions.
+// A nil versions skips the check, while an empty one never matches.
+func checkVersion(ctx context.Context, q queryer, id int64, versions []int64) error {
+	const (
+		query = `SELECT version FROM todos WHERE id = ? AND deleted_at IS NULL`
+	)
+
+	if versions == nil {
+		return nil
+	}
+
+	var version int64
+	if err := q.QueryRowContext(ctx, query, id).Scan(&version); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return &model.ErrNotFound{}
+		}
+		return err
+	}
+	for _, v := range versions {
+		if v == version {
+			return nil
+		}
+	}
+	return &model.ErrPreconditionFailed{}
+}
+
 // CreateTODO creates a TODO on DB.
 func (s *TODOService) CreateTODO(ctx context.Context, subject, description string) (*model.Todo, error) {
+	return s.CreateTODOWithAttributes(ctx, subject, description, &model.TODOAttributes{})
+}
+
+// CreateTODOWithAttributes creates a TODO with optional attributes on DB.
+func (s *TODOService) CreateTODOWithAttributes(ctx context.Context, subject, description string, attrs *model.TODOAttributes) (*model.Todo, error) {
+	var todo *model.Todo
+	err := s.withTx(ctx, func(tx *sql.Tx) (err error) {
+		todo, err = createTODO(ctx, tx, subject, description, attrs)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return todo, nil
+}
+
+// createTODO creates a TODO in tx and records its creation.
+func createTODO(ctx context.Context, tx *sql.Tx, subject, description string, attrs *model.TODOAttributes) (*model.Todo, error) {
+	id, err := insertTODO(ctx, tx, subject, description, attrs)
+	if err != nil {
+		return nil, err
+	}
+
+	todo, err := readTODOByID(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := recordTODOEvent(ctx, tx, model.TODOActionCreate, id, nil, todo); err != nil {
+		return nil, err
+	}
+	return todo, nil
+}
+
+// insertTODO validates references in attrs, inserts a TODO with its tags and returns its id.
+func insertTODO(ctx context.Context, tx *sql.Tx, subject, description string, attrs *model.TODOAttributes) (int64, error) {
 	const (
-		insert  = `INSERT INTO todos(subject, description) VALUES(?, ?)`
-		confirm = `SELECT subject, description, created_at, updated_at FROM todos WHERE id = ?`
+		insert = `INSERT INTO todos(subject, description, due_at, priority, project_id, parent_id, recurrence) VALUES(?, ?, ?, ?, ?, ?, ?)`
 	)
 
+	if err := checkProjectExists(ctx, tx, attrs.ProjectID); err != nil {
+		return 0, err
+	}
+	if err := checkParent(ctx, tx, 0, attrs.ParentID); err != nil {
+		return 0, err
+	}
+	recurrence, err := normalizeRecurrence(attrs.Recurrence)
+	if err != nil {
+		return 0, err
+	}
+
 	// prepare statement
-	stmt, err := s.db.PrepareContext(ctx, insert)
+	stmt, err := tx.PrepareContext(ctx, insert)
 	if err != nil {
-		return nil, err
+		return 0, err
 	}
 	defer stmt.Close()
 
-	res, err := stmt.ExecContext(ctx, subject, description)
+	res, err := stmt.ExecContext(ctx, subject, description, nullableTime(attrs.DueAt), attrs.Priority.Rank(), attrs.ProjectID, attrs.ParentID, recurrence)
 	if err != nil {
-		return nil, err
+		return 0, err
 	}
 
 	lastID, err := res.LastInsertId()
 	if err != nil {
-		return nil, err
+		return 0, err
 	}
 
-	var todo model.Todo
-	row := s.db.QueryRowContext(ctx, confirm, lastID)
-	if err := row.Scan(&todo.Subject, &todo.Description, &todo.CreatedAt, &todo.UpdatedAt); err != nil {
-		return nil, err
+	if err := setTODOTags(ctx, tx, lastID, attrs.Tags); err != nil {
+		return 0, err
 	}
-	todo.ID = lastID
 
-	return &todo, nil
+	return lastID, nil
 }
 
 // ReadTODO reads TODOs on DB.
 func (s *TODOService) ReadTODO(ctx context.Context, prevID, size int64) ([]*model.Todo, error) {
-	const (
-		readWithPrevID = `SELECT id, subject, description, created_at, updated_at FROM todos WHERE id > ? ORDER BY id ASC LIMIT ?`
-		readAll        = `SELECT id, subject, description, created_at, updated_at FROM todos ORDER BY id ASC LIMIT ?`
+	return s.ListTODO(ctx, &model.TODOQuery{PrevID: prevID, Size: size})
+}
+
+// ReadTODOByID reads a TODO on DB.
+func (s *TODOService) ReadTODOByID(ctx context.Context, id int64) (*model.Todo, error) {
+	return readTODOByID(ctx, s.db, id)
+}
+
+// ListTODO reads TODOs matching q on DB.
+func (s *TODOService) ListTODO(ctx context.Context, q *model.TODOQuery) ([]*model.Todo, error) {
+	l, err := newTODOListQuery(q)
+	if err != nil {
+		return nil, err
+	}
+	var after *keyset
+	if q.PrevID > 0 {
+		if after, err = readKeyset(ctx, s.db, l.order, q.PrevID, "prev_id"); err != nil {
+			return nil, err
+		}
+	}
+	todos, _, err := l.readPage(ctx, s.db, l.order, after, pageSize(q))
+	if err != nil {
+		return nil, err
+	}
+	if err := loadTODOTags(ctx, s.db, todos); err != nil {
+		return nil, err
+	}
+	return todos, nil
+}
+
+// pageSize returns the number of TODOs to read for q.
+func pageSize(q *model.TODOQuery) int64 {
+	if q.Size == 0 {
+		return 5
+	}
+	return q.Size
+}
+
+// todoListQuery is the SQL conditions and order of a TODOQuery, without its pagination.
+type todoListQuery struct {
+	// columns is todoColumns, or the columns of q.View.
+	columns string
+	where   []string
+	args    []interface{}
+	order   []sortColumn
+}
+
+// newTODOListQuery compiles the conditions and order of q.
+func newTODOListQuery(q *model.TODOQuery) (*todoListQuery, error) {
+	order, err := compileOrder(q.Order)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		where = []string{"deleted_at IS NULL"}
+		args  []interface{}
 	)
+	if q.Filter != nil {
+		cond, filterArgs, err := compileFilter(q.Filter)
+		if err != nil {
+			return nil, err
+		}
+		where = append(where, cond)
+		args = append(args, filterArgs...)
+	}
+	if q.ProjectID != nil {
+		where = append(where, "project_id = ?")
+		args = append(args, *q.ProjectID)
+	}
+	if q.ParentID != nil {
+		where = append(where, "parent_id = ?")
+		args = append(args, *q.ParentID)
+	}
+	switch q.Status {
+	case model.TODOStatusOpen:
+		where = append(where, "done = 0")
+	case model.TODOStatusDone:
+		where = append(where, "done = 1")
+	}
 
-	if size == 0 {
-		size = 5
+	// 期限の境界は設定されたタイムゾーン (time.Local) の日付で判定する
+	now := time.Now()
+	switch q.Due {
+	case model.TODODueOverdue:
+		where = append(where, "done = 0", "due_at < ?")
+		args = append(args, dbTime(now))
+	case model.TODODueToday:
+		today := startOfDay(now)
+		where = append(where, "due_at >= ?", "due_at < ?")
+		args = append(args, dbTime(today), dbTime(today.AddDate(0, 0, 1)))
+	}
+	if q.DueWithinDays > 0 {
+		end := startOfDay(now).AddDate(0, 0, int(q.DueWithinDays)+1)
+		where = append(where, "due_at >= ?", "due_at < ?")
+		args = append(args, dbTime(now), dbTime(end))
 	}
 
-	var rows *sql.Rows
-	var err error
+	if tags := normalizeTags(q.Tags); len(tags) > 0 {
+		tagQuery := fmt.Sprintf(`SELECT tt.todo_id FROM todo_tags tt JOIN tags t ON t.id = tt.tag_id WHERE t.name IN (%s)`, placeholders(len(tags)))
+		for _, tag := range tags {
+			args = append(args, tag)
+		}
+		if q.TagMatch != model.TagMatchAny {
+			tagQuery += " GROUP BY tt.todo_id HAVING COUNT(*) = ?"
+			args = append(args, len(tags))
+		}
+		where = append(where, "id IN ("+tagQuery+")")
+	}
 
-	if prevID > 0 {
-		rows, err = s.db.QueryContext(ctx, readWithPrevID, prevID, size)
-	} else {
-		rows, err = s.db.QueryContext(ctx, readAll, size)
+	return &todoListQuery{columns: todoViewColumns(q.View), where: where, args: args, order: order}, nil
+}
+
+// readPage reads limit TODOs after the keyset after in the order of columns, or from the first one when after is nil,
+// without their tags. keys are the keysets of the TODOs, with which the next page is read.
+func (l *todoListQuery) readPage(ctx context.Context, q queryer, columns []sortColumn, after *keyset, limit int64) (todos []*model.Todo, keys []*keyset, err error) {
+	where, args := l.where, l.args[:len(l.args):len(l.args)]
+	// after 以降を取得する keyset pagination。並び順のキーで比較するため、
+	// 並び順を変えても同じ TODO が複数のページに現れることはない
+	if after != nil {
+		cond, condArgs := after.condition(columns)
+		where = append(where[:len(where):len(where)], cond)
+		args = append(args, condArgs...)
 	}
+
+	query := `SELECT ` + l.columns + `, ` + sortKeyColumns(columns) + ` FROM todos WHERE ` + strings.Join(where, " AND ")
+	query += orderByClause(columns) + " LIMIT ?"
+	rows, err := q.QueryContext(ctx, query, append(args, limit)...)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	defer rows.Close()
 
-	todos := make([]*model.Todo, 0)
+	todos = make([]*model.Todo, 0)
 	for rows.Next() {
-		var todo model.Todo
-		if err := rows.Scan(&todo.ID, &todo.Subject, &todo.Description, &todo.CreatedAt, &todo.UpdatedAt); err != nil {
-			return nil, err
+		row := keyedRow{rows: rows, keys: make([]interface{}, len(columns))}
+		todo, err := scanTODO(row)
+		if err != nil {
+			return nil, nil, err
 		}
-		todos = append(todos, &todo)
+		todos = append(todos, todo)
+		keys = append(keys, &keyset{keys: row.keys})
 	}
-
 	if err := rows.Err(); err != nil {
-		return nil, err
+		return nil, nil, err
 	}
+	return todos, keys, nil
+}
 
-	return todos, nil
+// keyedRow scans the sort keys selected after the columns of scanTODO into keys.
+type keyedRow struct {
+	rows *sql.Rows
+	keys []interface{}
+}
+
+func (r keyedRow) Scan(dest ...interface{}) error {
+	for i := range r.keys {
+		dest = append(dest, &r.keys[i])
+	}
+	return r.rows.Scan(dest...)
 }
 
-// DeleteTODO deletes TODOs on DB.
+// DeleteTODO moves TODOs to the trash on DB.
+// Children of the deleted TODOs are kept and moved to the parent of the deleted TODO.
+// TODOs in the trash can be restored with RestoreTODO until they are purged.
 func (s *TODOService) DeleteTODO(ctx context.Context, ids []int64) error {
+	return s.DeleteTODOWithPolicy(ctx, ids, model.ChildDeleteReparent, nil)
+}
+
+// DeleteTODOWithPolicy moves TODOs to the trash on DB.
+// TODOs already in the trash are treated as missing.
+// policy decides what happens to the descendants of the deleted TODOs; see model.ChildDeletePolicy.
+// A non-nil ifMatch requires every TODO to be at one of the listed versions; see checkVersion.
+func (s *TODOService) DeleteTODOWithPolicy(ctx context.Context, ids []int64, policy model.ChildDeletePolicy, ifMatch []int64) error {
 	if len(ids) == 0 {
 		return nil
 	}
 
-	placeholders := make([]string, len(ids))
-	args := make([]interface{}, len(ids))
-	for i, id := range ids {
-		placeholders[i] = "?"
-		args[i] = id
-	}
+	return s.withTx(ctx, func(tx *sql.Tx) error {
+		return deleteTODOs(ctx, tx, ids, policy, ifMatch)
+	})
+}
 
-	query := fmt.Sprintf("DELETE FROM todos WHERE id IN (%s)", strings.Join(placeholders, ","))
-	res, err := s.db.ExecContext(ctx, query, args...)
-	if err != nil {
-		return err
+// deleteTODOs moves the TODOs ids to the trash in tx, returning *model.ErrNotFound when none of them is outside the trash.
+func deleteTODOs(ctx context.Context, tx *sql.Tx, ids []int64, policy model.ChildDeletePolicy, ifMatch []int64) error {
+	for _, id := range ids {
+		if err := checkVersion(ctx, tx, id, ifMatch); err != nil {
+			return err
+		}
 	}
-
-	rowsAffected, err := res.RowsAffected()
+	rowsAffected, err := trashTODOs(ctx, tx, ids, policy, time.Now())
 	if err != nil {
 		return err
 	}
 	if rowsAffected == 0 {
 		return &model.ErrNotFound{}
 	}
-
 	return nil
 }
 
-// UpdateTODO updates a TODO on DB.
+// UpdateTODO updates the subject and the description of a TODO on DB, keeping its other attributes.
 func (s *TODOService) UpdateTODO(ctx context.Context, id int64, subject, description string) (*model.Todo, error) {
-	res, err := s.db.ExecContext(ctx, updateTODOQuery, subject, description, id)
+	patch := &model.TODOPatch{
+		Subject:     model.Optional[string]{Set: true, Value: subject},
+		Description: model.Optional[string]{Set: true, Value: description},
+	}
+	return s.PatchTODO(ctx, id, patch, nil)
+}
+
+// PatchTODO partially updates a TODO on DB.
+// Only the columns set in patch are written; the others are left untouched.
+// A non-nil ifMatch requires the TODO to be at one of the listed versions; see checkVersion.
+func (s *TODOService) PatchTODO(ctx context.Context, id int64, patch *model.TODOPatch, ifMatch []int64) (*model.Todo, error) {
+	var todo *model.Todo
+	err := s.withTx(ctx, func(tx *sql.Tx) (err error) {
+		todo, err = patchTODO(ctx, tx, id, patch, ifMatch)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	rowsAffected, err := res.RowsAffected()
+	return todo, nil
+}
+
+// patchTODO partially updates a TODO in tx and records the change.
+func patchTODO(ctx context.Context, tx *sql.Tx, id int64, patch *model.TODOPatch, ifMatch []int64) (*model.Todo, error) {
+	current, err := readTODOByID(ctx, tx, id)
 	if err != nil {
 		return nil, err
 	}
+	if err := checkVersion(ctx, tx, id, ifMatch); err != nil {
+		return nil, err
+	}
 
-	if rowsAffected == 0 {
-		return nil, &model.ErrNotFound{}
+	var (
+		sets []string
+		args []interface{}
+	)
+	set := func(column string, arg interface{}) {
+		sets = append(sets, column+" = ?")
+		args = append(args, arg)
 	}
 
-	var todo model.Todo
-	row := s.db.QueryRowContext(ctx, selectTODOByIDQuery, id)
-	if err := row.Scan(&todo.ID, &todo.Subject, &todo.Description, &todo.CreatedAt, &todo.UpdatedAt); err != nil {
+	if patch.Subject.Set {
+		set("subject", patch.Subject.Value)
+	}
+	if patch.Description.Set {
+		set("description", patch.Description.Value)
+	}
+	if patch.DueAt.Set {
+		set("due_at", nullableTime(patch.DueAt.Value))
+	}
+	if patch.Priority.Set {
+		set("priority", patch.Priority.Value.Rank())
+	}
+	if patch.ProjectID.Set {
+		if err := checkProjectExists(ctx, tx, patch.ProjectID.Value); err != nil {
+			return nil, err
+		}
+		set("project_id", patch.ProjectID.Value)
+	}
+	if patch.ParentID.Set {
+		if err := checkParent(ctx, tx, id, patch.ParentID.Value); err != nil {
+			return nil, err
+		}
+		set("parent_id", patch.ParentID.Value)
+	}
+	if patch.Recurrence.Set {
+		recurrence, err := normalizeRecurrence(patch.Recurrence.Value)
+		if err != nil {
+			return nil, err
+		}
+		set("recurrence", recurrence)
+	}
+
+	if len(sets) > 0 || patch.Tags.Set {
+		sets = append(sets, "updated_at = CURRENT_TIMESTAMP")
+		query := `UPDATE todos SET ` + strings.Join(sets, ", ") + ` WHERE id = ?`
+		if _, err := tx.ExecContext(ctx, query, append(args, id)...); err != nil {
+			return nil, err
+		}
+	}
+
+	if patch.Tags.Set {
+		if err := setTODOTags(ctx, tx, id, patch.Tags.Value); err != nil {
+			return nil, err
+		}
+	}
+
+	todo, err := readTODOByID(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := recordTODOEvent(ctx, tx, model.TODOActionUpdate, id, current, todo); err != nil {
 		return nil, err
 	}
+	return todo, nil
+}
 
-	return &todo, nil
+// CompleteTODO marks a TODO as done on DB.
+// Completing a TODO that is already done keeps its original completed_at.
+// When a recurring TODO is completed, its next occurrence is created and returned as next.
+func (s *TODOService) CompleteTODO(ctx context.Context, id int64) (todo, next *model.Todo, err error) {
+	err = s.withTx(ctx, func(tx *sql.Tx) (err error) {
+		todo, next, err = completeTODO(ctx, tx, id)
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return todo, next, nil
+}
+
+// completeTODO marks a TODO as done in tx and records the change, creating the next occurrence of a recurring TODO.
+// The next occurrence is created only once, even when the TODO is reopened and completed again.
+func completeTODO(ctx context.Context, tx *sql.Tx, id int64) (todo, next *model.Todo, err error) {
+	const (
+		selectNextID = `SELECT next_id FROM todos WHERE id = ?`
+	)
+
+	current, err := readTODOByID(ctx, tx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var nextID int64
+	if !current.Done && current.Recurrence != "" {
+		// 再開して完了し直した場合は、前回作った次の TODO があれば作らない
+		var generated sql.NullInt64
+		if err := tx.QueryRowContext(ctx, selectNextID, id).Scan(&generated); err != nil {
+			return nil, nil, err
+		}
+		if !generated.Valid {
+			if nextID, err = insertNextOccurrence(ctx, tx, current); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+
+	var nullableNextID interface{}
+	if nextID != 0 {
+		nullableNextID = nextID
+	}
+	if _, err := tx.ExecContext(ctx, completeTODOQuery, nullableNextID, id); err != nil {
+		return nil, nil, err
+	}
+	todo, err = readTODOByID(ctx, tx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := recordTODOEvent(ctx, tx, model.TODOActionUpdate, id, current, todo); err != nil {
+		return nil, nil, err
+	}
+
+	if nextID == 0 {
+		return todo, nil, nil
+	}
+	next, err = readTODOByID(ctx, tx, nextID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := recordTODOEvent(ctx, tx, model.TODOActionCreate, nextID, nil, next); err != nil {
+		return nil, nil, err
+	}
+	return todo, next, nil
+}
+
+// ReopenTODO marks a TODO as not done on DB.
+func (s *TODOService) ReopenTODO(ctx context.Context, id int64) (*model.Todo, error) {
+	var todo *model.Todo
+	err := s.withTx(ctx, func(tx *sql.Tx) error {
+		current, err := readTODOByID(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx, reopenTODOQuery, id); err != nil {
+			return err
+		}
+		todo, err = readTODOByID(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		return recordTODOEvent(ctx, tx, model.TODOActionUpdate, id, current, todo)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return todo, nil
 }
@@ -0,0 +1,278 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/TechBowl-japan/go-stations/db"
+	"github.com/TechBowl-japan/go-stations/model"
+	"github.com/TechBowl-japan/go-stations/service"
+)
+
+// subjects returns the subjects of todos in order.
+func subjects(todos []*model.Todo) []string {
+	got := make([]string, 0, len(todos))
+	for _, todo := range todos {
+		got = append(got, todo.Subject)
+	}
+	return got
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestCompleteTODO(t *testing.T) {
+	t.Parallel()
+
+	todoDB, err := db.NewDB(filepath.Join(t.TempDir(), "todo.db"))
+	if err != nil {
+		t.Fatalf("failed to create db: %v", err)
+	}
+	t.Cleanup(func() { todoDB.Close() })
+
+	ctx := context.Background()
+	svc := service.NewTODOService(todoDB)
+	for _, subject := range []string{"done", "open"} {
+		if _, err := svc.CreateTODO(ctx, subject, ""); err != nil {
+			t.Fatalf("failed to create todo: %v", err)
+		}
+	}
+
+	done, _, err := svc.CompleteTODO(ctx, 1)
+	if err != nil {
+		t.Fatalf("failed to complete todo: %v", err)
+	}
+	if !done.Done || done.CompletedAt == nil {
+		t.Fatalf("unexpected completed todo, got = %+v", done)
+	}
+	// 完了済みの TODO を完了にしても完了日時は変わらない
+	again, _, err := svc.CompleteTODO(ctx, 1)
+	if err != nil {
+		t.Fatalf("failed to complete todo again: %v", err)
+	}
+	if again.CompletedAt == nil || !again.CompletedAt.Equal(*done.CompletedAt) {
+		t.Errorf("completed_at changed, got = %v, want = %v", again.CompletedAt, done.CompletedAt)
+	}
+
+	for status, want := range map[model.TODOStatus][]string{
+		"":                   {"done", "open"},
+		model.TODOStatusOpen: {"open"},
+		model.TODOStatusDone: {"done"},
+	} {
+		todos, err := svc.ListTODO(ctx, &model.TODOQuery{Size: 10, Status: status})
+		if err != nil {
+			t.Fatalf("failed to list %q todos: %v", status, err)
+		}
+		if got := subjects(todos); !equalStrings(got, want) {
+			t.Errorf("unexpected %q todos, got = %v, want = %v", status, got, want)
+		}
+	}
+
+	reopened, err := svc.ReopenTODO(ctx, 1)
+	if err != nil {
+		t.Fatalf("failed to reopen todo: %v", err)
+	}
+	if reopened.Done || reopened.CompletedAt != nil {
+		t.Errorf("unexpected reopened todo, got = %+v", reopened)
+	}
+
+	var errNotFound *model.ErrNotFound
+	if _, _, err := svc.CompleteTODO(ctx, 99); !errors.As(err, &errNotFound) {
+		t.Errorf("unexpected error of missing todo, got = %v", err)
+	}
+	if _, err := svc.ReopenTODO(ctx, 99); !errors.As(err, &errNotFound) {
+		t.Errorf("unexpected error of missing todo, got = %v", err)
+	}
+}
+
+func TestListTODODue(t *testing.T) {
+	t.Parallel()
+
+	todoDB, err := db.NewDB(filepath.Join(t.TempDir(), "todo.db"))
+	if err != nil {
+		t.Fatalf("failed to create db: %v", err)
+	}
+	t.Cleanup(func() { todoDB.Close() })
+
+	ctx := context.Background()
+	svc := service.NewTODOService(todoDB)
+
+	// 日付の境界は time.Local の 0 時。日付をまたいで実行しても結果が変わらないよう、
+	// 現在時刻の前後の TODO は今日の 0 時と現在時刻、現在時刻と今日の終わりのそれぞれ中間にする
+	now := time.Now()
+	y, m, d := now.Date()
+	today := time.Date(y, m, d, 0, 0, 0, 0, time.Local)
+	tomorrow := today.AddDate(0, 0, 1)
+	at := func(t time.Time) *time.Time { return &t }
+	for _, todo := range []struct {
+		subject string
+		dueAt   *time.Time
+		done    bool
+	}{
+		{"no due", nil, false},
+		{"start of today", at(today), false},
+		{"past done", at(today.Add(now.Sub(today) / 2)), true},
+		{"soon", at(now.Add(tomorrow.Sub(now) / 2)), false},
+		{"end of today", at(tomorrow.Add(-time.Second)), false},
+		{"end of tomorrow", at(today.AddDate(0, 0, 2).Add(-time.Second)), false},
+		{"day after tomorrow", at(today.AddDate(0, 0, 2)), false},
+	} {
+		created, err := svc.CreateTODOWithAttributes(ctx, todo.subject, "", &model.TODOAttributes{DueAt: todo.dueAt})
+		if err != nil {
+			t.Fatalf("failed to create todo: %v", err)
+		}
+		if todo.done {
+			if _, _, err := svc.CompleteTODO(ctx, created.ID); err != nil {
+				t.Fatalf("failed to complete todo: %v", err)
+			}
+		}
+	}
+
+	cases := map[string]struct {
+		query model.TODOQuery
+		want  []string
+	}{
+		"Overdue excludes done and future TODOs": {
+			query: model.TODOQuery{Due: model.TODODueOverdue},
+			want:  []string{"start of today"},
+		},
+		"Today covers the whole day": {
+			query: model.TODOQuery{Due: model.TODODueToday},
+			want:  []string{"start of today", "past done", "soon", "end of today"},
+		},
+		"Within 0 days does not filter": {
+			query: model.TODOQuery{DueWithinDays: 0},
+			want:  []string{"no due", "start of today", "past done", "soon", "end of today", "end of tomorrow", "day after tomorrow"},
+		},
+		"Within 1 day ends at the end of tomorrow": {
+			query: model.TODOQuery{DueWithinDays: 1},
+			want:  []string{"soon", "end of today", "end of tomorrow"},
+		},
+		"Due today within 1 day": {
+			query: model.TODOQuery{Due: model.TODODueToday, DueWithinDays: 1},
+			want:  []string{"soon", "end of today"},
+		},
+	}
+
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			c.query.Size = 10
+			todos, err := svc.ListTODO(ctx, &c.query)
+			if err != nil {
+				t.Fatalf("failed to list todos: %v", err)
+			}
+			if got := subjects(todos); !equalStrings(got, c.want) {
+				t.Errorf("unexpected todos, got = %v, want = %v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestListTODOPriority(t *testing.T) {
+	t.Parallel()
+
+	todoDB, err := db.NewDB(filepath.Join(t.TempDir(), "todo.db"))
+	if err != nil {
+		t.Fatalf("failed to create db: %v", err)
+	}
+	t.Cleanup(func() { todoDB.Close() })
+
+	ctx := context.Background()
+	svc := service.NewTODOService(todoDB)
+
+	day := func(d int) *time.Time {
+		t := time.Date(2026, 11, d, 12, 0, 0, 0, time.Local)
+		return &t
+	}
+	for _, todo := range []struct {
+		subject string
+		attrs   model.TODOAttributes
+	}{
+		{"low", model.TODOAttributes{Priority: model.PriorityLow, DueAt: day(3)}},
+		{"high late", model.TODOAttributes{Priority: model.PriorityHigh, DueAt: day(5)}},
+		{"high early", model.TODOAttributes{Priority: model.PriorityHigh, DueAt: day(1)}},
+		{"high tie", model.TODOAttributes{Priority: model.PriorityHigh, DueAt: day(5)}},
+		{"urgent no due", model.TODOAttributes{Priority: model.PriorityUrgent}},
+		{"urgent due", model.TODOAttributes{Priority: model.PriorityUrgent, DueAt: day(9)}},
+		{"none", model.TODOAttributes{}},
+	} {
+		if _, err := svc.CreateTODOWithAttributes(ctx, todo.subject, "", &todo.attrs); err != nil {
+			t.Fatalf("failed to create todo: %v", err)
+		}
+	}
+
+	cases := map[string]struct {
+		sort string
+		want []string
+	}{
+		"Priority ascending ties by id": {
+			sort: "priority",
+			want: []string{"none", "low", "high late", "high early", "high tie", "urgent no due", "urgent due"},
+		},
+		"Priority descending ties by id": {
+			sort: "-priority",
+			want: []string{"urgent no due", "urgent due", "high late", "high early", "high tie", "low", "none"},
+		},
+		"Triage order": {
+			sort: "-priority,due_at",
+			want: []string{"urgent due", "urgent no due", "high early", "high late", "high tie", "low", "none"},
+		},
+	}
+
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			order, err := model.ParseSort(c.sort)
+			if err != nil {
+				t.Fatalf("failed to parse sort: %v", err)
+			}
+
+			// 同じ優先度や期限の TODO がページの境界にあっても、prev_id で読み飛ばしや重複が起きない
+			var (
+				got    []string
+				prevID int64
+			)
+			for i := 0; i <= len(c.want); i++ {
+				todos, err := svc.ListTODO(ctx, &model.TODOQuery{PrevID: prevID, Size: 2, Order: order})
+				if err != nil {
+					t.Fatalf("failed to list todos: %v", err)
+				}
+				if len(todos) == 0 {
+					break
+				}
+				got = append(got, subjects(todos)...)
+				prevID = todos[len(todos)-1].ID
+			}
+			if !equalStrings(got, c.want) {
+				t.Errorf("unexpected todos, got = %v, want = %v", got, c.want)
+			}
+		})
+	}
+
+	var errValidation *model.ErrValidation
+	if _, err := model.ParseSort("urgency"); !errors.As(err, &errValidation) {
+		t.Errorf("unexpected error for unknown sort field, got = %v", err)
+	}
+	var errValidationFailed *model.ErrValidationFailed
+	req := &model.CreateTODORequest{Subject: "critical", TODOAttributes: model.TODOAttributes{Priority: "critical"}}
+	if err := model.Validate(req); !errors.As(err, &errValidationFailed) {
+		t.Errorf("unexpected error for unknown priority, got = %v", err)
+	}
+}
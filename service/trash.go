@@ -0,0 +1,222 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/TechBowl-japan/go-stations/model"
+)
+
+const (
+	// 指定した TODO (? の位置に id を列挙する) のうちゴミ箱にあるものと、
+	// それらと同時にゴミ箱に移された子孫の id を返す再帰クエリ
+	restoredIDsQuery = `WITH RECURSIVE restored(id, deleted_at) AS (
+  SELECT id, deleted_at FROM todos WHERE id IN (%s) AND deleted_at IS NOT NULL
+  UNION
+  SELECT t.id, t.deleted_at FROM todos t JOIN restored r ON t.parent_id = r.id WHERE t.deleted_at = r.deleted_at
+) SELECT id FROM restored`
+)
+
+// DefaultTrashRetention is how long TODOs are kept in the trash before they are purged, unless configured otherwise.
+const DefaultTrashRetention = 30 * 24 * time.Hour
+
+// int64Args converts ids to the arguments of a query with placeholders.
+func int64Args(ids []int64) []interface{} {
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	return args
+}
+
+// trashTODOs moves the TODOs ids outside the trash to the trash at now, handling their descendants according to policy,
+// and returns the number of TODOs trashed among ids.
+// With model.ChildDeleteCascade the descendants are trashed at the same time, so that RestoreTODO brings them back together.
+func trashTODOs(ctx context.Context, tx *sql.Tx, ids []int64, policy model.ChildDeletePolicy, now time.Time) (int64, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	live, err := queryIDs(ctx, tx, fmt.Sprintf(`SELECT id FROM todos WHERE id IN (%s) AND deleted_at IS NULL`, placeholders(len(ids))), int64Args(ids)...)
+	if err != nil || len(live) == 0 {
+		return 0, err
+	}
+
+	targets := live
+	var children []int64
+	if policy == model.ChildDeleteCascade {
+		query := fmt.Sprintf(`SELECT id FROM todos WHERE id IN (%s) AND id NOT IN (%s) AND deleted_at IS NULL`, fmt.Sprintf(descendantIDsQuery, placeholders(len(live))), placeholders(len(live)))
+		descendants, err := queryIDs(ctx, tx, query, append(int64Args(live), int64Args(live)...)...)
+		if err != nil {
+			return 0, err
+		}
+		targets = append(targets, descendants...)
+	} else {
+		// 付け替えられる子の変更も履歴に残す
+		children, err = queryIDs(ctx, tx, fmt.Sprintf(`SELECT id FROM todos WHERE parent_id IN (%[1]s) AND id NOT IN (%[1]s)`, placeholders(len(live))), append(int64Args(live), int64Args(live)...)...)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	before, err := snapshotTODOs(ctx, tx, append(targets, children...))
+	if err != nil {
+		return 0, err
+	}
+
+	if policy != model.ChildDeleteCascade {
+		if err := reparentChildren(ctx, tx, live); err != nil {
+			return 0, err
+		}
+	}
+	query := fmt.Sprintf(`UPDATE todos SET deleted_at = ? WHERE id IN (%s) AND deleted_at IS NULL`, placeholders(len(targets)))
+	if _, err := tx.ExecContext(ctx, query, append([]interface{}{dbTime(now)}, int64Args(targets)...)...); err != nil {
+		return 0, err
+	}
+
+	after, err := snapshotTODOs(ctx, tx, append(targets, children...))
+	if err != nil {
+		return 0, err
+	}
+	if err := recordTODOEvents(ctx, tx, model.TODOActionUpdate, children, before, after); err != nil {
+		return 0, err
+	}
+	if err := recordTODOEvents(ctx, tx, model.TODOActionDelete, targets, before, after); err != nil {
+		return 0, err
+	}
+
+	return int64(len(live)), nil
+}
+
+// ReadTrash reads TODOs in the trash on DB, ordered by id.
+func (s *TODOService) ReadTrash(ctx context.Context, prevID, size int64) ([]*model.Todo, error) {
+	const (
+		read = `SELECT ` + todoColumns + ` FROM todos WHERE deleted_at IS NOT NULL AND id > ? ORDER BY id ASC LIMIT ?`
+	)
+
+	if size == 0 {
+		size = 5
+	}
+
+	return readTODOs(ctx, s.db, read, prevID, size)
+}
+
+// RestoreTODO moves TODOs out of the trash on DB and returns the restored ones among ids.
+// Descendants trashed together with a TODO are restored with it.
+// A restored TODO whose parent is still in the trash becomes a top-level TODO.
+func (s *TODOService) RestoreTODO(ctx context.Context, ids []int64) ([]*model.Todo, error) {
+	if len(ids) == 0 {
+		return nil, &model.ErrNotFound{}
+	}
+
+	todos := make([]*model.Todo, 0, len(ids))
+	err := s.withTx(ctx, func(tx *sql.Tx) error {
+		restored, err := queryIDs(ctx, tx, fmt.Sprintf(restoredIDsQuery, placeholders(len(ids))), int64Args(ids)...)
+		if err != nil {
+			return err
+		}
+		if len(restored) == 0 {
+			return &model.ErrNotFound{}
+		}
+
+		before, err := snapshotTODOs(ctx, tx, restored)
+		if err != nil {
+			return err
+		}
+
+		args := int64Args(restored)
+		restore := fmt.Sprintf(`UPDATE todos SET deleted_at = NULL WHERE id IN (%s)`, placeholders(len(restored)))
+		if _, err := tx.ExecContext(ctx, restore, args...); err != nil {
+			return err
+		}
+		// 親がゴミ箱に残っている場合は親子関係を外す
+		detach := fmt.Sprintf(`UPDATE todos SET parent_id = NULL WHERE id IN (%s) AND parent_id IN (SELECT id FROM todos WHERE deleted_at IS NOT NULL)`, placeholders(len(restored)))
+		if _, err := tx.ExecContext(ctx, detach, args...); err != nil {
+			return err
+		}
+
+		after, err := snapshotTODOs(ctx, tx, restored)
+		if err != nil {
+			return err
+		}
+		if err := recordTODOEvents(ctx, tx, model.TODOActionRestore, restored, before, after); err != nil {
+			return err
+		}
+
+		isRestored := make(map[int64]bool, len(restored))
+		for _, id := range restored {
+			isRestored[id] = true
+		}
+		for _, id := range ids {
+			if !isRestored[id] {
+				continue
+			}
+			// 同じ id が複数回指定された場合も 1 度だけ返す
+			isRestored[id] = false
+			todos = append(todos, after[id])
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return todos, nil
+}
+
+// PurgeTODO permanently deletes TODOs moved to the trash at or before before on DB,
+// and returns the number of TODOs deleted.
+func (s *TODOService) PurgeTODO(ctx context.Context, before time.Time) (int64, error) {
+	const (
+		selectPurged = `SELECT id FROM todos WHERE deleted_at <= ?`
+		// 残る TODO が削除される TODO を親として参照しないよう、先に親子関係を外す
+		selectDetached = `SELECT id FROM todos WHERE parent_id IN (SELECT id FROM todos WHERE deleted_at <= ?) AND (deleted_at IS NULL OR deleted_at > ?)`
+		detach         = `UPDATE todos SET parent_id = NULL WHERE parent_id IN (SELECT id FROM todos WHERE deleted_at <= ?) AND (deleted_at IS NULL OR deleted_at > ?)`
+		purge          = `DELETE FROM todos WHERE deleted_at <= ?`
+	)
+
+	var purged int64
+	err := s.withTx(ctx, func(tx *sql.Tx) error {
+		before := dbTime(before)
+		purgedIDs, err := queryIDs(ctx, tx, selectPurged, before)
+		if err != nil || len(purgedIDs) == 0 {
+			return err
+		}
+		detached, err := queryIDs(ctx, tx, selectDetached, before, before)
+		if err != nil {
+			return err
+		}
+		snapshots, err := snapshotTODOs(ctx, tx, append(purgedIDs, detached...))
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx, detach, before, before); err != nil {
+			return err
+		}
+		res, err := tx.ExecContext(ctx, purge, before)
+		if err != nil {
+			return err
+		}
+		purged, err = res.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		after, err := snapshotTODOs(ctx, tx, detached)
+		if err != nil {
+			return err
+		}
+		if err := recordTODOEvents(ctx, tx, model.TODOActionUpdate, detached, snapshots, after); err != nil {
+			return err
+		}
+		return recordTODOEvents(ctx, tx, model.TODOActionPurge, purgedIDs, snapshots, nil)
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return purged, nil
+}
@@ -0,0 +1,83 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/TechBowl-japan/go-stations/db"
+	"github.com/TechBowl-japan/go-stations/model"
+	"github.com/TechBowl-japan/go-stations/service"
+)
+
+func TestTrash(t *testing.T) {
+	t.Parallel()
+
+	todoDB, err := db.NewDB(filepath.Join(t.TempDir(), "todo.db"))
+	if err != nil {
+		t.Fatalf("failed to create db: %v", err)
+	}
+	t.Cleanup(func() { todoDB.Close() })
+
+	ctx := context.Background()
+	svc := service.NewTODOService(todoDB)
+
+	parent, err := svc.CreateTODO(ctx, "parent", "")
+	if err != nil {
+		t.Fatalf("failed to create todo: %v", err)
+	}
+	child, err := svc.CreateTODOWithAttributes(ctx, "child", "", &model.TODOAttributes{ParentID: &parent.ID})
+	if err != nil {
+		t.Fatalf("failed to create todo: %v", err)
+	}
+
+	if err := svc.DeleteTODOWithPolicy(ctx, []int64{parent.ID}, model.ChildDeleteCascade, nil); err != nil {
+		t.Fatalf("failed to delete todo: %v", err)
+	}
+	var errNotFound *model.ErrNotFound
+	if _, err := svc.ReadTODOByID(ctx, child.ID); !errors.As(err, &errNotFound) {
+		t.Errorf("trashed child is still readable, err = %v", err)
+	}
+	if err := svc.DeleteTODO(ctx, []int64{parent.ID}); !errors.As(err, &errNotFound) {
+		t.Errorf("trashed todo is deleted again, err = %v", err)
+	}
+	trash, err := svc.ReadTrash(ctx, 0, 10)
+	if err != nil {
+		t.Fatalf("failed to read trash: %v", err)
+	}
+	if len(trash) != 2 || trash[0].DeletedAt == nil {
+		t.Errorf("unexpected trash, got = %+v", trash)
+	}
+
+	restored, err := svc.RestoreTODO(ctx, []int64{parent.ID})
+	if err != nil {
+		t.Fatalf("failed to restore todo: %v", err)
+	}
+	if len(restored) != 1 || restored[0].ID != parent.ID || restored[0].DeletedAt != nil {
+		t.Errorf("unexpected restored todos, got = %+v", restored)
+	}
+	got, err := svc.ReadTODOByID(ctx, child.ID)
+	if err != nil {
+		t.Fatalf("child is not restored with its parent: %v", err)
+	}
+	if got.ParentID == nil || *got.ParentID != parent.ID {
+		t.Errorf("unexpected parent of the restored child, got = %v", got.ParentID)
+	}
+
+	if err := svc.DeleteTODO(ctx, []int64{child.ID}); err != nil {
+		t.Fatalf("failed to delete todo: %v", err)
+	}
+	purged, err := svc.PurgeTODO(ctx, time.Now().Add(-time.Hour))
+	if err != nil || purged != 0 {
+		t.Errorf("todo within the retention is purged, purged = %d, err = %v", purged, err)
+	}
+	purged, err = svc.PurgeTODO(ctx, time.Now())
+	if err != nil || purged != 1 {
+		t.Errorf("unexpected purge, purged = %d, err = %v", purged, err)
+	}
+	if _, err := svc.RestoreTODO(ctx, []int64{child.ID}); !errors.As(err, &errNotFound) {
+		t.Errorf("purged todo is restored, err = %v", err)
+	}
+}
@@ -0,0 +1,154 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/TechBowl-japan/go-stations/model"
+)
+
+// todoColumnDefaults are selected instead of the columns of todoColumns not chosen by a model.TODOView,
+// so that scanTODO scans any projection. id, version and deleted_at are always selected.
+var todoColumnDefaults = map[string]string{
+	"subject":      "''",
+	"description":  "''",
+	"done":         "0",
+	"completed_at": "NULL",
+	"due_at":       "NULL",
+	"priority":     "0",
+	"project_id":   "NULL",
+	"parent_id":    "NULL",
+	"recurrence":   "''",
+	"created_at":   "NULL",
+	"updated_at":   "NULL",
+}
+
+// todoViewColumns returns todoColumns with the columns not chosen by view replaced by their defaults.
+func todoViewColumns(view *model.TODOView) string {
+	if view == nil || len(view.Fields) == 0 {
+		return todoColumns
+	}
+
+	columns := strings.Split(todoColumns, ", ")
+	for i, column := range columns {
+		def, ok := todoColumnDefaults[column]
+		// 展開するプロジェクトは project_id で読み込む
+		if !ok || view.Selects(column) || (column == "project_id" && view.Expands(model.TODOExpandProject)) {
+			continue
+		}
+		columns[i] = def
+	}
+	return strings.Join(columns, ", ")
+}
+
+// expandTODOs loads the tags and the expansions chosen by view into todos, with a single query for each.
+func expandTODOs(ctx context.Context, q queryer, todos []*model.Todo, view *model.TODOView) error {
+	if view.Selects("tags") {
+		if err := loadTODOTags(ctx, q, todos); err != nil {
+			return err
+		}
+	}
+	if view.Expands(model.TODOExpandProject) {
+		if err := loadTODOProjects(ctx, q, todos); err != nil {
+			return err
+		}
+	}
+	if view.Expands(model.TODOExpandSubtasks) {
+		if err := loadSubtaskCounts(ctx, q, todos); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadTODOProjects fills Project of todos with a single query.
+func loadTODOProjects(ctx context.Context, q queryer, todos []*model.Todo) error {
+	byProject := make(map[int64][]*model.Todo)
+	args := make([]interface{}, 0, len(todos))
+	for _, todo := range todos {
+		if todo.ProjectID == nil {
+			continue
+		}
+		if _, ok := byProject[*todo.ProjectID]; !ok {
+			args = append(args, *todo.ProjectID)
+		}
+		byProject[*todo.ProjectID] = append(byProject[*todo.ProjectID], todo)
+	}
+	if len(args) == 0 {
+		return nil
+	}
+
+	query := fmt.Sprintf(`SELECT id, name, description, created_at, updated_at FROM projects WHERE id IN (%s)`, placeholders(len(args)))
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var project model.Project
+		if err := rows.Scan(&project.ID, &project.Name, &project.Description, &project.CreatedAt, &project.UpdatedAt); err != nil {
+			return err
+		}
+		for _, todo := range byProject[project.ID] {
+			todo.Project = &project
+		}
+	}
+	return rows.Err()
+}
+
+// loadSubtaskCounts fills Subtasks of todos with a single query.
+// TODOs without children get zero counts.
+func loadSubtaskCounts(ctx context.Context, q queryer, todos []*model.Todo) error {
+	if len(todos) == 0 {
+		return nil
+	}
+
+	byID := make(map[int64]*model.Todo, len(todos))
+	args := make([]interface{}, 0, len(todos))
+	for _, todo := range todos {
+		todo.Subtasks = &model.SubtaskCounts{}
+		byID[todo.ID] = todo
+		args = append(args, todo.ID)
+	}
+
+	query := fmt.Sprintf(`SELECT parent_id, COUNT(*), IFNULL(SUM(done), 0) FROM todos WHERE parent_id IN (%s) AND deleted_at IS NULL GROUP BY parent_id`, placeholders(len(args)))
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			parentID int64
+			counts   model.SubtaskCounts
+		)
+		if err := rows.Scan(&parentID, &counts.Total, &counts.Done); err != nil {
+			return err
+		}
+		if todo, ok := byID[parentID]; ok {
+			*todo.Subtasks = counts
+		}
+	}
+	return rows.Err()
+}
+
+// ReadTODOByIDWithView reads a TODO on DB with the fields and the expansions chosen by view.
+func (s *TODOService) ReadTODOByIDWithView(ctx context.Context, id int64, view *model.TODOView) (*model.Todo, error) {
+	query := `SELECT ` + todoViewColumns(view) + ` FROM todos WHERE id = ? AND deleted_at IS NULL`
+	todo, err := scanTODO(s.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, &model.ErrNotFound{}
+		}
+		return nil, err
+	}
+	if err := expandTODOs(ctx, s.db, []*model.Todo{todo}, view); err != nil {
+		return nil, err
+	}
+	return todo, nil
+}
@@ -0,0 +1,75 @@
+package service_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/TechBowl-japan/go-stations/db"
+	"github.com/TechBowl-japan/go-stations/model"
+	"github.com/TechBowl-japan/go-stations/service"
+)
+
+func TestListTODOPageView(t *testing.T) {
+	t.Parallel()
+
+	todoDB, err := db.NewDB(filepath.Join(t.TempDir(), "todo.db"))
+	if err != nil {
+		t.Fatalf("failed to create db: %v", err)
+	}
+	t.Cleanup(func() { todoDB.Close() })
+
+	ctx := context.Background()
+	svc := service.NewTODOService(todoDB)
+	project, err := service.NewProjectService(todoDB).CreateProject(ctx, "project", "")
+	if err != nil {
+		t.Fatalf("failed to create project: %v", err)
+	}
+	parent, err := svc.CreateTODOWithAttributes(ctx, "parent", "description", &model.TODOAttributes{ProjectID: &project.ID, Tags: []string{"work"}})
+	if err != nil {
+		t.Fatalf("failed to create todo: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := svc.CreateTODOWithAttributes(ctx, "child", "", &model.TODOAttributes{ParentID: &parent.ID}); err != nil {
+			t.Fatalf("failed to create todo: %v", err)
+		}
+	}
+	if _, _, err := svc.CompleteTODO(ctx, parent.ID+1); err != nil {
+		t.Fatalf("failed to complete todo: %v", err)
+	}
+
+	view, err := model.ParseTODOView("subject", "project,subtasks")
+	if err != nil {
+		t.Fatalf("failed to parse view: %v", err)
+	}
+	page, err := svc.ListTODOPage(ctx, &model.TODOQuery{View: view})
+	if err != nil {
+		t.Fatalf("failed to list todos: %v", err)
+	}
+	if len(page.Todos) != 3 {
+		t.Fatalf("unexpected todos, got = %+v", page.Todos)
+	}
+
+	got := page.Todos[0]
+	// 選ばなかった項目は読み込まない
+	if got.Subject != "parent" || got.Description != "" || got.Tags != nil || !got.CreatedAt.IsZero() {
+		t.Errorf("unexpected fields, got = %+v", got)
+	}
+	if got.Project == nil || got.Project.Name != "project" {
+		t.Errorf("unexpected project, got = %+v", got.Project)
+	}
+	if got.Subtasks == nil || *got.Subtasks != (model.SubtaskCounts{Total: 2, Done: 1}) {
+		t.Errorf("unexpected subtasks, got = %+v", got.Subtasks)
+	}
+	if child := page.Todos[1]; child.Project != nil || child.Subtasks == nil || *child.Subtasks != (model.SubtaskCounts{}) {
+		t.Errorf("unexpected child, got = %+v", child)
+	}
+
+	todo, err := svc.ReadTODOByIDWithView(ctx, parent.ID, &model.TODOView{Fields: []string{"description"}, Expand: []model.TODOExpansion{model.TODOExpandTags}})
+	if err != nil {
+		t.Fatalf("failed to read todo: %v", err)
+	}
+	if todo.Subject != "" || todo.Description != "description" || len(todo.Tags) != 1 || todo.Version != parent.Version {
+		t.Errorf("unexpected todo, got = %+v", todo)
+	}
+}
@@ -0,0 +1,585 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/TechBowl-japan/go-stations/model"
+)
+
+// Headers set on every webhook request.
+const (
+	// WebhookSignatureHeader carries SignWebhookPayload of the request, such as "sha256=5d41...".
+	WebhookSignatureHeader = "X-Webhook-Signature"
+	// WebhookTimestampHeader carries the Unix time the request was signed at.
+	WebhookTimestampHeader = "X-Webhook-Timestamp"
+	// WebhookEventHeader carries the action of the TODO event, such as "create".
+	WebhookEventHeader = "X-Webhook-Event"
+	// WebhookDeliveryHeader carries the delivery id, which stays the same across retries.
+	WebhookDeliveryHeader = "X-Webhook-Delivery"
+)
+
+const (
+	// webhookMaxAttempts is the number of failed attempts after which a delivery is dead.
+	webhookMaxAttempts = 8
+	// webhookRetryBaseDelay is the delay before the first retry, doubled on every retry.
+	webhookRetryBaseDelay = 30 * time.Second
+	// webhookRetryMaxDelay caps the delay between retries.
+	webhookRetryMaxDelay = time.Hour
+	// webhookTimeout is how long a receiver can take to respond.
+	webhookTimeout = 10 * time.Second
+	// webhookBatchSize is the number of deliveries attempted by a single DeliverWebhooks.
+	webhookBatchSize = 100
+	// webhookConcurrency is the number of webhooks DeliverWebhooks sends to at the same time.
+	webhookConcurrency = 8
+	// webhookMaxErrorLength truncates the error recorded for a failed attempt.
+	webhookMaxErrorLength = 1000
+)
+
+// A WebhookService implements CRUD of webhooks and delivers TODO events to them.
+//
+// Deliveries are queued in the same transaction as the TODO event they notify,
+// so that an event is never lost even if the process stops before it is delivered.
+type WebhookService struct {
+	db     *sql.DB
+	client *http.Client
+	dialer *net.Dialer
+}
+
+// NewWebhookService returns new WebhookService.
+// It refuses to send requests to loopback, private and link-local addresses; see AllowPrivateNetworks.
+func NewWebhookService(db *sql.DB) *WebhookService {
+	dialer := &net.Dialer{Timeout: webhookTimeout, Control: blockPrivateAddress}
+	return &WebhookService{
+		db: db,
+		client: &http.Client{
+			Timeout: webhookTimeout,
+			// 宛先のアドレスを接続時に確認できるよう、環境変数のプロキシは使わない
+			Transport: &http.Transport{
+				DialContext:         dialer.DialContext,
+				TLSHandshakeTimeout: webhookTimeout,
+				MaxIdleConnsPerHost: 2,
+				IdleConnTimeout:     90 * time.Second,
+			},
+			// リダイレクトは追わずに失敗として扱う
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		dialer: dialer,
+	}
+}
+
+// AllowPrivateNetworks lets s send requests to loopback, private and link-local addresses,
+// which are refused by default so that webhooks cannot reach the services behind the server.
+// It must be called before the first delivery, and is meant for development and tests.
+func (s *WebhookService) AllowPrivateNetworks() {
+	s.dialer.Control = nil
+}
+
+// blockPrivateAddress is the net.Dialer.Control refusing connections to addresses that are not publicly routable,
+// such as 127.0.0.1, 10.0.0.0/8 and 169.254.169.254. It checks the resolved address being connected to,
+// so that a host name resolving to a different address later, as in DNS rebinding, cannot bypass it.
+func blockPrivateAddress(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return err
+	}
+	addr = addr.Unmap()
+	if !addr.IsGlobalUnicast() || addr.IsPrivate() || sharedAddressSpace.Contains(addr) {
+		return fmt.Errorf("webhook: connecting to %s is not allowed", addr)
+	}
+	return nil
+}
+
+// sharedAddressSpace is the carrier-grade NAT range of RFC 6598, which is not covered by netip.Addr.IsPrivate.
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// SignWebhookPayload returns the signature of body sent at timestamp: "sha256=" followed by
+// the hex encoded HMAC-SHA256 of the timestamp, a dot and body, keyed with secret.
+// Receivers verify a request by comparing it with WebhookSignatureHeader.
+func SignWebhookPayload(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+const (
+	webhookColumns          = `id, url, events, active, created_at, updated_at`
+	selectWebhookByIDQuery  = `SELECT ` + webhookColumns + ` FROM webhooks WHERE id = ?`
+	webhookDeliveryColumns  = `d.id, d.webhook_id, d.event_id, e.action, d.status, d.attempts, d.next_attempt_at, d.last_attempt_at, d.last_status_code, d.last_error, d.created_at`
+	selectWebhookDeliveries = `SELECT ` + webhookDeliveryColumns + ` FROM webhook_deliveries d JOIN todo_events e ON e.id = d.event_id`
+)
+
+// encodeWebhookEvents converts events to the column value, which wraps each event with commas
+// so that enqueueWebhookDeliveries can find one with INSTR. No events are stored as "".
+func encodeWebhookEvents(events []model.TODOAction) string {
+	if len(events) == 0 {
+		return ""
+	}
+	names := make([]string, len(events))
+	for i, event := range events {
+		names[i] = string(event)
+	}
+	return "," + strings.Join(names, ",") + ","
+}
+
+// decodeWebhookEvents converts a column value of encodeWebhookEvents back to events.
+func decodeWebhookEvents(value string) []model.TODOAction {
+	events := make([]model.TODOAction, 0)
+	for _, name := range strings.Split(strings.Trim(value, ","), ",") {
+		if name != "" {
+			events = append(events, model.TODOAction(name))
+		}
+	}
+	return events
+}
+
+// checkWebhookURL returns *model.ErrValidation unless rawURL is an absolute http or https URL.
+func checkWebhookURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return &model.ErrValidation{Field: "url", Message: "must be an absolute http or https URL"}
+	}
+	return nil
+}
+
+// newWebhookSecret generates a random secret for a webhook created without one.
+func newWebhookSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func scanWebhook(row rowScanner) (*model.Webhook, error) {
+	var (
+		webhook model.Webhook
+		events  string
+	)
+	if err := row.Scan(&webhook.ID, &webhook.URL, &events, &webhook.Active, &webhook.CreatedAt, &webhook.UpdatedAt); err != nil {
+		return nil, err
+	}
+	webhook.Events = decodeWebhookEvents(events)
+	return &webhook, nil
+}
+
+// readWebhookByID reads a webhook, returning *model.ErrNotFound when it does not exist.
+func readWebhookByID(ctx context.Context, q queryer, id int64) (*model.Webhook, error) {
+	webhook, err := scanWebhook(q.QueryRowContext(ctx, selectWebhookByIDQuery, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, &model.ErrNotFound{Resource: "webhook"}
+		}
+		return nil, err
+	}
+	return webhook, nil
+}
+
+// scanWebhookDelivery scans a row selected with webhookDeliveryColumns.
+func scanWebhookDelivery(row rowScanner) (*model.WebhookDelivery, error) {
+	var (
+		delivery      model.WebhookDelivery
+		nextAttemptAt sql.NullTime
+		lastAttemptAt sql.NullTime
+	)
+	if err := row.Scan(&delivery.ID, &delivery.WebhookID, &delivery.EventID, &delivery.Action, &delivery.Status, &delivery.Attempts,
+		&nextAttemptAt, &lastAttemptAt, &delivery.LastStatusCode, &delivery.LastError, &delivery.CreatedAt); err != nil {
+		return nil, err
+	}
+	if nextAttemptAt.Valid && delivery.Status == model.WebhookDeliveryPending {
+		delivery.NextAttemptAt = &nextAttemptAt.Time
+	}
+	if lastAttemptAt.Valid {
+		delivery.LastAttemptAt = &lastAttemptAt.Time
+	}
+	return &delivery, nil
+}
+
+// enqueueWebhookDeliveries queues the delivery of the TODO event eventID to every active webhook subscribing to action.
+func enqueueWebhookDeliveries(ctx context.Context, q queryer, eventID int64, action model.TODOAction) error {
+	const insert = `INSERT INTO webhook_deliveries(webhook_id, event_id, next_attempt_at)
+		SELECT id, ?, ? FROM webhooks WHERE active = 1 AND (events = '' OR INSTR(events, ?) > 0)`
+
+	_, err := q.ExecContext(ctx, insert, eventID, dbTime(time.Now()), ","+string(action)+",")
+	return err
+}
+
+// CreateWebhook creates a webhook on DB. A random secret is generated when secret is empty.
+// The returned webhook is the only one that includes the secret.
+func (s *WebhookService) CreateWebhook(ctx context.Context, rawURL string, events []model.TODOAction, secret string) (*model.Webhook, error) {
+	const insert = `INSERT INTO webhooks(url, events, secret) VALUES(?, ?, ?)`
+
+	if err := checkWebhookURL(rawURL); err != nil {
+		return nil, err
+	}
+	if secret == "" {
+		var err error
+		if secret, err = newWebhookSecret(); err != nil {
+			return nil, err
+		}
+	}
+
+	res, err := s.db.ExecContext(ctx, insert, rawURL, encodeWebhookEvents(events), secret)
+	if err != nil {
+		return nil, err
+	}
+	lastID, err := res.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+
+	webhook, err := readWebhookByID(ctx, s.db, lastID)
+	if err != nil {
+		return nil, err
+	}
+	webhook.Secret = secret
+	return webhook, nil
+}
+
+// ReadWebhook reads webhooks on DB.
+func (s *WebhookService) ReadWebhook(ctx context.Context, prevID, size int64) ([]*model.Webhook, error) {
+	const read = `SELECT ` + webhookColumns + ` FROM webhooks WHERE id > ? ORDER BY id ASC LIMIT ?`
+
+	if size == 0 {
+		size = 5
+	}
+
+	rows, err := s.db.QueryContext(ctx, read, prevID, size)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	webhooks := make([]*model.Webhook, 0)
+	for rows.Next() {
+		webhook, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		webhooks = append(webhooks, webhook)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return webhooks, nil
+}
+
+// ReadWebhookByID reads a webhook on DB.
+func (s *WebhookService) ReadWebhookByID(ctx context.Context, id int64) (*model.Webhook, error) {
+	return readWebhookByID(ctx, s.db, id)
+}
+
+// UpdateWebhook updates a webhook on DB. A nil active keeps the current state and an empty secret keeps the current secret.
+// Deactivating a webhook stops queuing new deliveries and holds the pending ones until it is activated again.
+func (s *WebhookService) UpdateWebhook(ctx context.Context, id int64, rawURL string, events []model.TODOAction, active *bool, secret string) (*model.Webhook, error) {
+	const update = `UPDATE webhooks SET url = ?, events = ?, active = IFNULL(?, active), secret = IFNULL(NULLIF(?, ''), secret) WHERE id = ?`
+
+	if err := checkWebhookURL(rawURL); err != nil {
+		return nil, err
+	}
+
+	res, err := s.db.ExecContext(ctx, update, rawURL, encodeWebhookEvents(events), active, secret, id)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	if rows == 0 {
+		return nil, &model.ErrNotFound{Resource: "webhook"}
+	}
+
+	return readWebhookByID(ctx, s.db, id)
+}
+
+// DeleteWebhook deletes a webhook and its deliveries on DB.
+func (s *WebhookService) DeleteWebhook(ctx context.Context, id int64) error {
+	const deleteWebhook = `DELETE FROM webhooks WHERE id = ?`
+
+	res, err := s.db.ExecContext(ctx, deleteWebhook, id)
+	if err != nil {
+		return err
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return &model.ErrNotFound{Resource: "webhook"}
+	}
+	return nil
+}
+
+// ReadWebhookDeliveries reads the deliveries of the webhook webhookID on DB, oldest first.
+// An empty status reads the deliveries in every status.
+func (s *WebhookService) ReadWebhookDeliveries(ctx context.Context, webhookID int64, status model.WebhookDeliveryStatus, prevID, size int64) ([]*model.WebhookDelivery, error) {
+	const read = selectWebhookDeliveries + ` WHERE d.webhook_id = ? AND (? = '' OR d.status = ?) AND d.id > ? ORDER BY d.id ASC LIMIT ?`
+
+	if _, err := readWebhookByID(ctx, s.db, webhookID); err != nil {
+		return nil, err
+	}
+	if size == 0 {
+		size = 5
+	}
+
+	rows, err := s.db.QueryContext(ctx, read, webhookID, status, status, prevID, size)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := make([]*model.WebhookDelivery, 0)
+	for rows.Next() {
+		delivery, err := scanWebhookDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return deliveries, nil
+}
+
+// RedeliverWebhook queues the delivery deliveryID of the webhook webhookID again, with its attempts reset.
+// Dead deliveries are brought back this way once the receiver is fixed.
+func (s *WebhookService) RedeliverWebhook(ctx context.Context, webhookID, deliveryID int64) (*model.WebhookDelivery, error) {
+	const (
+		update = `UPDATE webhook_deliveries SET status = 'pending', attempts = 0, next_attempt_at = ? WHERE id = ? AND webhook_id = ?`
+		read   = selectWebhookDeliveries + ` WHERE d.id = ?`
+	)
+
+	res, err := s.db.ExecContext(ctx, update, dbTime(time.Now()), deliveryID, webhookID)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	if rows == 0 {
+		return nil, &model.ErrNotFound{Resource: "delivery"}
+	}
+
+	return scanWebhookDelivery(s.db.QueryRowContext(ctx, read, deliveryID))
+}
+
+// A pendingDelivery is a delivery due to be attempted, with what is needed to send it.
+type pendingDelivery struct {
+	id        int64
+	webhookID int64
+	attempts  int64
+	url       string
+	secret    string
+	event     *model.TODOEvent
+}
+
+// DeliverWebhooks attempts the deliveries of active webhooks due at or before now, and returns the number of attempts.
+// The deliveries of a webhook are sent in order, while up to webhookConcurrency webhooks are sent to at the same time,
+// so that a slow receiver does not hold back the others.
+// A failed attempt is retried with an exponential backoff, and the delivery becomes dead after webhookMaxAttempts failures.
+// Until then the later deliveries of the webhook wait for it, so that they are never received before it.
+// The errors of the receivers are recorded on the deliveries; only DB errors are returned.
+func (s *WebhookService) DeliverWebhooks(ctx context.Context, now time.Time) (int, error) {
+	// 同じ Webhook の前の配信が再試行を待っている間は、後の配信を送らない
+	const read = `SELECT d.id, d.webhook_id, d.attempts, w.url, w.secret, e.id, e.todo_id, e.action, e.actor, e.old_value, e.new_value, e.created_at
+		FROM webhook_deliveries d
+		JOIN webhooks w ON w.id = d.webhook_id
+		JOIN todo_events e ON e.id = d.event_id
+		WHERE d.status = 'pending' AND d.next_attempt_at <= ? AND w.active = 1
+		AND NOT EXISTS (SELECT 1 FROM webhook_deliveries p
+			WHERE p.webhook_id = d.webhook_id AND p.status = 'pending' AND p.id < d.id AND p.next_attempt_at > ?)
+		ORDER BY d.id ASC LIMIT ?`
+
+	deliveries, err := s.readPendingDeliveries(ctx, read, dbTime(now), dbTime(now), webhookBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	var (
+		byWebhook = make(map[int64][]*pendingDelivery)
+		webhooks  []int64
+	)
+	for _, d := range deliveries {
+		if _, ok := byWebhook[d.webhookID]; !ok {
+			webhooks = append(webhooks, d.webhookID)
+		}
+		byWebhook[d.webhookID] = append(byWebhook[d.webhookID], d)
+	}
+
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		attempts  int
+		recordErr error
+		sem       = make(chan struct{}, webhookConcurrency)
+	)
+	for _, id := range webhooks {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(deliveries []*pendingDelivery) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			for _, d := range deliveries {
+				statusCode, sendErr := s.send(ctx, d)
+				// 結果の書き込みは 1 つずつ行う
+				mu.Lock()
+				if recordErr == nil {
+					recordErr = s.recordAttempt(ctx, d, now, statusCode, sendErr)
+					attempts++
+				}
+				failed := recordErr != nil
+				mu.Unlock()
+				// 失敗した配信より後の配信は、その再試行を待つ
+				if failed || sendErr != nil {
+					return
+				}
+			}
+		}(byWebhook[id])
+	}
+	wg.Wait()
+	if recordErr != nil {
+		return 0, recordErr
+	}
+	return attempts, nil
+}
+
+// readPendingDeliveries runs query selecting the deliveries to attempt with their webhooks and events.
+func (s *WebhookService) readPendingDeliveries(ctx context.Context, query string, args ...interface{}) ([]*pendingDelivery, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deliveries []*pendingDelivery
+	for rows.Next() {
+		var (
+			d        pendingDelivery
+			event    model.TODOEvent
+			oldValue sql.NullString
+			newValue sql.NullString
+		)
+		if err := rows.Scan(&d.id, &d.webhookID, &d.attempts, &d.url, &d.secret,
+			&event.ID, &event.TODOID, &event.Action, &event.Actor, &oldValue, &newValue, &event.CreatedAt); err != nil {
+			return nil, err
+		}
+		if event.OldValue, err = unmarshalSnapshot(oldValue); err != nil {
+			return nil, err
+		}
+		if event.NewValue, err = unmarshalSnapshot(newValue); err != nil {
+			return nil, err
+		}
+		d.event = &event
+		deliveries = append(deliveries, &d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return deliveries, nil
+}
+
+// send posts the event of d to its webhook signed at the time it is sent, and returns the status code of the response.
+// It returns an error when the request fails or the status code is not 2xx.
+func (s *WebhookService) send(ctx context.Context, d *pendingDelivery) (int, error) {
+	body, err := json.Marshal(d.event)
+	if err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookSignatureHeader, SignWebhookPayload(d.secret, timestamp, body))
+	req.Header.Set(WebhookTimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(WebhookEventHeader, string(d.event.Action))
+	req.Header.Set(WebhookDeliveryHeader, strconv.FormatInt(d.id, 10))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	// 接続を再利用できるように、レスポンスの本文はある程度まで読み捨てる
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// recordAttempt records the result of the attempt of d at now, scheduling the retry or marking it dead when sendErr is not nil.
+func (s *WebhookService) recordAttempt(ctx context.Context, d *pendingDelivery, now time.Time, statusCode int, sendErr error) error {
+	const update = `UPDATE webhook_deliveries SET status = ?, attempts = ?, next_attempt_at = ?, last_attempt_at = ?, last_status_code = ?, last_error = ? WHERE id = ?`
+
+	var (
+		attempts      = d.attempts + 1
+		status        = model.WebhookDeliverySucceeded
+		nextAttemptAt interface{}
+		lastError     string
+	)
+	if sendErr != nil {
+		lastError = sendErr.Error()
+		if len(lastError) > webhookMaxErrorLength {
+			lastError = lastError[:webhookMaxErrorLength]
+		}
+		if attempts >= webhookMaxAttempts {
+			status = model.WebhookDeliveryDead
+		} else {
+			status = model.WebhookDeliveryPending
+			nextAttemptAt = dbTime(now.Add(webhookRetryDelay(attempts)))
+		}
+	}
+
+	_, err := s.db.ExecContext(ctx, update, status, attempts, nextAttemptAt, dbTime(now), statusCode, lastError, d.id)
+	return err
+}
+
+// webhookRetryDelay returns the delay before retrying a delivery failed attempts times.
+func webhookRetryDelay(attempts int64) time.Duration {
+	delay := webhookRetryBaseDelay
+	for i := int64(1); i < attempts && delay < webhookRetryMaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > webhookRetryMaxDelay {
+		delay = webhookRetryMaxDelay
+	}
+	return delay
+}
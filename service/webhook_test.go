@@ -0,0 +1,350 @@
+package service_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/TechBowl-japan/go-stations/db"
+	"github.com/TechBowl-japan/go-stations/model"
+	"github.com/TechBowl-japan/go-stations/service"
+)
+
+// webhookReceiver records the requests to a httptest server, responding with status.
+type webhookReceiver struct {
+	mu       sync.Mutex
+	status   int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func newWebhookReceiver(t *testing.T, status int) (*webhookReceiver, *httptest.Server) {
+	t.Helper()
+
+	recv := &webhookReceiver{status: status}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		recv.mu.Lock()
+		defer recv.mu.Unlock()
+		recv.requests = append(recv.requests, r)
+		recv.bodies = append(recv.bodies, body)
+		w.WriteHeader(recv.status)
+	}))
+	t.Cleanup(srv.Close)
+	return recv, srv
+}
+
+func TestDeliverWebhooks(t *testing.T) {
+	t.Parallel()
+
+	todoDB, err := db.NewDB(filepath.Join(t.TempDir(), "todo.db"))
+	if err != nil {
+		t.Fatalf("failed to create db: %v", err)
+	}
+	t.Cleanup(func() { todoDB.Close() })
+
+	ctx := context.Background()
+	svc := service.NewWebhookService(todoDB)
+	svc.AllowPrivateNetworks()
+	todoSvc := service.NewTODOService(todoDB)
+	recv, srv := newWebhookReceiver(t, http.StatusNoContent)
+
+	webhook, err := svc.CreateWebhook(ctx, srv.URL, []model.TODOAction{model.TODOActionCreate}, "")
+	if err != nil {
+		t.Fatalf("failed to create webhook: %v", err)
+	}
+	if webhook.Secret == "" {
+		t.Fatal("secret is not generated")
+	}
+
+	todo, err := todoSvc.CreateTODO(ctx, "notified", "")
+	if err != nil {
+		t.Fatalf("failed to create todo: %v", err)
+	}
+	// 購読していない種類の変更は配信されない
+	if _, err := todoSvc.UpdateTODO(ctx, todo.ID, "not notified", ""); err != nil {
+		t.Fatalf("failed to update todo: %v", err)
+	}
+
+	now := time.Now()
+	if n, err := svc.DeliverWebhooks(ctx, now); err != nil || n != 1 {
+		t.Fatalf("unexpected deliveries, got = %d, err = %v", n, err)
+	}
+	if n, err := svc.DeliverWebhooks(ctx, now); err != nil || n != 0 {
+		t.Errorf("delivered twice, got = %d, err = %v", n, err)
+	}
+
+	req, body := recv.requests[0], recv.bodies[0]
+	timestamp, err := strconv.ParseInt(req.Header.Get(service.WebhookTimestampHeader), 10, 64)
+	if err != nil || timestamp < now.Unix() || timestamp > time.Now().Unix() {
+		t.Errorf("unexpected timestamp, got = %s", req.Header.Get(service.WebhookTimestampHeader))
+	}
+	want := service.SignWebhookPayload(webhook.Secret, timestamp, body)
+	if got := req.Header.Get(service.WebhookSignatureHeader); got != want {
+		t.Errorf("unexpected signature, got = %s, want = %s", got, want)
+	}
+	if got := req.Header.Get(service.WebhookEventHeader); got != string(model.TODOActionCreate) {
+		t.Errorf("unexpected event, got = %s", got)
+	}
+	var event model.TODOEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		t.Fatalf("failed to decode payload %s: %v", body, err)
+	}
+	if event.TODOID != todo.ID || event.NewValue == nil || event.NewValue.Subject != "notified" {
+		t.Errorf("unexpected payload, got = %s", body)
+	}
+
+	deliveries, err := svc.ReadWebhookDeliveries(ctx, webhook.ID, "", 0, 10)
+	if err != nil {
+		t.Fatalf("failed to read deliveries: %v", err)
+	}
+	if len(deliveries) != 1 || deliveries[0].Status != model.WebhookDeliverySucceeded ||
+		deliveries[0].Attempts != 1 || deliveries[0].LastStatusCode != http.StatusNoContent {
+		t.Errorf("unexpected deliveries, got = %+v", deliveries)
+	}
+}
+
+func TestDeliverWebhooksRetry(t *testing.T) {
+	t.Parallel()
+
+	todoDB, err := db.NewDB(filepath.Join(t.TempDir(), "todo.db"))
+	if err != nil {
+		t.Fatalf("failed to create db: %v", err)
+	}
+	t.Cleanup(func() { todoDB.Close() })
+
+	ctx := context.Background()
+	svc := service.NewWebhookService(todoDB)
+	svc.AllowPrivateNetworks()
+	recv, srv := newWebhookReceiver(t, http.StatusInternalServerError)
+
+	webhook, err := svc.CreateWebhook(ctx, srv.URL, nil, "secret")
+	if err != nil {
+		t.Fatalf("failed to create webhook: %v", err)
+	}
+	if _, err := service.NewTODOService(todoDB).CreateTODO(ctx, "retried", ""); err != nil {
+		t.Fatalf("failed to create todo: %v", err)
+	}
+
+	readDelivery := func() *model.WebhookDelivery {
+		t.Helper()
+		deliveries, err := svc.ReadWebhookDeliveries(ctx, webhook.ID, "", 0, 10)
+		if err != nil || len(deliveries) != 1 {
+			t.Fatalf("unexpected deliveries, got = %+v, err = %v", deliveries, err)
+		}
+		return deliveries[0]
+	}
+
+	now := time.Now()
+	if _, err := svc.DeliverWebhooks(ctx, now); err != nil {
+		t.Fatalf("failed to deliver: %v", err)
+	}
+	delivery := readDelivery()
+	if delivery.Status != model.WebhookDeliveryPending || delivery.Attempts != 1 || delivery.NextAttemptAt == nil ||
+		delivery.LastStatusCode != http.StatusInternalServerError || delivery.LastError == "" {
+		t.Fatalf("unexpected delivery, got = %+v", delivery)
+	}
+	// 再試行の時刻より前には配信しない
+	if n, err := svc.DeliverWebhooks(ctx, now.Add(time.Second)); err != nil || n != 0 {
+		t.Errorf("retried too early, got = %d, err = %v", n, err)
+	}
+
+	// 再試行の間隔は倍々に延びる
+	var prevDelay time.Duration
+	for delivery.Status == model.WebhookDeliveryPending {
+		delay := delivery.NextAttemptAt.Sub(*delivery.LastAttemptAt)
+		if delay <= prevDelay {
+			t.Errorf("delay does not grow, got = %v, previous = %v", delay, prevDelay)
+		}
+		prevDelay = delay
+
+		now = *delivery.NextAttemptAt
+		if n, err := svc.DeliverWebhooks(ctx, now); err != nil || n != 1 {
+			t.Fatalf("unexpected deliveries, got = %d, err = %v", n, err)
+		}
+		delivery = readDelivery()
+	}
+	if delivery.Status != model.WebhookDeliveryDead || delivery.Attempts != 8 || delivery.NextAttemptAt != nil {
+		t.Errorf("unexpected dead delivery, got = %+v", delivery)
+	}
+	if got := recv.requests[0].Header.Get(service.WebhookDeliveryHeader); got != strconv.FormatInt(delivery.ID, 10) {
+		t.Errorf("unexpected delivery header, got = %s", got)
+	}
+
+	// 送信先が直ったら手動で配信し直せる
+	recv.mu.Lock()
+	recv.status = http.StatusOK
+	recv.mu.Unlock()
+	if _, err := svc.RedeliverWebhook(ctx, webhook.ID, delivery.ID); err != nil {
+		t.Fatalf("failed to redeliver: %v", err)
+	}
+	if n, err := svc.DeliverWebhooks(ctx, time.Now().Add(time.Second)); err != nil || n != 1 {
+		t.Fatalf("unexpected deliveries, got = %d, err = %v", n, err)
+	}
+	if delivery := readDelivery(); delivery.Status != model.WebhookDeliverySucceeded || delivery.Attempts != 1 {
+		t.Errorf("unexpected redelivered delivery, got = %+v", delivery)
+	}
+}
+
+func TestDeliverWebhooksOrder(t *testing.T) {
+	t.Parallel()
+
+	todoDB, err := db.NewDB(filepath.Join(t.TempDir(), "todo.db"))
+	if err != nil {
+		t.Fatalf("failed to create db: %v", err)
+	}
+	t.Cleanup(func() { todoDB.Close() })
+
+	ctx := context.Background()
+	svc := service.NewWebhookService(todoDB)
+	svc.AllowPrivateNetworks()
+	recv, srv := newWebhookReceiver(t, http.StatusInternalServerError)
+
+	webhook, err := svc.CreateWebhook(ctx, srv.URL, nil, "secret")
+	if err != nil {
+		t.Fatalf("failed to create webhook: %v", err)
+	}
+	todoSvc := service.NewTODOService(todoDB)
+	for _, subject := range []string{"first", "second"} {
+		if _, err := todoSvc.CreateTODO(ctx, subject, ""); err != nil {
+			t.Fatalf("failed to create todo: %v", err)
+		}
+	}
+
+	// 失敗した配信より後の配信は、その再試行まで送らない
+	now := time.Now()
+	if n, err := svc.DeliverWebhooks(ctx, now); err != nil || n != 1 {
+		t.Fatalf("unexpected deliveries, got = %d, err = %v", n, err)
+	}
+	if n, err := svc.DeliverWebhooks(ctx, now.Add(time.Second)); err != nil || n != 0 {
+		t.Fatalf("later delivery is sent before the retry, got = %d, err = %v", n, err)
+	}
+
+	recv.mu.Lock()
+	recv.status = http.StatusOK
+	recv.mu.Unlock()
+	// 署名の時刻は再試行の予定ではなく送った時刻にする
+	retryAt := now.Add(time.Hour)
+	if n, err := svc.DeliverWebhooks(ctx, retryAt); err != nil || n != 2 {
+		t.Fatalf("unexpected deliveries, got = %d, err = %v", n, err)
+	}
+
+	var subjects []string
+	for i, body := range recv.bodies {
+		var event model.TODOEvent
+		if err := json.Unmarshal(body, &event); err != nil || event.NewValue == nil {
+			t.Fatalf("failed to decode payload %s: %v", body, err)
+		}
+		subjects = append(subjects, event.NewValue.Subject)
+		if got, _ := strconv.ParseInt(recv.requests[i].Header.Get(service.WebhookTimestampHeader), 10, 64); got >= retryAt.Unix() {
+			t.Errorf("request %d is signed at the retry time, got = %d", i, got)
+		}
+	}
+	if !equalStrings(subjects, []string{"first", "first", "second"}) {
+		t.Errorf("unexpected order, got = %v", subjects)
+	}
+
+	deliveries, err := svc.ReadWebhookDeliveries(ctx, webhook.ID, model.WebhookDeliverySucceeded, 0, 10)
+	if err != nil || len(deliveries) != 2 {
+		t.Errorf("unexpected deliveries, got = %+v, err = %v", deliveries, err)
+	}
+}
+
+func TestDeliverWebhooksPrivateNetwork(t *testing.T) {
+	t.Parallel()
+
+	todoDB, err := db.NewDB(filepath.Join(t.TempDir(), "todo.db"))
+	if err != nil {
+		t.Fatalf("failed to create db: %v", err)
+	}
+	t.Cleanup(func() { todoDB.Close() })
+
+	ctx := context.Background()
+	// AllowPrivateNetworks を呼ばない場合は 127.0.0.1 に接続しない
+	svc := service.NewWebhookService(todoDB)
+	recv, srv := newWebhookReceiver(t, http.StatusOK)
+
+	webhook, err := svc.CreateWebhook(ctx, srv.URL, nil, "secret")
+	if err != nil {
+		t.Fatalf("failed to create webhook: %v", err)
+	}
+	if _, err := service.NewTODOService(todoDB).CreateTODO(ctx, "blocked", ""); err != nil {
+		t.Fatalf("failed to create todo: %v", err)
+	}
+	if n, err := svc.DeliverWebhooks(ctx, time.Now()); err != nil || n != 1 {
+		t.Fatalf("unexpected deliveries, got = %d, err = %v", n, err)
+	}
+
+	deliveries, err := svc.ReadWebhookDeliveries(ctx, webhook.ID, "", 0, 10)
+	if err != nil || len(deliveries) != 1 {
+		t.Fatalf("unexpected deliveries, got = %+v, err = %v", deliveries, err)
+	}
+	if d := deliveries[0]; d.Status != model.WebhookDeliveryPending || d.LastStatusCode != 0 || d.LastError == "" {
+		t.Errorf("unexpected delivery, got = %+v", d)
+	}
+	if len(recv.requests) != 0 {
+		t.Errorf("unexpected requests, got = %d", len(recv.requests))
+	}
+}
+
+func TestDeliverWebhooksSlowReceiver(t *testing.T) {
+	t.Parallel()
+
+	todoDB, err := db.NewDB(filepath.Join(t.TempDir(), "todo.db"))
+	if err != nil {
+		t.Fatalf("failed to create db: %v", err)
+	}
+	t.Cleanup(func() { todoDB.Close() })
+
+	ctx := context.Background()
+	svc := service.NewWebhookService(todoDB)
+	svc.AllowPrivateNetworks()
+
+	// 遅い送信先は速い送信先の配信が終わるまで応答しない
+	fast, fastSrv := newWebhookReceiver(t, http.StatusOK)
+	release := make(chan struct{})
+	slowSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	t.Cleanup(slowSrv.Close)
+
+	if _, err := svc.CreateWebhook(ctx, slowSrv.URL, nil, "secret"); err != nil {
+		t.Fatalf("failed to create webhook: %v", err)
+	}
+	if _, err := svc.CreateWebhook(ctx, fastSrv.URL, nil, "secret"); err != nil {
+		t.Fatalf("failed to create webhook: %v", err)
+	}
+	if _, err := service.NewTODOService(todoDB).CreateTODO(ctx, "delivered", ""); err != nil {
+		t.Fatalf("failed to create todo: %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := svc.DeliverWebhooks(ctx, time.Now())
+		done <- err
+	}()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		fast.mu.Lock()
+		n := len(fast.requests)
+		fast.mu.Unlock()
+		if n == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("fast receiver is blocked by slow receiver")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatalf("failed to deliver: %v", err)
+	}
+}